@@ -1,10 +1,13 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
 
 	"github.com/idoyudha/eshop-product/config"
 	"github.com/idoyudha/eshop-product/internal/app"
+	"github.com/idoyudha/eshop-product/internal/entity"
 )
 
 func main() {
@@ -13,5 +16,50 @@ func main() {
 		log.Fatal("Config error: ", err)
 	}
 
+	// admin commands run once and exit instead of starting the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "image-gc":
+			flags := flag.NewFlagSet("image-gc", flag.ExitOnError)
+			dryRun := flags.Bool("dry-run", false, "report orphaned images without deleting them")
+			_ = flags.Parse(os.Args[2:])
+
+			app.RunImageGC(cfg, *dryRun)
+			return
+		case "backfill-catalog":
+			app.RunCatalogBackfill(cfg)
+			return
+		case "export":
+			flags := flag.NewFlagSet("export", flag.ExitOnError)
+			format := flags.String("format", "csv", "csv or jsonl")
+			out := flags.String("out", "", "file to write the export to")
+			s3Key := flags.String("s3-key", "", "key in the product bucket to write the export to, instead of a file")
+			categoryID := flags.String("category", "", "export only the products of this category")
+			status := flags.String("status", "", "export only the products with this status")
+			includeCategory := flags.Bool("include-category", false, "add the category name of every product")
+			_ = flags.Parse(os.Args[2:])
+
+			if (*out == "") == (*s3Key == "") {
+				log.Fatal("export: set exactly one of -out and -s3-key")
+			}
+			exportFormat, err := entity.ParseExportFormat(*format)
+			if err != nil {
+				log.Fatal("export: ", err)
+			}
+
+			app.RunExport(cfg, *out, *s3Key, entity.ExportOptions{
+				Format: exportFormat,
+				Filter: entity.ProductFilter{
+					CategoryID: *categoryID,
+					Status:     entity.ProductStatus(*status),
+				},
+				IncludeCategory: *includeCategory,
+			})
+			return
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+	}
+
 	app.Run(cfg)
 }
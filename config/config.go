@@ -1,13 +1,27 @@
 package config
 
-import "github.com/ilyakaznacheev/cleanenv"
+import (
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
 
 type (
 	// Config
 	Config struct {
-		App  `yaml:"app"`
-		HTTP `yaml:"http"`
-		Log  `yaml:"log"`
+		App         `yaml:"app"`
+		HTTP        `yaml:"http"`
+		Log         `yaml:"log"`
+		Image       `yaml:"image"`
+		ImageGC     `yaml:"image_gc"`
+		Price       `yaml:"price"`
+		Promotion   `yaml:"promotion"`
+		Publication `yaml:"publication"`
+		Trash       `yaml:"trash"`
+		Import      `yaml:"import"`
+		Reservation `yaml:"reservation"`
+		Stock       `yaml:"stock"`
+		Locale      `yaml:"locale"`
 		AWS
 		Redis
 		Kafka
@@ -30,6 +44,7 @@ type (
 		AwsSecretey   string `env-required:"true" env:"AWS_SECRET_KEY"`
 		ProductBucket string `env-required:"true" env:"AWS_PRODUCT_BUCKET"`
 		CdnDomain     string `env-required:"true" env:"AWS_CDN_DOMAIN"`
+		S3Endpoint    string `env:"AWS_S3_ENDPOINT"` // optional, e.g. a minio url
 	}
 
 	// Redis
@@ -40,6 +55,73 @@ type (
 		RedisPassword      string `env-required:"true" env:"REDIS_PASSWORD"`
 	}
 
+	// Image upload limits
+	Image struct {
+		MaxSizeBytes   int64         `yaml:"max_size_bytes"  env:"IMAGE_MAX_SIZE_BYTES"`
+		MaxWidth       int           `yaml:"max_width"       env:"IMAGE_MAX_WIDTH"`
+		MaxHeight      int           `yaml:"max_height"      env:"IMAGE_MAX_HEIGHT"`
+		MaxPixels      int           `yaml:"max_pixels"      env:"IMAGE_MAX_PIXELS"`
+		AllowedFormats []string      `yaml:"allowed_formats" env:"IMAGE_ALLOWED_FORMATS" env-separator:","`
+		UploadURLTTL   time.Duration `yaml:"upload_url_ttl"  env:"IMAGE_UPLOAD_URL_TTL"`
+	}
+
+	// ImageGC deletes product images no product references any more and uploads never confirmed
+	ImageGC struct {
+		Enabled     bool          `yaml:"enabled"      env:"IMAGE_GC_ENABLED"`
+		DryRun      bool          `yaml:"dry_run"      env:"IMAGE_GC_DRY_RUN"`
+		Interval    time.Duration `yaml:"interval"     env:"IMAGE_GC_INTERVAL"`
+		GracePeriod time.Duration `yaml:"grace_period" env:"IMAGE_GC_GRACE_PERIOD"`
+	}
+
+	// Price scheduler applying the scheduled price changes
+	Price struct {
+		SchedulerInterval time.Duration `yaml:"scheduler_interval" env:"PRICE_SCHEDULER_INTERVAL"`
+	}
+
+	// Promotion prices, the promotions are cached for CacheTTL and the scheduler announces
+	// the prices of the promotions that started or ended
+	Promotion struct {
+		CacheTTL          time.Duration `yaml:"cache_ttl"          env:"PROMOTION_CACHE_TTL"`
+		SchedulerInterval time.Duration `yaml:"scheduler_interval" env:"PROMOTION_SCHEDULER_INTERVAL"`
+	}
+
+	// Publication scheduler publishing the scheduled products
+	Publication struct {
+		SchedulerInterval time.Duration `yaml:"scheduler_interval" env:"PUBLICATION_SCHEDULER_INTERVAL"`
+	}
+
+	// Trash purge hard-deleting the items soft-deleted longer than the retention period
+	Trash struct {
+		Retention     time.Duration `yaml:"retention"      env:"TRASH_RETENTION"`
+		PurgeInterval time.Duration `yaml:"purge_interval" env:"TRASH_PURGE_INTERVAL"`
+	}
+
+	// Import of product files, a job without progress for StaleAfter was interrupted and is failed
+	Import struct {
+		StaleAfter    time.Duration `yaml:"stale_after"    env:"IMPORT_STALE_AFTER"`
+		SweepInterval time.Duration `yaml:"sweep_interval" env:"IMPORT_SWEEP_INTERVAL"`
+	}
+
+	// Reservation of stock for orders, an unpaid order gives its stock back after the TTL
+	Reservation struct {
+		TTL           time.Duration `yaml:"ttl"            env:"RESERVATION_TTL"`
+		SweepInterval time.Duration `yaml:"sweep_interval" env:"RESERVATION_SWEEP_INTERVAL"`
+	}
+
+	// Stock alerts, products without their own reorder threshold use the low threshold and
+	// the same alert is not sent again for a product within the cooldown
+	Stock struct {
+		LowThreshold  int           `yaml:"low_threshold"  env:"STOCK_LOW_THRESHOLD"`
+		AlertCooldown time.Duration `yaml:"alert_cooldown" env:"STOCK_ALERT_COOLDOWN"`
+	}
+
+	// Locale of the catalog content, the untranslated names and descriptions are in the default locale
+	Locale struct {
+		Default   string            `yaml:"default"   env:"LOCALE_DEFAULT"`
+		Supported []string          `yaml:"supported" env:"LOCALE_SUPPORTED" env-separator:","`
+		Fallbacks map[string]string `yaml:"fallbacks" env:"LOCALE_FALLBACKS"` // locale to the locale tried next, like ms:id
+	}
+
 	// Log
 	Log struct {
 		Level string `yaml:"log_level"`
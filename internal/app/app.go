@@ -1,18 +1,22 @@
 package app
 
 import (
+	"context"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/gin-gonic/gin"
 	"github.com/idoyudha/eshop-product/config"
 	v1Http "github.com/idoyudha/eshop-product/internal/controller/http/v1"
 	kafkaEvent "github.com/idoyudha/eshop-product/internal/controller/kafka"
+	"github.com/idoyudha/eshop-product/internal/entity"
 	"github.com/idoyudha/eshop-product/internal/usecase"
 	"github.com/idoyudha/eshop-product/internal/usecase/repo"
 	"github.com/idoyudha/eshop-product/pkg/aws"
 	"github.com/idoyudha/eshop-product/pkg/httpserver"
+	"github.com/idoyudha/eshop-product/pkg/imaging"
 	"github.com/idoyudha/eshop-product/pkg/kafka"
 	"github.com/idoyudha/eshop-product/pkg/logger"
 	"github.com/idoyudha/eshop-product/pkg/redis"
@@ -48,26 +52,249 @@ func Run(cfg *config.Config) {
 		l.Fatal("app - Run - redis.NewRedis: ", err)
 	}
 
+	imageValidator := imaging.NewValidator(imageLimits(cfg.Image))
+
+	locales, err := entity.NewLocales(cfg.Locale.Default, cfg.Locale.Supported, cfg.Locale.Fallbacks)
+	if err != nil {
+		l.Fatal("app - Run - entity.NewLocales: ", err)
+	}
+
+	stockAlerts := entity.StockAlertPolicy{
+		DefaultThreshold: cfg.Stock.LowThreshold,
+		Cooldown:         cfg.Stock.AlertCooldown,
+	}
+
+	productRepoImage := repo.NewProductS3Repo(s3, imageValidator, cfg.Image.UploadURLTTL)
+	productRepoDynamo := repo.NewProductDynamoDBRepo(dynamoDB)
+	variantRepoDynamo := repo.NewVariantDynamoRepo(dynamoDB)
+	categoryRepoDynamo := repo.NewCategoryDynamoRepo(dynamoDB)
+	priceRepoDynamo := repo.NewPriceDynamoRepo(dynamoDB)
+	promotionRepoDynamo := repo.NewPromotionDynamoRepo(dynamoDB)
+	slugRepoDynamo := repo.NewSlugDynamoRepo(dynamoDB)
+	bundleRepoDynamo := repo.NewBundleDynamoRepo(dynamoDB)
+	stockRepoDynamo := repo.NewStockMovementDynamoRepo(dynamoDB)
+	productRepoSearch := repo.NewProductSearchRepo()
+
+	promotionEvaluator := usecase.NewPromotionEvaluator(
+		promotionRepoDynamo,
+		categoryRepoDynamo,
+		variantRepoDynamo,
+		cfg.Promotion.CacheTTL,
+	)
+
 	productUseCase := usecase.NewProductUseCase(
-		repo.NewProductS3Repo(s3),
-		repo.NewProductDynamoDBRepo(dynamoDB),
+		productRepoImage,
+		productRepoDynamo,
+		productRepoSearch,
+		variantRepoDynamo,
+		categoryRepoDynamo,
+		priceRepoDynamo,
+		slugRepoDynamo,
+		bundleRepoDynamo,
+		stockRepoDynamo,
+		promotionEvaluator,
+		kafkaProducer,
+		locales,
+		stockAlerts,
+	)
+
+	variantUseCase := usecase.NewVariantUseCase(
+		variantRepoDynamo,
+		productRepoDynamo,
+		stockRepoDynamo,
+		promotionEvaluator,
+		kafkaProducer,
+	)
+
+	err = productUseCase.BuildSearchIndex(context.Background())
+	if err != nil {
+		l.Error("app - Run - productUseCase.BuildSearchIndex: ", err)
+	}
+
+	priceUseCase := usecase.NewPriceUseCase(
+		priceRepoDynamo,
+		productRepoDynamo,
+		productRepoSearch,
+		variantRepoDynamo,
+		bundleRepoDynamo,
+		promotionEvaluator,
+		kafkaProducer,
+	)
+
+	promotionUseCase := usecase.NewPromotionUseCase(
+		promotionRepoDynamo,
+		productRepoDynamo,
+		variantRepoDynamo,
+		categoryRepoDynamo,
+		promotionEvaluator,
+		kafkaProducer,
+	)
+
+	productLinkRepoDynamo := repo.NewProductLinkDynamoRepo(dynamoDB)
+
+	productLinkUseCase := usecase.NewProductLinkUseCase(
+		productLinkRepoDynamo,
+		productRepoDynamo,
+		productRepoSearch,
+		promotionEvaluator,
+	)
+
+	imageGCUseCase := usecase.NewImageGCUseCase(
+		productRepoImage,
+		productRepoDynamo,
+		cfg.ImageGC.GracePeriod,
+		cfg.Image.UploadURLTTL,
+	)
+
+	importUseCase := usecase.NewImportUseCase(
+		productRepoImage,
+		productRepoDynamo,
+		productRepoSearch,
+		variantRepoDynamo,
+		categoryRepoDynamo,
+		priceRepoDynamo,
+		repo.NewImportDynamoRepo(dynamoDB),
+		slugRepoDynamo,
+		promotionEvaluator,
 		kafkaProducer,
+		cfg.Import.StaleAfter,
 	)
 
+	categoryRepoRedis := repo.NewCategoryRedisRepo(redisClient)
+
 	categoryUseCase := usecase.NewCategoryUseCase(
-		repo.NewCategoryRedisRepo(redisClient),
-		repo.NewCategoryDynamoRepo(dynamoDB),
+		categoryRepoRedis,
+		categoryRepoDynamo,
+		slugRepoDynamo,
+		locales,
+	)
+
+	exportUseCase := usecase.NewExportUseCase(
+		productRepoImage,
+		productRepoDynamo,
+		categoryRepoDynamo,
+		categoryRepoRedis,
+	)
+
+	trashUseCase := usecase.NewTrashUseCase(
+		productRepoImage,
+		productRepoDynamo,
+		productRepoSearch,
+		variantRepoDynamo,
+		priceRepoDynamo,
+		slugRepoDynamo,
+		productLinkRepoDynamo,
+		bundleRepoDynamo,
+		categoryRepoDynamo,
+		categoryRepoRedis,
+		cfg.Trash.Retention,
+	)
+
+	reservationUseCase := usecase.NewReservationUseCase(
+		repo.NewReservationDynamoRepo(dynamoDB),
+		productRepoDynamo,
+		productRepoSearch,
+		bundleRepoDynamo,
+		priceRepoDynamo,
+		variantRepoDynamo,
+		promotionEvaluator,
+		kafkaProducer,
+		cfg.Reservation.TTL,
+		stockAlerts,
 	)
 
+	// Background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	if cfg.ImageGC.Enabled && cfg.ImageGC.Interval > 0 {
+		go runEvery(jobsCtx, cfg.ImageGC.Interval, func(ctx context.Context) {
+			collectOrphanedImages(ctx, imageGCUseCase, cfg.ImageGC.DryRun, l)
+		})
+	}
+
+	if cfg.Price.SchedulerInterval > 0 {
+		go runEvery(jobsCtx, cfg.Price.SchedulerInterval, func(ctx context.Context) {
+			applied, err := priceUseCase.ApplyDuePriceChanges(ctx)
+			if err != nil {
+				l.Error("app - Run - priceUseCase.ApplyDuePriceChanges: ", err)
+			}
+			if applied > 0 {
+				l.Info("app - Run - applied %d scheduled price changes", applied)
+			}
+		})
+	}
+
+	if cfg.Promotion.SchedulerInterval > 0 {
+		go runEvery(jobsCtx, cfg.Promotion.SchedulerInterval, func(ctx context.Context) {
+			announced, err := promotionUseCase.AnnouncePromotionPhases(ctx)
+			if err != nil {
+				l.Error("app - Run - promotionUseCase.AnnouncePromotionPhases: ", err)
+			}
+			if announced > 0 {
+				l.Info("app - Run - announced the prices of %d started or ended promotions", announced)
+			}
+		})
+	}
+
+	if cfg.Publication.SchedulerInterval > 0 {
+		go runEvery(jobsCtx, cfg.Publication.SchedulerInterval, func(ctx context.Context) {
+			published, err := productUseCase.PublishDueProducts(ctx)
+			if err != nil {
+				l.Error("app - Run - productUseCase.PublishDueProducts: ", err)
+			}
+			if published > 0 {
+				l.Info("app - Run - published %d scheduled products", published)
+			}
+		})
+	}
+
+	if cfg.Trash.PurgeInterval > 0 {
+		go runEvery(jobsCtx, cfg.Trash.PurgeInterval, func(ctx context.Context) {
+			report, err := trashUseCase.PurgeExpired(ctx)
+			if err != nil {
+				l.Error("app - Run - trashUseCase.PurgeExpired: ", err)
+			}
+			if report != nil && report.Products+report.Categories > 0 {
+				l.Info("app - Run - purged %d products, %d categories and %d images from the trash",
+					report.Products, report.Categories, report.Images)
+			}
+		})
+	}
+
+	if cfg.Import.SweepInterval > 0 {
+		go runEvery(jobsCtx, cfg.Import.SweepInterval, func(ctx context.Context) {
+			failed, err := importUseCase.FailStaleImports(ctx)
+			if err != nil {
+				l.Error("app - Run - importUseCase.FailStaleImports: ", err)
+			}
+			if failed > 0 {
+				l.Info("app - Run - failed %d interrupted import jobs", failed)
+			}
+		})
+	}
+
+	if cfg.Reservation.SweepInterval > 0 {
+		go runEvery(jobsCtx, cfg.Reservation.SweepInterval, func(ctx context.Context) {
+			released, err := reservationUseCase.ReleaseExpiredReservations(ctx)
+			if err != nil {
+				l.Error("app - Run - reservationUseCase.ReleaseExpiredReservations: ", err)
+			}
+			if released > 0 {
+				l.Info("app - Run - released %d expired stock reservations", released)
+			}
+		})
+	}
+
 	// HTTP Server
 	handler := gin.Default()
-	v1Http.HTTPNewRouter(handler, productUseCase, categoryUseCase, l)
+	v1Http.HTTPNewRouter(handler, productUseCase, productLinkUseCase, importUseCase, exportUseCase, variantUseCase, priceUseCase, promotionUseCase, categoryUseCase, trashUseCase, imageValidator, locales, l)
 	httpServer := httpserver.New(handler, httpserver.Port(cfg.HTTP.Port))
 
 	// Kafka Consumer
 	kafkaErrChan := make(chan error, 1)
 	go func() {
-		if err := kafkaEvent.KafkaNewRouter(productUseCase, l, kafkaConsumer); err != nil {
+		if err := kafkaEvent.KafkaNewRouter(productUseCase, variantUseCase, reservationUseCase, l, kafkaConsumer); err != nil {
 			kafkaErrChan <- err
 		}
 	}()
@@ -88,3 +315,18 @@ func Run(cfg *config.Config) {
 		l.Info("app - Run - httpServer.Shutdown: %s", err)
 	}
 }
+
+func imageLimits(cfg config.Image) imaging.Limits {
+	formats := make([]imaging.Format, 0, len(cfg.AllowedFormats))
+	for _, f := range cfg.AllowedFormats {
+		formats = append(formats, imaging.Format(strings.ToLower(strings.TrimSpace(f))))
+	}
+
+	return imaging.Limits{
+		MaxBytes:  cfg.MaxSizeBytes,
+		MaxWidth:  cfg.MaxWidth,
+		MaxHeight: cfg.MaxHeight,
+		MaxPixels: cfg.MaxPixels,
+		Formats:   formats,
+	}
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"context"
+
+	"github.com/idoyudha/eshop-product/config"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/internal/usecase/repo"
+	"github.com/idoyudha/eshop-product/pkg/aws"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+)
+
+// RunCatalogBackfill sets the catalog attribute on the products saved before it existed,
+// it backs the backfill-catalog admin command
+func RunCatalogBackfill(cfg *config.Config) {
+	l := logger.New(cfg.Log.Level)
+
+	dynamoDB, err := aws.NewDynamoDB(&cfg.AWS)
+	if err != nil {
+		l.Fatal("app - RunCatalogBackfill - dynamodb.NewDynamoDB: ", err)
+	}
+
+	catalogUseCase := usecase.NewCatalogUseCase(repo.NewProductDynamoDBRepo(dynamoDB))
+
+	backfilled, err := catalogUseCase.BackfillCatalog(context.Background())
+	if err != nil {
+		l.Fatal("app - RunCatalogBackfill - catalogUseCase.BackfillCatalog: ", err)
+	}
+	l.Info("app - RunCatalogBackfill - backfilled %d products", backfilled)
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"os"
+
+	"github.com/idoyudha/eshop-product/config"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/internal/usecase/repo"
+	"github.com/idoyudha/eshop-product/pkg/aws"
+	"github.com/idoyudha/eshop-product/pkg/imaging"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+	"github.com/idoyudha/eshop-product/pkg/redis"
+)
+
+// RunExport exports the products once, it backs the export admin command. The export
+// goes to the s3 key when it is set, to the file at out otherwise.
+func RunExport(cfg *config.Config, out, s3Key string, options entity.ExportOptions) {
+	l := logger.New(cfg.Log.Level)
+
+	s3, err := aws.NewS3(&cfg.AWS)
+	if err != nil {
+		l.Fatal("app - RunExport - aws.NewS3: ", err)
+	}
+
+	dynamoDB, err := aws.NewDynamoDB(&cfg.AWS)
+	if err != nil {
+		l.Fatal("app - RunExport - dynamodb.NewDynamoDB: ", err)
+	}
+
+	redisClient, err := redis.NewRedis(cfg.Redis)
+	if err != nil {
+		l.Fatal("app - RunExport - redis.NewRedis: ", err)
+	}
+
+	exportUseCase := usecase.NewExportUseCase(
+		repo.NewProductS3Repo(s3, imaging.NewValidator(imageLimits(cfg.Image)), cfg.Image.UploadURLTTL),
+		repo.NewProductDynamoDBRepo(dynamoDB),
+		repo.NewCategoryDynamoRepo(dynamoDB),
+		repo.NewCategoryRedisRepo(redisClient),
+	)
+
+	ctx := context.Background()
+
+	if s3Key != "" {
+		exported, err := exportUseCase.ExportProductsToS3(ctx, s3Key, options)
+		if err != nil {
+			l.Fatal("app - RunExport - exportUseCase.ExportProductsToS3: ", err)
+		}
+		l.Info("app - RunExport - exported %d products to s3 key %s", exported, s3Key)
+		return
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		l.Fatal("app - RunExport - os.Create: ", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	exported, err := exportUseCase.ExportProducts(ctx, w, options)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		l.Fatal("app - RunExport - exportUseCase.ExportProducts: ", err)
+	}
+	l.Info("app - RunExport - exported %d products to %s", exported, out)
+}
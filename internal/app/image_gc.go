@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/idoyudha/eshop-product/config"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/internal/usecase/repo"
+	"github.com/idoyudha/eshop-product/pkg/aws"
+	"github.com/idoyudha/eshop-product/pkg/imaging"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+)
+
+// RunImageGC collects the orphaned product images once, it backs the image-gc admin command
+func RunImageGC(cfg *config.Config, dryRun bool) {
+	l := logger.New(cfg.Log.Level)
+
+	s3, err := aws.NewS3(&cfg.AWS)
+	if err != nil {
+		l.Fatal("app - RunImageGC - aws.NewS3: ", err)
+	}
+
+	dynamoDB, err := aws.NewDynamoDB(&cfg.AWS)
+	if err != nil {
+		l.Fatal("app - RunImageGC - dynamodb.NewDynamoDB: ", err)
+	}
+
+	imageGCUseCase := usecase.NewImageGCUseCase(
+		repo.NewProductS3Repo(s3, imaging.NewValidator(imageLimits(cfg.Image)), cfg.Image.UploadURLTTL),
+		repo.NewProductDynamoDBRepo(dynamoDB),
+		cfg.ImageGC.GracePeriod,
+		cfg.Image.UploadURLTTL,
+	)
+
+	collectOrphanedImages(context.Background(), imageGCUseCase, dryRun, l)
+}
+
+func collectOrphanedImages(ctx context.Context, uc usecase.ImageGC, dryRun bool, l logger.Interface) {
+	report, err := uc.CollectOrphanedImages(ctx, dryRun)
+	if err != nil {
+		l.Error("app - collectOrphanedImages - uc.CollectOrphanedImages: ", err)
+		return
+	}
+
+	for _, orphan := range report.Orphans {
+		l.Info("app - collectOrphanedImages - orphan: %s (%d bytes, last modified %s)",
+			orphan.Key, orphan.Size, orphan.LastModified.Format(time.RFC3339))
+	}
+	for _, upload := range report.ExpiredUploads {
+		l.Info("app - collectOrphanedImages - expired upload: %s (%d bytes, last modified %s)",
+			upload.Key, upload.Size, upload.LastModified.Format(time.RFC3339))
+	}
+	l.Info("app - collectOrphanedImages - dry run: %t, scanned: %d, referenced: %d, in grace period: %d, orphans: %d (%d bytes), deleted: %d, expired uploads: %d, deleted uploads: %d",
+		report.DryRun, report.Scanned, report.Referenced, report.InGracePeriod, len(report.Orphans), report.OrphanBytes, report.Deleted,
+		len(report.ExpiredUploads), report.DeletedUploads)
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// runEvery calls job every interval until the context is cancelled,
+// the first call happens after one interval
+func runEvery(ctx context.Context, interval time.Duration, job func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job(ctx)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,33 +12,58 @@ import (
 )
 
 type categoryRoutes struct {
-	uc usecase.Category
-	l  logger.Interface
+	uc      usecase.Category
+	locales *entity.Locales
+	l       logger.Interface
 }
 
-func newCategoryRoutes(handler *gin.RouterGroup, uc usecase.Category, l logger.Interface) {
-	r := &categoryRoutes{uc: uc, l: l}
+func newCategoryRoutes(handler *gin.RouterGroup, uc usecase.Category, locales *entity.Locales, l logger.Interface) {
+	r := &categoryRoutes{uc: uc, locales: locales, l: l}
 
 	h := handler.Group("/categories")
 	{
 		h.POST("/", r.createCategory)
 		h.GET("/", r.getCategories)
 		h.GET("/:id", r.getCategoryByID)
+		h.GET("/slug/:slug", r.getCategoryBySlug)
 		h.GET("/parent/:id", r.getCategoriesByParentID)
 		h.PUT("/:id", r.updateCategory)
 		h.DELETE("/:id", r.deleteCategory)
+
+		h.GET("/:id/translations", r.getCategoryTranslations)
+		h.PUT("/:id/translations/:locale", r.setCategoryTranslation)
+		h.DELETE("/:id/translations/:locale", r.deleteCategoryTranslation)
 	}
 }
 
 type createCategoryRequest struct {
-	Name     string  `json:"name" binding:"required"`
-	ParentID *string `json:"parent_id" binding:"required"`
+	Name       string                       `json:"name" binding:"required"`
+	ParentID   *string                      `json:"parent_id" binding:"required"`
+	Attributes []attributeDefinitionRequest `json:"attributes" binding:"omitempty,dive"`
+}
+
+type attributeDefinitionRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Type          string   `json:"type" binding:"required,oneof=string integer decimal boolean enum"`
+	Required      bool     `json:"required"`
+	AllowedValues []string `json:"allowed_values"`
+	Unit          string   `json:"unit"`
 }
 
 type createCategoryResponse struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	ParentID *string `json:"parent_id"`
+	ID         string                        `json:"id"`
+	Name       string                        `json:"name"`
+	Slug       string                        `json:"slug"`
+	ParentID   *string                       `json:"parent_id"`
+	Attributes []attributeDefinitionResponse `json:"attributes"`
+}
+
+type attributeDefinitionResponse struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	Required      bool     `json:"required"`
+	AllowedValues []string `json:"allowed_values,omitempty"`
+	Unit          string   `json:"unit,omitempty"`
 }
 
 func (r *categoryRoutes) createCategory(c *gin.Context) {
@@ -52,6 +79,10 @@ func (r *categoryRoutes) createCategory(c *gin.Context) {
 	category, err := r.uc.CreateCategory(c.Request.Context(), &categoryEntity)
 	if err != nil {
 		r.l.Error(err, "http - v1 - categoryRoutes - createCategory")
+		if errors.Is(err, entity.ErrInvalidAttributeSchema) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
 		return
 	}
@@ -64,11 +95,13 @@ func (r *categoryRoutes) createCategory(c *gin.Context) {
 type getChildCategories struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+	Slug string `json:"slug"`
 }
 
 type getParentCategoriesResponse struct {
 	ID     string               `json:"id"`
 	Name   string               `json:"name"`
+	Slug   string               `json:"slug"`
 	Childs []getChildCategories `json:"childs"`
 }
 
@@ -80,6 +113,7 @@ func (r *categoryRoutes) getCategories(c *gin.Context) {
 		return
 	}
 
+	localizeCategories(c, r.locales, *categories)
 	categoriesResponse := categoryEntityToGetAllCategoryResponse(*categories)
 
 	c.JSON(http.StatusOK, newGetSuccess(categoriesResponse))
@@ -92,9 +126,40 @@ func (r *categoryRoutes) getCategoryByID(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
 		return
 	}
+	if category == nil {
+		c.JSON(http.StatusNotFound, newNotFoundError(fmt.Sprintf("%s, id: %s", entity.ErrCategoryNotFound, c.Param("id"))))
+		return
+	}
+
+	category.Localize(r.locales.Chain(requestLocale(c, r.locales)))
+	categoryResponse := categoryEntityToUpdateCategoryResponse(*category)
+
+	setETag(c, category.Version)
+	c.JSON(http.StatusOK, newGetSuccess(categoryResponse))
+}
+
+func (r *categoryRoutes) getCategoryBySlug(c *gin.Context) {
+	category, err := r.uc.GetCategoryBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - categoryRoutes - getCategoryBySlug")
+		if errors.Is(err, entity.ErrCategoryNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
 
+	// the category was found by an old slug
+	if category.Slug != c.Param("slug") {
+		redirectToSlug(c, category.ID, category.Slug)
+		return
+	}
+
+	category.Localize(r.locales.Chain(requestLocale(c, r.locales)))
 	categoryResponse := categoryEntityToUpdateCategoryResponse(*category)
 
+	setETag(c, category.Version)
 	c.JSON(http.StatusOK, newGetSuccess(categoryResponse))
 }
 
@@ -106,18 +171,23 @@ func (r *categoryRoutes) getCategoriesByParentID(c *gin.Context) {
 		return
 	}
 
+	localizeCategories(c, r.locales, *categories)
 	categoriesResponse := categoriesEntityToGetChildCategoryResponse(*categories)
 
 	c.JSON(http.StatusOK, newGetSuccess(categoriesResponse))
 }
 
 type updateCategoryRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name       string                       `json:"name" binding:"required"`
+	Attributes []attributeDefinitionRequest `json:"attributes" binding:"omitempty,dive"` // replaces the schema when given
 }
 
 type updateCategoryResponse struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID         string                        `json:"id"`
+	Name       string                        `json:"name"`
+	Slug       string                        `json:"slug"`
+	Attributes []attributeDefinitionResponse `json:"attributes,omitempty"`
+	Version    int64                         `json:"version"`
 }
 
 func (r *categoryRoutes) updateCategory(c *gin.Context) {
@@ -128,20 +198,47 @@ func (r *categoryRoutes) updateCategory(c *gin.Context) {
 		return
 	}
 
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		r.l.Error(err, "http - v1 - categoryRoutes - updateCategory")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
 	category := entity.Category{
-		ID:   c.Param("id"),
-		Name: request.Name,
+		ID:         c.Param("id"),
+		Name:       request.Name,
+		Attributes: attributeDefinitionRequestsToEntity(request.Attributes),
+		Version:    version,
 	}
 
-	err := r.uc.UpdateCategory(c.Request.Context(), &category)
+	err = r.uc.UpdateCategory(c.Request.Context(), &category)
 	if err != nil {
 		r.l.Error(err, "http - v1 - categoryRoutes - updateCategory")
+		var mismatch *entity.VersionMismatchError
+		if errors.As(err, &mismatch) {
+			versionMismatch(c, mismatch.Current, err)
+			return
+		}
+		if errors.Is(err, entity.ErrConcurrentUpdate) {
+			c.JSON(http.StatusConflict, newConflictError(err.Error()))
+			return
+		}
+		if errors.Is(err, entity.ErrInvalidAttributeSchema) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		if errors.Is(err, entity.ErrCategoryNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
 		return
 	}
 
 	categoryResponse := categoryEntityToUpdateCategoryResponse(category)
 
+	setETag(c, category.Version)
 	c.JSON(http.StatusOK, newUpdateSuccess(categoryResponse))
 }
 
@@ -155,3 +252,80 @@ func (r *categoryRoutes) deleteCategory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, newDeleteSuccess())
 }
+
+type categoryTranslationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type categoryTranslationResponse struct {
+	Name string `json:"name"`
+}
+
+type categoryTranslationsResponse struct {
+	ID            string                                 `json:"id"`
+	DefaultLocale string                                 `json:"default_locale"`
+	Translations  map[string]categoryTranslationResponse `json:"translations"`
+}
+
+func (r *categoryRoutes) getCategoryTranslations(c *gin.Context) {
+	category, err := r.uc.GetCategoryByID(c.Request.Context(), c.Param("id"))
+	if err == nil && category == nil {
+		err = fmt.Errorf("%w, id: %s", entity.ErrCategoryNotFound, c.Param("id"))
+	}
+	if err != nil {
+		r.l.Error(err, "http - v1 - categoryRoutes - getCategoryTranslations")
+		if errors.Is(err, entity.ErrCategoryNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(categoryEntityToCategoryTranslationsResponse(*category, r.locales.Default)))
+}
+
+func (r *categoryRoutes) setCategoryTranslation(c *gin.Context) {
+	var request categoryTranslationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - categoryRoutes - setCategoryTranslation")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	category, err := r.uc.SetCategoryTranslation(
+		c.Request.Context(),
+		c.Param("id"),
+		c.Param("locale"),
+		entity.CategoryTranslation(request),
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - categoryRoutes - setCategoryTranslation")
+		r.translationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(categoryEntityToCategoryTranslationsResponse(*category, r.locales.Default)))
+}
+
+func (r *categoryRoutes) deleteCategoryTranslation(c *gin.Context) {
+	category, err := r.uc.DeleteCategoryTranslation(c.Request.Context(), c.Param("id"), c.Param("locale"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - categoryRoutes - deleteCategoryTranslation")
+		r.translationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(categoryEntityToCategoryTranslationsResponse(*category, r.locales.Default)))
+}
+
+func (r *categoryRoutes) translationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, entity.ErrInvalidLocale):
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+	case errors.Is(err, entity.ErrCategoryNotFound), errors.Is(err, entity.ErrTranslationNotFound):
+		c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+	}
+}
@@ -1,9 +1,12 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/pkg/imaging"
 )
 
 type response struct {
@@ -21,6 +24,7 @@ type restError struct {
 
 type errorMessage struct {
 	Message string `json:"message"`
+	Reason  string `json:"reason,omitempty"`
 	Causes  error  `json:"causes"`
 }
 
@@ -42,6 +46,25 @@ func newNotFoundError(message string) *restError {
 	}
 }
 
+func newConflictError(message string) *restError {
+	return &restError{
+		Code: http.StatusConflict,
+		Error: errorMessage{
+			Message: message,
+		},
+	}
+}
+
+func newPreconditionFailedError(message string) *restError {
+	return &restError{
+		Code: http.StatusPreconditionFailed,
+		Error: errorMessage{
+			Message: message,
+			Reason:  "version_mismatch",
+		},
+	}
+}
+
 func newInternalServerError(message string) *restError {
 	return &restError{
 		Code: http.StatusInternalServerError,
@@ -50,3 +73,40 @@ func newInternalServerError(message string) *restError {
 		},
 	}
 }
+
+// newImageValidationError maps a rejected upload to 415 for the format and 400 otherwise
+func newImageValidationError(err error) *restError {
+	restErr := &restError{
+		Code: http.StatusBadRequest,
+		Error: errorMessage{
+			Message: err.Error(),
+			Reason:  "invalid_image",
+		},
+	}
+
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		restErr.Code = http.StatusUnsupportedMediaType
+		restErr.Error.Reason = "unsupported_format"
+	case errors.Is(err, imaging.ErrImageTooLarge):
+		restErr.Error.Reason = "image_too_large"
+	case errors.Is(err, entity.ErrImageUploadNotFound):
+		restErr.Error.Reason = "upload_not_found"
+	case errors.Is(err, entity.ErrImageSourceRequired):
+		restErr.Error.Reason = "image_required"
+	case errors.Is(err, entity.ErrInvalidImageKey):
+		restErr.Error.Reason = "invalid_image_key"
+	}
+
+	return restErr
+}
+
+// isImageValidationError reports whether the image sent by the client was rejected
+func isImageValidationError(err error) bool {
+	return errors.Is(err, imaging.ErrUnsupportedFormat) ||
+		errors.Is(err, imaging.ErrImageTooLarge) ||
+		errors.Is(err, imaging.ErrInvalidImage) ||
+		errors.Is(err, entity.ErrImageUploadNotFound) ||
+		errors.Is(err, entity.ErrImageSourceRequired) ||
+		errors.Is(err, entity.ErrInvalidImageKey)
+}
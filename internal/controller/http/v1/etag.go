@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidIfMatch = errors.New(`invalid If-Match, expected the ETag of the resource like "3"`)
+
+// setETag sends the version of the resource as a strong entity tag
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatchVersion returns the version the client expects from If-Match, 0 when the header
+// is left out or is "*". The write is then conditioned on the version the update read, a
+// concurrent write makes it fail with 409 instead of 412
+func ifMatchVersion(c *gin.Context) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, errInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errInvalidIfMatch
+	}
+	return version, nil
+}
+
+// versionMismatch answers a write that expected another version with 412, the ETag
+// and the error carry the current version
+func versionMismatch(c *gin.Context, current int64, err error) {
+	setETag(c, current)
+	c.JSON(http.StatusPreconditionFailed, newPreconditionFailedError(err.Error()))
+}
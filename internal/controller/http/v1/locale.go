@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"golang.org/x/text/language"
+)
+
+// requestLocale negotiates the locale of the response, the locale query parameter goes
+// before the Accept-Language header. The chosen one is sent back as Content-Language.
+func requestLocale(c *gin.Context, locales *entity.Locales) string {
+	var requested []string
+	if locale := c.Query("locale"); locale != "" {
+		requested = append(requested, locale)
+	}
+
+	// the tags come sorted by their quality, an unparsable header is ignored
+	tags, _, _ := language.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		requested = append(requested, tag.String(), base.String())
+	}
+
+	locale := locales.Match(requested...)
+	c.Header("Content-Language", locale)
+	return locale
+}
+
+// localizeProducts shows the products in the locale of the request
+func localizeProducts(c *gin.Context, locales *entity.Locales, products []entity.Product) {
+	chain := locales.Chain(requestLocale(c, locales))
+	for i := range products {
+		products[i].Localize(chain)
+	}
+}
+
+func localizeCategories(c *gin.Context, locales *entity.Locales, categories []entity.Category) {
+	chain := locales.Chain(requestLocale(c, locales))
+	for i := range categories {
+		categories[i].Localize(chain)
+	}
+}
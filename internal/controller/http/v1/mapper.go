@@ -10,24 +10,43 @@ func createProductRequestToProductEntity(request createProductRequest) entity.Pr
 	return entity.Product{
 		Name:        request.Name,
 		Description: request.Description,
-		Price:       request.Price,
+		Price:       entity.Money{Amount: request.Price, Currency: request.Currency},
 		Quantity:    request.Quantity,
+		Status:      entity.ProductStatus(request.Status),
+		PublishAt:   request.PublishAt,
 		CategoryID:  request.CategoryID,
+		Type:        entity.ProductType(request.Type),
+		Version:     1,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+
+		ReorderThreshold: request.ReorderThreshold,
 	}
 }
 
 func productEntityToProductResponse(product entity.Product) createProductResponse {
 	return createProductResponse{
-		ID:          product.ID,
-		SKU:         product.SKU,
-		Name:        product.Name,
-		ImageURL:    product.ImageURL,
-		Description: product.Description,
-		Price:       product.Price,
-		Quantity:    product.Quantity,
-		CategoryID:  product.CategoryID,
+		ID:                product.ID,
+		SKU:               product.SKU,
+		Name:              product.Name,
+		Slug:              product.Slug,
+		ImageURL:          product.ImageURL,
+		Images:            productImagesToProductImageResponse(product.Images),
+		Description:       product.Description,
+		Price:             moneyResponse(product.Price),
+		Prices:            moneyListToResponse(product.Prices),
+		SalePrice:         moneyResponse(product.SalePrice),
+		AppliedPromotions: appliedPromotionsToResponse(product.AppliedPromotions),
+		Status:            string(product.Status),
+		PublishAt:         product.PublishAt,
+		PublishedAt:       product.PublishedAt,
+		Quantity:          product.Quantity,
+		Version:           product.Version,
+		CategoryID:        product.CategoryID,
+		Attributes:        product.Attributes,
+		Type:              string(product.Type),
+		Bundle:            bundleEntityToResponse(product.Bundle),
+		ReorderThreshold:  product.ReorderThreshold,
 	}
 }
 
@@ -36,9 +55,11 @@ func updateProductRequestToProductEntity(request updateProductRequest, id string
 		ID:          id,
 		Name:        request.Name,
 		Description: request.Description,
-		Price:       request.Price,
+		Price:       entity.Money{Amount: request.Price, Currency: request.Currency},
 		CategoryID:  request.CategoryID,
 		UpdatedAt:   time.Now(),
+
+		ReorderThreshold: request.ReorderThreshold,
 	}
 }
 
@@ -46,10 +67,17 @@ func productEntityToUpdateProductResponse(product entity.Product) updateProductR
 	return updateProductResponse{
 		ID:          product.ID,
 		Name:        product.Name,
+		Slug:        product.Slug,
 		ImageURL:    product.ImageURL,
+		Images:      productImagesToProductImageResponse(product.Images),
 		Description: product.Description,
-		Price:       product.Price,
+		Price:       moneyResponse(product.Price),
+		Prices:      moneyListToResponse(product.Prices),
 		CategoryID:  product.CategoryID,
+		Attributes:  product.Attributes,
+		Version:     product.Version,
+
+		ReorderThreshold: product.ReorderThreshold,
 	}
 }
 
@@ -57,14 +85,24 @@ func productEntitiesToGetProductResponse(product []entity.Product) []getProductR
 	var response []getProductResponse
 	for _, p := range product {
 		response = append(response, getProductResponse{
-			ID:          p.ID,
-			SKU:         p.SKU,
-			Name:        p.Name,
-			ImageURL:    p.ImageURL,
-			Description: p.Description,
-			Price:       p.Price,
-			Quantity:    p.Quantity,
-			CategoryID:  p.CategoryID,
+			ID:                p.ID,
+			SKU:               p.SKU,
+			Name:              p.Name,
+			Slug:              p.Slug,
+			ImageURL:          p.ImageURL,
+			Images:            productImagesToProductImageResponse(p.Images),
+			Description:       p.Description,
+			Price:             moneyResponse(p.Price),
+			Prices:            moneyListToResponse(p.Prices),
+			SalePrice:         moneyResponse(p.SalePrice),
+			AppliedPromotions: appliedPromotionsToResponse(p.AppliedPromotions),
+			Status:            string(p.Status),
+			PublishAt:         p.PublishAt,
+			PublishedAt:       p.PublishedAt,
+			Quantity:          p.Quantity,
+			Version:           p.Version,
+			CategoryID:        p.CategoryID,
+			Attributes:        p.Attributes,
 		})
 	}
 	return response
@@ -72,31 +110,206 @@ func productEntitiesToGetProductResponse(product []entity.Product) []getProductR
 
 func productEntityToGetProductResponse(product entity.Product) getProductResponse {
 	return getProductResponse{
-		ID:          product.ID,
-		SKU:         product.SKU,
-		Name:        product.Name,
-		ImageURL:    product.ImageURL,
-		Description: product.Description,
-		Price:       product.Price,
-		Quantity:    product.Quantity,
-		CategoryID:  product.CategoryID,
+		ID:                product.ID,
+		SKU:               product.SKU,
+		Name:              product.Name,
+		Slug:              product.Slug,
+		ImageURL:          product.ImageURL,
+		Images:            productImagesToProductImageResponse(product.Images),
+		Description:       product.Description,
+		Price:             moneyResponse(product.Price),
+		Prices:            moneyListToResponse(product.Prices),
+		SalePrice:         moneyResponse(product.SalePrice),
+		AppliedPromotions: appliedPromotionsToResponse(product.AppliedPromotions),
+		Status:            string(product.Status),
+		PublishAt:         product.PublishAt,
+		PublishedAt:       product.PublishedAt,
+		Quantity:          product.Quantity,
+		Version:           product.Version,
+		CategoryID:        product.CategoryID,
+		Attributes:        product.Attributes,
+		Type:              string(product.Type),
+		Bundle:            bundleEntityToResponse(product.Bundle),
 	}
 }
 
-func createCategoryRequestToCategoryEntity(request createCategoryRequest) entity.Category {
-	return entity.Category{
-		Name:      request.Name,
-		ParentID:  request.ParentID,
+func productSearchHitsToSearchProductResponse(hits []entity.ProductSearchHit) []searchProductResponse {
+	response := make([]searchProductResponse, 0, len(hits))
+	for _, h := range hits {
+		response = append(response, searchProductResponse{
+			getProductResponse: productEntityToGetProductResponse(h.Product),
+			Score:              h.Score,
+		})
+	}
+	return response
+}
+
+// moneyRequestToEntity converts an optional amount, nil stays nil
+func moneyRequestToEntity(request *moneyRequest) *entity.Money {
+	if request == nil {
+		return nil
+	}
+	money := entity.Money(*request)
+	return &money
+}
+
+func moneyToResponse(money *entity.Money) *moneyResponse {
+	if money == nil {
+		return nil
+	}
+	response := moneyResponse(*money)
+	return &response
+}
+
+func moneyListToResponse(prices []entity.Money) []moneyResponse {
+	response := make([]moneyResponse, 0, len(prices))
+	for _, price := range prices {
+		response = append(response, moneyResponse(price))
+	}
+	return response
+}
+
+func moneyRequestsToEntity(requests []moneyRequest) []entity.Money {
+	prices := make([]entity.Money, 0, len(requests))
+	for _, request := range requests {
+		prices = append(prices, entity.Money(request))
+	}
+	return prices
+}
+
+func appliedPromotionsToResponse(promotions []entity.AppliedPromotion) []appliedPromotionResponse {
+	response := make([]appliedPromotionResponse, 0, len(promotions))
+	for _, p := range promotions {
+		promotion := appliedPromotionResponse{
+			PromotionID:  p.PromotionID,
+			Name:         p.Name,
+			DiscountType: string(p.DiscountType),
+			Value:        p.Value,
+			Discount:     moneyResponse(p.Discount),
+		}
+		if p.DiscountType == entity.DiscountFixed {
+			amount := moneyResponse(p.Amount)
+			promotion.Amount = &amount
+		}
+		response = append(response, promotion)
+	}
+	return response
+}
+
+func createVariantRequestToVariantEntity(request createVariantRequest, productID string) entity.Variant {
+	return entity.Variant{
+		ProductID: productID,
+		Options:   request.Options,
+		Price:     moneyRequestToEntity(request.Price),
+		Quantity:  request.Quantity,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 }
 
+func updateVariantRequestToVariantEntity(request updateVariantRequest, productID, variantID string) entity.Variant {
+	return entity.Variant{
+		ID:        variantID,
+		ProductID: productID,
+		Options:   request.Options,
+		Price:     moneyRequestToEntity(request.Price),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func variantEntityToVariantResponse(variant entity.Variant) variantResponse {
+	return variantResponse{
+		ID:        variant.ID,
+		ProductID: variant.ProductID,
+		SKU:       variant.SKU,
+		Options:   variant.Options,
+		Price:     moneyToResponse(variant.Price),
+		Quantity:  variant.Quantity,
+	}
+}
+
+func variantEntitiesToVariantResponse(variants []entity.Variant) []variantResponse {
+	response := make([]variantResponse, 0, len(variants))
+	for _, v := range variants {
+		response = append(response, variantEntityToVariantResponse(v))
+	}
+	return response
+}
+
+func paginationQueryToPagination(query paginationQuery) entity.Pagination {
+	page := entity.Pagination{
+		Limit:  query.Limit,
+		Cursor: query.Cursor,
+	}
+	if page.Limit == 0 {
+		page.Limit = entity.DefaultPageLimit
+	}
+	return page
+}
+
+func productFilterQueryToProductQuery(query productFilterQuery) (entity.ProductFilter, entity.ProductSort) {
+	filter := entity.ProductFilter{
+		CategoryID: query.CategoryID,
+		Name:       query.Name,
+		InStock:    query.InStock,
+	}
+
+	currency := query.Currency
+	if currency == "" {
+		currency = entity.DefaultCurrency
+	}
+	if query.MinPrice != nil {
+		filter.MinPrice = &entity.Money{Amount: *query.MinPrice, Currency: currency}
+	}
+	if query.MaxPrice != nil {
+		filter.MaxPrice = &entity.Money{Amount: *query.MaxPrice, Currency: currency}
+	}
+
+	sort := entity.ProductSort{
+		Field: entity.ProductSortField(query.SortBy),
+		Desc:  query.Order == "desc",
+	}
+	return filter, sort
+}
+
+func productImagesToProductImageResponse(images []entity.ProductImage) []productImageResponse {
+	response := make([]productImageResponse, 0, len(images))
+	for _, image := range images {
+		renditions := make([]imageRenditionResponse, 0, len(image.Renditions))
+		for _, rendition := range image.Renditions {
+			renditions = append(renditions, imageRenditionResponse(rendition))
+		}
+
+		response = append(response, productImageResponse{
+			ID:         image.ID,
+			URL:        image.URL,
+			AltText:    image.AltText,
+			Position:   image.Position,
+			IsPrimary:  image.IsPrimary,
+			Renditions: renditions,
+		})
+	}
+	return response
+}
+
+func createCategoryRequestToCategoryEntity(request createCategoryRequest) entity.Category {
+	return entity.Category{
+		Name:       request.Name,
+		ParentID:   request.ParentID,
+		Attributes: attributeDefinitionRequestsToEntity(request.Attributes),
+		Version:    1,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+}
+
 func categoryEntityToCreateCategoryResponse(category entity.Category) createCategoryResponse {
 	return createCategoryResponse{
-		ID:       category.ID,
-		Name:     category.Name,
-		ParentID: category.ParentID,
+		ID:         category.ID,
+		Name:       category.Name,
+		Slug:       category.Slug,
+		ParentID:   category.ParentID,
+		Attributes: attributeDefinitionsToResponse(category.Attributes),
 	}
 }
 
@@ -108,6 +321,7 @@ func categoryEntityToGetAllCategoryResponse(categories []entity.Category) []getP
 			parentCategories = append(parentCategories, getParentCategoriesResponse{
 				ID:     c.ID,
 				Name:   c.Name,
+				Slug:   c.Slug,
 				Childs: []getChildCategories{},
 			})
 		}
@@ -119,6 +333,7 @@ func categoryEntityToGetAllCategoryResponse(categories []entity.Category) []getP
 			childCategory = getChildCategories{
 				ID:   c.ID,
 				Name: c.Name,
+				Slug: c.Slug,
 			}
 		}
 
@@ -132,10 +347,49 @@ func categoryEntityToGetAllCategoryResponse(categories []entity.Category) []getP
 }
 
 func categoryEntityToUpdateCategoryResponse(category entity.Category) updateCategoryResponse {
-	return updateCategoryResponse{
-		ID:   category.ID,
-		Name: category.Name,
+	response := updateCategoryResponse{
+		ID:      category.ID,
+		Name:    category.Name,
+		Slug:    category.Slug,
+		Version: category.Version,
+	}
+	if category.Attributes != nil {
+		response.Attributes = attributeDefinitionsToResponse(category.Attributes)
 	}
+	return response
+}
+
+// attributeDefinitionRequestsToEntity keeps a missing schema nil, an update then leaves it as it is
+func attributeDefinitionRequestsToEntity(requests []attributeDefinitionRequest) []entity.AttributeDefinition {
+	if requests == nil {
+		return nil
+	}
+
+	definitions := make([]entity.AttributeDefinition, 0, len(requests))
+	for _, r := range requests {
+		definitions = append(definitions, entity.AttributeDefinition{
+			Name:          r.Name,
+			Type:          entity.AttributeType(r.Type),
+			Required:      r.Required,
+			AllowedValues: r.AllowedValues,
+			Unit:          r.Unit,
+		})
+	}
+	return definitions
+}
+
+func attributeDefinitionsToResponse(definitions []entity.AttributeDefinition) []attributeDefinitionResponse {
+	response := make([]attributeDefinitionResponse, 0, len(definitions))
+	for _, d := range definitions {
+		response = append(response, attributeDefinitionResponse{
+			Name:          d.Name,
+			Type:          string(d.Type),
+			Required:      d.Required,
+			AllowedValues: d.AllowedValues,
+			Unit:          d.Unit,
+		})
+	}
+	return response
 }
 
 func categoriesEntityToGetChildCategoryResponse(categories []entity.Category) []getChildCategories {
@@ -144,6 +398,304 @@ func categoriesEntityToGetChildCategoryResponse(categories []entity.Category) []
 		response = append(response, getChildCategories{
 			ID:   c.ID,
 			Name: c.Name,
+			Slug: c.Slug,
+		})
+	}
+	return response
+}
+
+func imageUploadEntityToCreateImageUploadResponse(upload entity.ImageUpload) createImageUploadResponse {
+	return createImageUploadResponse{
+		UploadToken: upload.Token,
+		UploadURL:   upload.URL,
+		Headers:     upload.Headers,
+		ExpiresAt:   upload.ExpiresAt,
+	}
+}
+
+func priceHistoryEntryToResponse(entry entity.PriceHistoryEntry) priceHistoryResponse {
+	return priceHistoryResponse{
+		ID:                entry.ID,
+		ProductID:         entry.ProductID,
+		Price:             moneyResponse(entry.Price),
+		PreviousPrice:     moneyResponse(entry.PreviousPrice),
+		EffectiveFrom:     entry.EffectiveFrom,
+		Actor:             entry.Actor,
+		Source:            string(entry.Source),
+		ScheduledChangeID: entry.ScheduledChangeID,
+	}
+}
+
+func priceHistoryEntriesToResponse(entries []entity.PriceHistoryEntry) []priceHistoryResponse {
+	response := make([]priceHistoryResponse, 0, len(entries))
+	for _, e := range entries {
+		response = append(response, priceHistoryEntryToResponse(e))
+	}
+	return response
+}
+
+func scheduledPriceChangeToResponse(change entity.ScheduledPriceChange) scheduledPriceChangeResponse {
+	return scheduledPriceChangeResponse{
+		ID:            change.ID,
+		ProductID:     change.ProductID,
+		Price:         moneyResponse(change.Price),
+		EffectiveFrom: change.EffectiveFrom,
+		Actor:         change.Actor,
+		Status:        string(change.Status),
+		CreatedAt:     change.CreatedAt,
+	}
+}
+
+func scheduledPriceChangesToResponse(changes []entity.ScheduledPriceChange) []scheduledPriceChangeResponse {
+	response := make([]scheduledPriceChangeResponse, 0, len(changes))
+	for _, c := range changes {
+		response = append(response, scheduledPriceChangeToResponse(c))
+	}
+	return response
+}
+
+func promotionRequestToPromotionEntity(request promotionRequest, id string) entity.Promotion {
+	targets := make([]entity.PromotionTarget, 0, len(request.Targets))
+	for _, t := range request.Targets {
+		targets = append(targets, entity.PromotionTarget{
+			Type: entity.PromotionTargetType(t.Type),
+			ID:   t.ID,
+		})
+	}
+
+	promotion := entity.Promotion{
+		ID:           id,
+		Name:         request.Name,
+		DiscountType: entity.DiscountType(request.DiscountType),
+		Value:        request.Value,
+		Targets:      targets,
+		Priority:     request.Priority,
+		Stackable:    request.Stackable,
+		StartsAt:     request.StartsAt,
+		EndsAt:       request.EndsAt,
+	}
+	if request.Amount != nil {
+		promotion.Amount = entity.Money(*request.Amount)
+	}
+	return promotion
+}
+
+func promotionEntityToResponse(promotion entity.Promotion) promotionResponse {
+	targets := make([]promotionTargetResponse, 0, len(promotion.Targets))
+	for _, t := range promotion.Targets {
+		targets = append(targets, promotionTargetResponse{
+			Type: string(t.Type),
+			ID:   t.ID,
+		})
+	}
+
+	var amount *moneyResponse
+	if promotion.DiscountType == entity.DiscountFixed {
+		amount = moneyToResponse(&promotion.Amount)
+	}
+
+	return promotionResponse{
+		ID:           promotion.ID,
+		Name:         promotion.Name,
+		DiscountType: string(promotion.DiscountType),
+		Value:        promotion.Value,
+		Amount:       amount,
+		Targets:      targets,
+		Priority:     promotion.Priority,
+		Stackable:    promotion.Stackable,
+		StartsAt:     promotion.StartsAt,
+		EndsAt:       promotion.EndsAt,
+		CreatedAt:    promotion.CreatedAt,
+		UpdatedAt:    promotion.UpdatedAt,
+	}
+}
+
+func promotionEntitiesToResponse(promotions []entity.Promotion) []promotionResponse {
+	response := make([]promotionResponse, 0, len(promotions))
+	for _, p := range promotions {
+		response = append(response, promotionEntityToResponse(p))
+	}
+	return response
+}
+
+func productEntityToTrashResponse(product entity.Product) trashProductResponse {
+	return trashProductResponse{
+		ID:         product.ID,
+		SKU:        product.SKU,
+		Name:       product.Name,
+		CategoryID: product.CategoryID,
+		Status:     string(product.Status),
+		DeletedAt:  product.DeletedAt,
+	}
+}
+
+func productEntitiesToTrashResponse(products []entity.Product) []trashProductResponse {
+	response := make([]trashProductResponse, 0, len(products))
+	for _, product := range products {
+		response = append(response, productEntityToTrashResponse(product))
+	}
+	return response
+}
+
+func categoryEntityToTrashResponse(category entity.Category) trashCategoryResponse {
+	return trashCategoryResponse{
+		ID:        category.ID,
+		Name:      category.Name,
+		ParentID:  category.ParentID,
+		DeletedAt: category.DeletedAt,
+	}
+}
+
+func categoryEntitiesToTrashResponse(categories []entity.Category) []trashCategoryResponse {
+	response := make([]trashCategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		response = append(response, categoryEntityToTrashResponse(category))
+	}
+	return response
+}
+
+func importJobEntityToResponse(job entity.ImportJob) importJobResponse {
+	return importJobResponse{
+		ID:         job.ID,
+		FileName:   job.FileName,
+		Status:     string(job.Status),
+		TotalRows:  job.TotalRows,
+		Succeeded:  job.Succeeded,
+		Failed:     job.Failed,
+		Error:      job.Error,
+		CreatedBy:  job.CreatedBy,
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+func importRowErrorsToResponse(rowErrors []entity.ImportRowError) []importRowErrorResponse {
+	response := make([]importRowErrorResponse, 0, len(rowErrors))
+	for _, rowError := range rowErrors {
+		response = append(response, importRowErrorResponse{
+			Row:     rowError.Row,
+			Field:   rowError.Field,
+			Message: rowError.Message,
+		})
+	}
+	return response
+}
+
+func productEntityToProductTranslationsResponse(product entity.Product, defaultLocale string) productTranslationsResponse {
+	translations := make(map[string]productTranslationResponse, len(product.Translations))
+	for locale, translation := range product.Translations {
+		translations[locale] = productTranslationResponse(translation)
+	}
+	return productTranslationsResponse{
+		ID:            product.ID,
+		DefaultLocale: defaultLocale,
+		Translations:  translations,
+	}
+}
+
+func categoryEntityToCategoryTranslationsResponse(category entity.Category, defaultLocale string) categoryTranslationsResponse {
+	translations := make(map[string]categoryTranslationResponse, len(category.Translations))
+	for locale, translation := range category.Translations {
+		translations[locale] = categoryTranslationResponse(translation)
+	}
+	return categoryTranslationsResponse{
+		ID:            category.ID,
+		DefaultLocale: defaultLocale,
+		Translations:  translations,
+	}
+}
+
+func linkProductsRequestToProductLinkEntity(request linkProductsRequest, productID string) entity.ProductLink {
+	return entity.ProductLink{
+		ProductID:       productID,
+		LinkedProductID: request.LinkedProductID,
+		Type:            entity.ProductLinkType(request.Type),
+		Position:        request.Position,
+	}
+}
+
+func productLinkEntityToProductLinkResponse(link entity.ProductLink) productLinkResponse {
+	return productLinkResponse{
+		ProductID:       link.ProductID,
+		LinkedProductID: link.LinkedProductID,
+		Type:            string(link.Type),
+		Position:        link.Position,
+	}
+}
+
+func relatedProductEntitiesToRelatedProductResponse(related []entity.RelatedProduct) []relatedProductResponse {
+	response := make([]relatedProductResponse, 0, len(related))
+	for _, r := range related {
+		response = append(response, relatedProductResponse{
+			getProductResponse: productEntityToGetProductResponse(r.Product),
+			LinkType:           string(r.Type),
+			Source:             string(r.Source),
+			Score:              r.Score,
+		})
+	}
+	return response
+}
+
+func bundleRequestToEntity(request bundleRequest) *entity.Bundle {
+	components := make([]entity.BundleComponent, 0, len(request.Components))
+	for _, component := range request.Components {
+		components = append(components, entity.BundleComponent(component))
+	}
+	return &entity.Bundle{
+		Components:      components,
+		Pricing:         entity.BundlePricing(request.Pricing),
+		DiscountPercent: request.DiscountPercent,
+	}
+}
+
+// bundleEntityToResponse converts the bundle of a product, nil for a simple product
+func bundleEntityToResponse(bundle *entity.Bundle) *bundleResponse {
+	if bundle == nil {
+		return nil
+	}
+
+	components := make([]bundleComponentResponse, 0, len(bundle.Components))
+	for _, component := range bundle.Components {
+		components = append(components, bundleComponentResponse(component))
+	}
+	return &bundleResponse{
+		Components:      components,
+		Pricing:         string(bundle.Pricing),
+		DiscountPercent: bundle.DiscountPercent,
+	}
+}
+
+func stockMovementsToResponse(movements []entity.StockMovement) []stockMovementResponse {
+	response := make([]stockMovementResponse, 0, len(movements))
+	for _, m := range movements {
+		response = append(response, stockMovementResponse{
+			EventID:   m.EventID,
+			ProductID: m.ProductID,
+			VariantID: m.VariantID,
+			Delta:     m.Delta,
+			Quantity:  m.Quantity,
+			Reason:    string(m.Reason),
+			Source:    m.Source,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return response
+}
+
+func lowStockProductsToResponse(report []entity.LowStockProduct) []lowStockProductResponse {
+	response := make([]lowStockProductResponse, 0, len(report))
+	for _, r := range report {
+		response = append(response, lowStockProductResponse{
+			ID:         r.Product.ID,
+			SKU:        r.Product.SKU,
+			Name:       r.Product.Name,
+			CategoryID: r.Product.CategoryID,
+			Status:     string(r.Product.Status),
+			Quantity:   r.Product.Quantity,
+			Reserved:   r.Product.Reserved,
+			Threshold:  r.Threshold,
+			Level:      string(r.Level),
 		})
 	}
 	return response
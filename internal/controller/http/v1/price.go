@@ -0,0 +1,160 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+)
+
+type priceRoutes struct {
+	uc usecase.Price
+	l  logger.Interface
+}
+
+func newPriceRoutes(handler *gin.RouterGroup, uc usecase.Price, l logger.Interface) {
+	r := &priceRoutes{uc: uc, l: l}
+
+	h := handler.Group("/products/:id/prices")
+	{
+		h.GET("", r.getPriceHistory)
+		h.POST("/schedules", r.schedulePriceChange)
+		h.GET("/schedules", r.getScheduledPriceChanges)
+		h.DELETE("/schedules/:schedule_id", r.cancelScheduledPriceChange)
+	}
+}
+
+type getPriceHistoryQuery struct {
+	paginationQuery
+	At *time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type priceHistoryResponse struct {
+	ID                string        `json:"id"`
+	ProductID         string        `json:"product_id"`
+	Price             moneyResponse `json:"price"`
+	PreviousPrice     moneyResponse `json:"previous_price"`
+	EffectiveFrom     time.Time     `json:"effective_from"`
+	Actor             string        `json:"actor"`
+	Source            string        `json:"source"`
+	ScheduledChangeID string        `json:"scheduled_change_id,omitempty"`
+}
+
+// getPriceHistory lists the history newest first, with ?at= it returns the price in effect at that time
+func (r *priceRoutes) getPriceHistory(c *gin.Context) {
+	var query getPriceHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - priceRoutes - getPriceHistory")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	if query.At != nil {
+		entry, err := r.uc.GetPriceAt(c.Request.Context(), c.Param("id"), *query.At)
+		if err != nil {
+			r.l.Error(err, "http - v1 - priceRoutes - getPriceHistory")
+			if errors.Is(err, entity.ErrPriceHistoryNotFound) {
+				c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+			return
+		}
+
+		c.JSON(http.StatusOK, newGetSuccess(priceHistoryEntryToResponse(*entry)))
+		return
+	}
+
+	entries, nextCursor, err := r.uc.GetPriceHistory(c.Request.Context(), c.Param("id"), paginationQueryToPagination(query.paginationQuery))
+	if err != nil {
+		r.l.Error(err, "http - v1 - priceRoutes - getPriceHistory")
+		if errors.Is(err, entity.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetPageSuccess(priceHistoryEntriesToResponse(entries), nextCursor))
+}
+
+type schedulePriceChangeRequest struct {
+	Price         moneyRequest `json:"price" binding:"required"` // in the currency of the product
+	EffectiveFrom time.Time    `json:"effective_from" binding:"required"`
+}
+
+type scheduledPriceChangeResponse struct {
+	ID            string        `json:"id"`
+	ProductID     string        `json:"product_id"`
+	Price         moneyResponse `json:"price"`
+	EffectiveFrom time.Time     `json:"effective_from"`
+	Actor         string        `json:"actor"`
+	Status        string        `json:"status"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+func (r *priceRoutes) schedulePriceChange(c *gin.Context) {
+	var request schedulePriceChangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - priceRoutes - schedulePriceChange")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	changeEntity := entity.ScheduledPriceChange{
+		ProductID:     c.Param("id"),
+		Price:         entity.Money(request.Price),
+		EffectiveFrom: request.EffectiveFrom,
+		Actor:         actor(c),
+	}
+
+	change, err := r.uc.SchedulePriceChange(c.Request.Context(), &changeEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - priceRoutes - schedulePriceChange")
+		switch {
+		case errors.Is(err, entity.ErrInvalidPriceChange), errors.Is(err, entity.ErrCurrencyMismatch):
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		case errors.Is(err, entity.ErrProductNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCreateSuccess(scheduledPriceChangeToResponse(*change)))
+}
+
+func (r *priceRoutes) getScheduledPriceChanges(c *gin.Context) {
+	changes, err := r.uc.GetScheduledPriceChanges(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - priceRoutes - getScheduledPriceChanges")
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(scheduledPriceChangesToResponse(changes)))
+}
+
+func (r *priceRoutes) cancelScheduledPriceChange(c *gin.Context) {
+	err := r.uc.CancelScheduledPriceChange(c.Request.Context(), c.Param("id"), c.Param("schedule_id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - priceRoutes - cancelScheduledPriceChange")
+		switch {
+		case errors.Is(err, entity.ErrPriceChangeNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		case errors.Is(err, entity.ErrPriceChangeNotPending):
+			c.JSON(http.StatusConflict, newConflictError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newDeleteSuccess())
+}
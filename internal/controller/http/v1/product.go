@@ -1,96 +1,383 @@
 package v1
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"mime/multipart"
 	"net/http"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
 	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/pkg/imaging"
 	"github.com/idoyudha/eshop-product/pkg/logger"
 )
 
 type productRoutes struct {
-	uc usecase.Product
-	l  logger.Interface
+	uc      usecase.Product
+	iv      *imaging.Validator
+	locales *entity.Locales
+	l       logger.Interface
 }
 
-func newProductRoutes(handler *gin.RouterGroup, uc usecase.Product, l logger.Interface) {
-	r := &productRoutes{uc: uc, l: l}
+func newProductRoutes(handler *gin.RouterGroup, uc usecase.Product, iv *imaging.Validator, locales *entity.Locales, l logger.Interface) {
+	r := &productRoutes{uc: uc, iv: iv, locales: locales, l: l}
 
 	h := handler.Group("/products")
 	{
 		h.POST("", r.createProduct)
+		h.POST("/uploads", r.createImageUpload)
 		h.GET("", r.getProducts)
+		h.GET("/search", r.searchProducts)
+		h.GET("/low-stock", r.getLowStockProducts)
 		h.GET("/:id", r.getProductByID)
+		h.GET("/slug/:slug", r.getProductBySlug)
 		h.GET("/category/:id", r.getProductsByCategory)
 		h.POST("/categories", r.getProductsByCategories)
 		h.PUT("/:id", r.updateProduct)
-		h.DELETE("/:product_id/category/:category_id", r.deleteProduct)
+		h.DELETE("/:id/category/:category_id", r.deleteProduct)
+
+		h.POST("/:id/images", r.addProductImage)
+		h.PUT("/:id/images/order", r.reorderProductImages)
+		h.PATCH("/:id/images/:image_id", r.updateProductImage)
+		h.DELETE("/:id/images/:image_id", r.removeProductImage)
+
+		h.PUT("/:id/bundle", r.setBundle)
+
+		h.GET("/:id/stock-movements", r.getStockMovements)
+
+		h.GET("/:id/translations", r.getProductTranslations)
+		h.PUT("/:id/translations/:locale", r.setProductTranslation)
+		h.DELETE("/:id/translations/:locale", r.deleteProductTranslation)
+	}
+
+	// the public endpoints above only show published products
+	a := handler.Group("/admin/products")
+	{
+		a.GET("", r.getAdminProducts)
+		a.GET("/:id", r.getAdminProductByID)
+		a.PUT("/:id/status", r.changeProductStatus)
 	}
 }
 
 type createProductRequest struct {
-	Name        string                `form:"name" binding:"required"`
-	Image       *multipart.FileHeader `form:"image" binding:"required"`
-	Description string                `form:"description" binding:"required"`
-	Price       float64               `form:"price" binding:"required"`
-	Quantity    int                   `form:"quantity" binding:"required"`
-	CategoryID  string                `form:"category_id" binding:"required"`
+	Name           string                `form:"name" binding:"required"`
+	Image          *multipart.FileHeader `form:"image"`
+	UploadToken    string                `form:"upload_token"`
+	Description    string                `form:"description" binding:"required"`
+	Price          int64                 `form:"price" binding:"required_unless=BundlePricing discount,gte=0"` // in the minor unit of the currency
+	Currency       string                `form:"currency" binding:"required,len=3"`
+	Prices         string                `form:"prices"`                                         // json array of prices in other currencies
+	Quantity       int                   `form:"quantity" binding:"required_unless=Type bundle"` // derived from the components for a bundle
+	CategoryID     string                `form:"category_id" binding:"required"`
+	Attributes     string                `form:"attributes"`                                                 // json object of attribute values
+	Status         string                `form:"status" binding:"omitempty,oneof=draft scheduled published"` // published when left out
+	PublishAt      *time.Time            `form:"publish_at" time_format:"2006-01-02T15:04:05Z07:00"`         // required when scheduled
+	Type           string                `form:"type" binding:"omitempty,oneof=simple bundle"`
+	Components     string                `form:"components"` // json array of product_id and quantity, for a bundle
+	BundlePricing  string                `form:"bundle_pricing" binding:"required_if=Type bundle,omitempty,oneof=fixed discount"`
+	BundleDiscount float64               `form:"bundle_discount"` // percent off the sum of the component prices, for discount pricing
+	// ReorderThreshold is the quantity at which the stock is alerted as low, the default when left out
+	ReorderThreshold *int `form:"reorder_threshold" binding:"omitempty,gte=0"`
+}
+
+// bundleRequest is the composition of a bundle
+type bundleRequest struct {
+	Components      []bundleComponentRequest `json:"components" binding:"required,min=1,dive"`
+	Pricing         string                   `json:"pricing" binding:"required,oneof=fixed discount"`
+	DiscountPercent float64                  `json:"discount_percent"` // for discount pricing
+}
+
+type bundleComponentRequest struct {
+	ProductID string `json:"product_id" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,gt=0"`
+}
+
+type bundleResponse struct {
+	Components      []bundleComponentResponse `json:"components"`
+	Pricing         string                    `json:"pricing"`
+	DiscountPercent float64                   `json:"discount_percent,omitempty"`
+}
+
+type bundleComponentResponse struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+// moneyRequest is an amount in the minor unit of an ISO 4217 currency, 19.99 USD is {1999, "USD"}
+type moneyRequest struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required,len=3"`
+}
+
+type moneyResponse struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
 }
 
 type createProductResponse struct {
-	ID          string  `json:"id"`
-	SKU         string  `json:"sku"`
-	Name        string  `json:"name"`
-	ImageURL    string  `json:"image_url"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Quantity    int     `json:"quantity"`
-	CategoryID  string  `json:"category_id"`
+	ID                string                     `json:"id"`
+	SKU               string                     `json:"sku"`
+	Name              string                     `json:"name"`
+	Slug              string                     `json:"slug"`
+	ImageURL          string                     `json:"image_url"`
+	Images            []productImageResponse     `json:"images"`
+	Description       string                     `json:"description"`
+	Price             moneyResponse              `json:"price"`
+	Prices            []moneyResponse            `json:"prices"`
+	SalePrice         moneyResponse              `json:"sale_price"`
+	AppliedPromotions []appliedPromotionResponse `json:"applied_promotions"`
+	Status            string                     `json:"status"`
+	PublishAt         *time.Time                 `json:"publish_at,omitempty"`
+	PublishedAt       *time.Time                 `json:"published_at,omitempty"`
+	Quantity          int                        `json:"quantity"`
+	Version           int64                      `json:"version"`
+	CategoryID        string                     `json:"category_id"`
+	Attributes        map[string]interface{}     `json:"attributes"`
+	Type              string                     `json:"type"`
+	Bundle            *bundleResponse            `json:"bundle,omitempty"`
+	ReorderThreshold  *int                       `json:"reorder_threshold,omitempty"`
+}
+
+type appliedPromotionResponse struct {
+	PromotionID  string         `json:"promotion_id"`
+	Name         string         `json:"name"`
+	DiscountType string         `json:"discount_type"`
+	Value        float64        `json:"value,omitempty"`
+	Amount       *moneyResponse `json:"amount,omitempty"`
+	Discount     moneyResponse  `json:"discount"`
+}
+
+type productImageResponse struct {
+	ID         string                   `json:"id"`
+	URL        string                   `json:"url"`
+	AltText    string                   `json:"alt_text"`
+	Position   int                      `json:"position"`
+	IsPrimary  bool                     `json:"is_primary"`
+	Renditions []imageRenditionResponse `json:"renditions"`
+}
+
+type imageRenditionResponse struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url"`
 }
 
 func (r *productRoutes) createProduct(c *gin.Context) {
 	var request createProductRequest
 	if err := c.ShouldBind(&request); err != nil {
 		r.l.Error(err, "http - v1 - productRoutes - createProduct")
-		c.JSON(http.StatusBadRequest, newInternalServerError(err.Error()))
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	imageSource := entity.ImageSource{File: request.Image, UploadToken: request.UploadToken}
+	if err := r.validateImageSource(imageSource); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - createProduct")
+		restErr := newImageValidationError(err)
+		c.JSON(restErr.Code, restErr)
 		return
 	}
 
 	productEntity := createProductRequestToProductEntity(request)
 
-	product, err := r.uc.CreateProduct(c.Request.Context(), &productEntity, request.Image)
+	attributes, err := parseProductAttributes(request.Attributes)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - createProduct")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+	productEntity.Attributes = attributes
+	productEntity.UpdatedBy = actor(c)
+
+	prices, err := parsePriceList(request.Prices)
 	if err != nil {
 		r.l.Error(err, "http - v1 - productRoutes - createProduct")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+	productEntity.Prices = prices
+
+	if productEntity.Type == entity.ProductBundle {
+		bundle, err := parseBundle(request.Components, request.BundlePricing, request.BundleDiscount)
+		if err != nil {
+			r.l.Error(err, "http - v1 - productRoutes - createProduct")
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		productEntity.Bundle = bundle
+	}
+
+	product, err := r.uc.CreateProduct(c.Request.Context(), &productEntity, imageSource)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - createProduct")
+		if isImageValidationError(err) {
+			restErr := newImageValidationError(err)
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+		if errors.Is(err, entity.ErrInvalidAttributes) || errors.Is(err, entity.ErrCategoryNotFound) ||
+			errors.Is(err, entity.ErrInvalidMoney) || errors.Is(err, entity.ErrInvalidStatusTransition) ||
+			errors.Is(err, entity.ErrInvalidBundle) || errors.Is(err, entity.ErrCurrencyMismatch) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCreateSuccess(productEntityToProductResponse(*product)))
+}
+
+type createImageUploadRequest struct {
+	ContentType string `json:"content_type" binding:"required"`
+	Size        int64  `json:"size" binding:"required,gt=0"`
+}
+
+type createImageUploadResponse struct {
+	UploadToken string            `json:"upload_token"`
+	UploadURL   string            `json:"upload_url"`
+	Headers     map[string]string `json:"headers"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+}
+
+func (r *productRoutes) createImageUpload(c *gin.Context) {
+	var request createImageUploadRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - createImageUpload")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	upload, err := r.uc.CreateImageUpload(c.Request.Context(), request.ContentType, request.Size)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - createImageUpload")
+		if isImageValidationError(err) {
+			restErr := newImageValidationError(err)
+			c.JSON(restErr.Code, restErr)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusCreated, newCreateSuccess(product))
+	c.JSON(http.StatusCreated, newCreateSuccess(imageUploadEntityToCreateImageUploadResponse(*upload)))
 }
 
 type getProductResponse struct {
-	ID          string  `json:"id"`
-	SKU         string  `json:"sku"`
-	Name        string  `json:"name"`
-	ImageURL    string  `json:"image_url"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Quantity    int     `json:"quantity"`
-	CategoryID  string  `json:"category_id"`
+	ID                string                     `json:"id"`
+	SKU               string                     `json:"sku"`
+	Name              string                     `json:"name"`
+	Slug              string                     `json:"slug"`
+	ImageURL          string                     `json:"image_url"`
+	Images            []productImageResponse     `json:"images"`
+	Description       string                     `json:"description"`
+	Price             moneyResponse              `json:"price"`
+	Prices            []moneyResponse            `json:"prices"`
+	SalePrice         moneyResponse              `json:"sale_price"`
+	AppliedPromotions []appliedPromotionResponse `json:"applied_promotions"`
+	Status            string                     `json:"status"`
+	PublishAt         *time.Time                 `json:"publish_at,omitempty"`
+	PublishedAt       *time.Time                 `json:"published_at,omitempty"`
+	Quantity          int                        `json:"quantity"`
+	Version           int64                      `json:"version"`
+	CategoryID        string                     `json:"category_id"`
+	Attributes        map[string]interface{}     `json:"attributes"`
+	Type              string                     `json:"type"`
+	Bundle            *bundleResponse            `json:"bundle,omitempty"`
+}
+
+type paginationQuery struct {
+	Limit  int32  `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+}
+
+type getProductsQuery struct {
+	paginationQuery
+	productFilterQuery
+}
+
+// productFilterQuery holds the filters shared by the listings and the export
+type productFilterQuery struct {
+	CategoryID string `form:"category_id"`
+	Name       string `form:"name"`
+	MinPrice   *int64 `form:"min_price" binding:"omitempty,gte=0"` // in the minor unit of the currency
+	MaxPrice   *int64 `form:"max_price" binding:"omitempty,gte=0"`
+	Currency   string `form:"currency" binding:"omitempty,len=3"` // of the price bounds, the default currency when left out
+	InStock    *bool  `form:"in_stock"`
+	SortBy     string `form:"sort_by" binding:"omitempty,oneof=price name created_at updated_at"`
+	Order      string `form:"order" binding:"omitempty,oneof=asc desc"`
 }
 
 func (r *productRoutes) getProducts(c *gin.Context) {
-	products, err := r.uc.GetProducts(c.Request.Context())
+	var query getProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getProducts")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	filter, sort := productFilterQueryToProductQuery(query.productFilterQuery)
+	filter.Status = entity.ProductPublished
+
+	products, nextCursor, err := r.uc.GetProducts(c.Request.Context(), filter, sort, paginationQueryToPagination(query.paginationQuery))
 	if err != nil {
 		r.l.Error(err, "http - v1 - productRoutes - getProducts")
+		if errors.Is(err, entity.ErrInvalidCursor) || errors.Is(err, entity.ErrInvalidProductQuery) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
 		return
 	}
 
+	localizeProducts(c, r.locales, *products)
 	productsResponse := productEntitiesToGetProductResponse(*products)
 
+	c.JSON(http.StatusOK, newGetPageSuccess(productsResponse, nextCursor))
+}
+
+type searchProductsQuery struct {
+	Query string `form:"q" binding:"required"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type searchProductResponse struct {
+	getProductResponse
+	Score float64 `json:"score"`
+}
+
+func (r *productRoutes) searchProducts(c *gin.Context) {
+	var query searchProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - searchProducts")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = int(entity.DefaultPageLimit)
+	}
+
+	hits, err := r.uc.SearchProducts(c.Request.Context(), query.Query, query.Limit)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - searchProducts")
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	chain := r.locales.Chain(requestLocale(c, r.locales))
+	for i := range hits {
+		hits[i].Product.Localize(chain)
+	}
+	productsResponse := productSearchHitsToSearchProductResponse(hits)
+
 	c.JSON(http.StatusOK, newGetSuccess(productsResponse))
 }
 
@@ -98,26 +385,164 @@ func (r *productRoutes) getProductByID(c *gin.Context) {
 	product, err := r.uc.GetProductByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
 		r.l.Error(err, "http - v1 - productRoutes - getProductByID")
+		if errors.Is(err, entity.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	product.Localize(r.locales.Chain(requestLocale(c, r.locales)))
+	productsResponse := productEntityToGetProductResponse(*product)
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, newGetSuccess(productsResponse))
+}
+
+// slugRedirectResponse is the redirect hint for an old slug, Location holds the url of the current one
+type slugRedirectResponse struct {
+	ID   string `json:"id"`
+	Slug string `json:"slug"`
+}
+
+func (r *productRoutes) getProductBySlug(c *gin.Context) {
+	product, err := r.uc.GetProductBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getProductBySlug")
+		if errors.Is(err, entity.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
 		return
 	}
 
+	// the product was found by an old slug
+	if product.Slug != c.Param("slug") {
+		redirectToSlug(c, product.ID, product.Slug)
+		return
+	}
+
+	product.Localize(r.locales.Chain(requestLocale(c, r.locales)))
 	productsResponse := productEntityToGetProductResponse(*product)
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, newGetSuccess(productsResponse))
 }
 
+// redirectToSlug answers a lookup by an old slug with the current one, the request
+// path only differs in the last segment
+func redirectToSlug(c *gin.Context, id, slug string) {
+	c.Header("Location", path.Join(path.Dir(c.Request.URL.Path), slug))
+	c.JSON(http.StatusMovedPermanently, newMovedSuccess(slugRedirectResponse{ID: id, Slug: slug}))
+}
+
+type getAdminProductsQuery struct {
+	getProductsQuery
+	Status string `form:"status" binding:"omitempty,oneof=draft scheduled published archived"` // any status when left out
+}
+
+func (r *productRoutes) getAdminProducts(c *gin.Context) {
+	var query getAdminProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getAdminProducts")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	filter, sort := productFilterQueryToProductQuery(query.productFilterQuery)
+	filter.Status = entity.ProductStatus(query.Status)
+
+	products, nextCursor, err := r.uc.GetProducts(c.Request.Context(), filter, sort, paginationQueryToPagination(query.paginationQuery))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getAdminProducts")
+		if errors.Is(err, entity.ErrInvalidCursor) || errors.Is(err, entity.ErrInvalidProductQuery) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetPageSuccess(productEntitiesToGetProductResponse(*products), nextCursor))
+}
+
+func (r *productRoutes) getAdminProductByID(c *gin.Context) {
+	product, err := r.uc.GetAdminProductByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getAdminProductByID")
+		if errors.Is(err, entity.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, newGetSuccess(productEntityToGetProductResponse(*product)))
+}
+
+type changeProductStatusRequest struct {
+	Status    string     `json:"status" binding:"required,oneof=draft scheduled published archived"`
+	PublishAt *time.Time `json:"publish_at"` // required when scheduled
+}
+
+func (r *productRoutes) changeProductStatus(c *gin.Context) {
+	var request changeProductStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - changeProductStatus")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	product, err := r.uc.ChangeProductStatus(
+		c.Request.Context(),
+		c.Param("id"),
+		entity.ProductStatus(request.Status),
+		request.PublishAt,
+		actor(c),
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - changeProductStatus")
+		switch {
+		case errors.Is(err, entity.ErrProductNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		case errors.Is(err, entity.ErrInvalidStatusTransition), errors.Is(err, entity.ErrProductStatusChanged):
+			c.JSON(http.StatusConflict, newConflictError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(productEntityToGetProductResponse(*product)))
+}
+
 func (r *productRoutes) getProductsByCategory(c *gin.Context) {
-	productEntities, err := r.uc.GetProductsByCategory(c.Request.Context(), c.Param("id"))
+	var query paginationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getProductsByCategory")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	productEntities, nextCursor, err := r.uc.GetProductsByCategory(c.Request.Context(), c.Param("id"), paginationQueryToPagination(query))
 	if err != nil {
 		r.l.Error(err, "http - v1 - productRoutes - getProductsByCategory")
+		if errors.Is(err, entity.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
 		return
 	}
 
+	localizeProducts(c, r.locales, productEntities)
 	products := productEntitiesToGetProductResponse(productEntities)
 
-	c.JSON(http.StatusOK, newGetSuccess(products))
+	c.JSON(http.StatusOK, newGetPageSuccess(products, nextCursor))
 }
 
 type getProductsRequest struct {
@@ -139,6 +564,7 @@ func (r *productRoutes) getProductsByCategories(c *gin.Context) {
 		return
 	}
 
+	localizeProducts(c, r.locales, productEntities)
 	products := productEntitiesToGetProductResponse(productEntities)
 
 	c.JSON(http.StatusOK, newGetSuccess(products))
@@ -146,32 +572,32 @@ func (r *productRoutes) getProductsByCategories(c *gin.Context) {
 
 type updateProductRequest struct {
 	Name        string                `form:"name" binding:"required"`
-	Image       *multipart.FileHeader `form:"image" binding:"required"`
+	Image       *multipart.FileHeader `form:"image"`
+	UploadToken string                `form:"upload_token"`
 	Description string                `form:"description" binding:"required"`
-	Price       float64               `form:"price" binding:"required"`
+	Price       int64                 `form:"price" binding:"required,gt=0"` // in the minor unit of the currency
+	Currency    string                `form:"currency" binding:"required,len=3"`
+	Prices      string                `form:"prices"` // json array of prices in other currencies, kept when left out
 	CategoryID  string                `form:"category_id" binding:"required"`
-}
-
-func (u *updateProductRequest) validate() error {
-	if u.Image.Size > 1024*1024 {
-		return errors.New("image size must be less than 1MB")
-	}
-
-	contentType := u.Image.Header.Get("Content-Type")
-	if contentType != "image/jpeg" && contentType != "image/png" {
-		return errors.New("image must be in JPEG or PNG format")
-	}
-
-	return nil
+	Attributes  string                `form:"attributes"` // json object of attribute values, kept when left out
+	// ReorderThreshold is kept when left out
+	ReorderThreshold *int `form:"reorder_threshold" binding:"omitempty,gte=0"`
 }
 
 type updateProductResponse struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	ImageURL    string  `json:"image_url"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	CategoryID  string  `json:"category_id"`
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Slug        string                 `json:"slug"`
+	ImageURL    string                 `json:"image_url"`
+	Images      []productImageResponse `json:"images"`
+	Description string                 `json:"description"`
+	Price       moneyResponse          `json:"price"`
+	Prices      []moneyResponse        `json:"prices,omitempty"`
+	CategoryID  string                 `json:"category_id"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+	Version     int64                  `json:"version"`
+	// ReorderThreshold is left out when the default applies
+	ReorderThreshold *int `json:"reorder_threshold,omitempty"`
 }
 
 func (r *productRoutes) updateProduct(c *gin.Context) {
@@ -182,33 +608,427 @@ func (r *productRoutes) updateProduct(c *gin.Context) {
 		return
 	}
 
-	if err := request.validate(); err != nil {
+	imageSource := entity.ImageSource{File: request.Image, UploadToken: request.UploadToken}
+	if err := r.validateImageSource(imageSource); err != nil {
 		r.l.Error(err, "http - v1 - productRoutes - updateProduct")
-		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		restErr := newImageValidationError(err)
+		c.JSON(restErr.Code, restErr)
 		return
 	}
 
 	productEntity := updateProductRequestToProductEntity(request, c.Param("id"))
 
-	err := r.uc.UpdateProduct(c.Request.Context(), &productEntity, request.Image)
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - updateProduct")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+	productEntity.Version = version
+
+	attributes, err := parseProductAttributes(request.Attributes)
 	if err != nil {
 		r.l.Error(err, "http - v1 - productRoutes - updateProduct")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+	productEntity.Attributes = attributes
+	productEntity.UpdatedBy = actor(c)
+
+	prices, err := parsePriceList(request.Prices)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - updateProduct")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+	productEntity.Prices = prices
+
+	err = r.uc.UpdateProduct(c.Request.Context(), &productEntity, imageSource)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - updateProduct")
+		var mismatch *entity.VersionMismatchError
+		if errors.As(err, &mismatch) {
+			versionMismatch(c, mismatch.Current, err)
+			return
+		}
+		if errors.Is(err, entity.ErrConcurrentUpdate) {
+			c.JSON(http.StatusConflict, newConflictError(err.Error()))
+			return
+		}
+		if errors.Is(err, entity.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		if isImageValidationError(err) {
+			restErr := newImageValidationError(err)
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+		if errors.Is(err, entity.ErrInvalidAttributes) || errors.Is(err, entity.ErrCategoryNotFound) || errors.Is(err, entity.ErrInvalidMoney) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
 		return
 	}
 
 	product := productEntityToUpdateProductResponse(productEntity)
 
+	setETag(c, productEntity.Version)
 	c.JSON(http.StatusOK, newUpdateSuccess(product))
 }
 
 func (r *productRoutes) deleteProduct(c *gin.Context) {
-	err := r.uc.DeleteProduct(c.Request.Context(), c.Param("product_id"), c.Param("category_id"))
+	err := r.uc.DeleteProduct(c.Request.Context(), c.Param("id"), c.Param("category_id"))
 	if err != nil {
 		r.l.Error(err, "http - v1 - productRoutes - deleteProduct")
+		if errors.Is(err, entity.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
 		return
 	}
 
 	c.JSON(http.StatusOK, newDeleteSuccess())
 }
+
+type addProductImageRequest struct {
+	Image       *multipart.FileHeader `form:"image"`
+	UploadToken string                `form:"upload_token"`
+	AltText     string                `form:"alt_text"`
+	Primary     bool                  `form:"primary"`
+}
+
+func (r *productRoutes) addProductImage(c *gin.Context) {
+	var request addProductImageRequest
+	if err := c.ShouldBind(&request); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - addProductImage")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	imageSource := entity.ImageSource{File: request.Image, UploadToken: request.UploadToken}
+	if err := r.validateImageSource(imageSource); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - addProductImage")
+		restErr := newImageValidationError(err)
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	product, err := r.uc.AddProductImage(c.Request.Context(), c.Param("id"), imageSource, request.AltText, request.Primary)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - addProductImage")
+		if isImageValidationError(err) {
+			restErr := newImageValidationError(err)
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCreateSuccess(productImagesToProductImageResponse(product.Images)))
+}
+
+type reorderProductImagesRequest struct {
+	ImageIDs []string `json:"image_ids" binding:"required,min=1"`
+}
+
+func (r *productRoutes) reorderProductImages(c *gin.Context) {
+	var request reorderProductImagesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - reorderProductImages")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	product, err := r.uc.ReorderProductImages(c.Request.Context(), c.Param("id"), request.ImageIDs)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - reorderProductImages")
+		if errors.Is(err, entity.ErrProductImageNotFound) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(productImagesToProductImageResponse(product.Images)))
+}
+
+type updateProductImageRequest struct {
+	AltText *string `json:"alt_text"`
+	Primary bool    `json:"primary"`
+}
+
+func (r *productRoutes) updateProductImage(c *gin.Context) {
+	var request updateProductImageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - updateProductImage")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	product, err := r.uc.UpdateProductImage(c.Request.Context(), c.Param("id"), c.Param("image_id"), request.AltText, request.Primary)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - updateProductImage")
+		if errors.Is(err, entity.ErrProductImageNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(productImagesToProductImageResponse(product.Images)))
+}
+
+func (r *productRoutes) removeProductImage(c *gin.Context) {
+	product, err := r.uc.RemoveProductImage(c.Request.Context(), c.Param("id"), c.Param("image_id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - removeProductImage")
+		if errors.Is(err, entity.ErrProductImageNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(productImagesToProductImageResponse(product.Images)))
+}
+
+// parsePriceList decodes the prices form field
+func parsePriceList(raw string) ([]entity.Money, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var requests []moneyRequest
+	if err := json.Unmarshal([]byte(raw), &requests); err != nil || requests == nil {
+		return nil, fmt.Errorf("%w: prices must be a json array of amount and currency", entity.ErrInvalidMoney)
+	}
+	return moneyRequestsToEntity(requests), nil
+}
+
+// parseBundle decodes the components form field of a bundle
+func parseBundle(rawComponents, pricing string, discountPercent float64) (*entity.Bundle, error) {
+	var components []bundleComponentRequest
+	if err := json.Unmarshal([]byte(rawComponents), &components); err != nil || len(components) == 0 {
+		return nil, fmt.Errorf("%w: components must be a json array of product_id and quantity", entity.ErrInvalidBundle)
+	}
+	return bundleRequestToEntity(bundleRequest{
+		Components:      components,
+		Pricing:         pricing,
+		DiscountPercent: discountPercent,
+	}), nil
+}
+
+// parseProductAttributes decodes the attributes form field, numbers are kept as
+// json.Number so an integer attribute is not rounded through float64
+func parseProductAttributes(raw string) (entity.ProductAttributes, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+
+	var attributes entity.ProductAttributes
+	if err := decoder.Decode(&attributes); err != nil || attributes == nil {
+		return nil, fmt.Errorf("%w: attributes must be a json object", entity.ErrInvalidAttributes)
+	}
+	return attributes, nil
+}
+
+// validateImageSource checks the uploaded file by its content, the multipart content type is not trusted.
+// An upload token is validated once the object is read from s3.
+func (r *productRoutes) validateImageSource(source entity.ImageSource) error {
+	if err := source.Validate(); err != nil {
+		return err
+	}
+	if source.File == nil {
+		return nil
+	}
+
+	src, err := source.File.Open()
+	if err != nil {
+		return fmt.Errorf("%w: failed to open file", imaging.ErrInvalidImage)
+	}
+	defer src.Close()
+
+	_, err = r.iv.Validate(src)
+	return err
+}
+
+type productTranslationRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"` // at least one of both
+}
+
+type productTranslationResponse struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type productTranslationsResponse struct {
+	ID            string                                `json:"id"`
+	DefaultLocale string                                `json:"default_locale"`
+	Translations  map[string]productTranslationResponse `json:"translations"`
+}
+
+func (r *productRoutes) getProductTranslations(c *gin.Context) {
+	product, err := r.uc.GetAdminProductByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getProductTranslations")
+		if errors.Is(err, entity.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(productEntityToProductTranslationsResponse(*product, r.locales.Default)))
+}
+
+func (r *productRoutes) setProductTranslation(c *gin.Context) {
+	var request productTranslationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - setProductTranslation")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	product, err := r.uc.SetProductTranslation(
+		c.Request.Context(),
+		c.Param("id"),
+		c.Param("locale"),
+		entity.ProductTranslation(request),
+		actor(c),
+	)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - setProductTranslation")
+		r.translationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(productEntityToProductTranslationsResponse(*product, r.locales.Default)))
+}
+
+func (r *productRoutes) deleteProductTranslation(c *gin.Context) {
+	product, err := r.uc.DeleteProductTranslation(c.Request.Context(), c.Param("id"), c.Param("locale"), actor(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - deleteProductTranslation")
+		r.translationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(productEntityToProductTranslationsResponse(*product, r.locales.Default)))
+}
+
+func (r *productRoutes) translationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, entity.ErrInvalidLocale):
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+	case errors.Is(err, entity.ErrProductNotFound), errors.Is(err, entity.ErrTranslationNotFound):
+		c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+	}
+}
+
+func (r *productRoutes) setBundle(c *gin.Context) {
+	var request bundleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - setBundle")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	product, err := r.uc.SetBundle(c.Request.Context(), c.Param("id"), *bundleRequestToEntity(request), actor(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - setBundle")
+		switch {
+		case errors.Is(err, entity.ErrInvalidBundle), errors.Is(err, entity.ErrInvalidMoney), errors.Is(err, entity.ErrCurrencyMismatch):
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		case errors.Is(err, entity.ErrProductNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(productEntityToGetProductResponse(*product)))
+}
+
+type stockMovementResponse struct {
+	EventID   string    `json:"event_id"`
+	ProductID string    `json:"product_id"`
+	VariantID string    `json:"variant_id,omitempty"`
+	Delta     int       `json:"delta"`
+	Quantity  int       `json:"quantity"`
+	Reason    string    `json:"reason"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// getStockMovements lists the inventory ledger of the product, newest first
+func (r *productRoutes) getStockMovements(c *gin.Context) {
+	var query paginationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getStockMovements")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	movements, nextCursor, err := r.uc.GetStockMovements(c.Request.Context(), c.Param("id"), paginationQueryToPagination(query))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getStockMovements")
+		if errors.Is(err, entity.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetPageSuccess(stockMovementsToResponse(movements), nextCursor))
+}
+
+type lowStockProductResponse struct {
+	ID         string `json:"id"`
+	SKU        string `json:"sku"`
+	Name       string `json:"name"`
+	CategoryID string `json:"category_id"`
+	Status     string `json:"status"`
+	Quantity   int    `json:"quantity"`
+	Reserved   int    `json:"reserved"`
+	Threshold  int    `json:"threshold"`
+	Level      string `json:"level"` // low or out
+}
+
+// getLowStockProducts reports the products at or below their reorder threshold
+func (r *productRoutes) getLowStockProducts(c *gin.Context) {
+	var query paginationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getLowStockProducts")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	report, nextCursor, err := r.uc.GetLowStockProducts(c.Request.Context(), paginationQueryToPagination(query))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productRoutes - getLowStockProducts")
+		if errors.Is(err, entity.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetPageSuccess(lowStockProductsToResponse(report), nextCursor))
+}
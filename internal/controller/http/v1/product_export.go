@@ -0,0 +1,83 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+)
+
+type productExportRoutes struct {
+	uc usecase.ProductExport
+	l  logger.Interface
+}
+
+func newProductExportRoutes(handler *gin.RouterGroup, uc usecase.ProductExport, l logger.Interface) {
+	r := &productExportRoutes{uc: uc, l: l}
+
+	h := handler.Group("/products/export")
+	{
+		h.GET("", r.exportProducts)
+	}
+}
+
+type exportProductsQuery struct {
+	productFilterQuery
+	Status          string `form:"status" binding:"omitempty,oneof=draft scheduled published archived"` // any status when left out
+	Format          string `form:"format" binding:"omitempty,oneof=csv jsonl ndjson"`                   // csv when left out
+	IncludeCategory bool   `form:"include_category"`
+}
+
+// exportProducts streams the products to the response while they are read, the status
+// is sent with the first page so a failure after that can only cut the body short
+func (r *productExportRoutes) exportProducts(c *gin.Context) {
+	var query exportProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - productExportRoutes - exportProducts")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	format, err := entity.ParseExportFormat(query.Format)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productExportRoutes - exportProducts")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	filter, sort := productFilterQueryToProductQuery(query.productFilterQuery)
+	filter.Status = entity.ProductStatus(query.Status)
+
+	options := entity.ExportOptions{
+		Format:          format,
+		Filter:          filter,
+		Sort:            sort,
+		IncludeCategory: query.IncludeCategory,
+	}
+
+	fileName := fmt.Sprintf("products-%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+
+	_, err = r.uc.ExportProducts(c.Request.Context(), c.Writer, options)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productExportRoutes - exportProducts")
+		if c.Writer.Written() {
+			return
+		}
+
+		// an empty value drops the header, the error is sent as json
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		if errors.Is(err, entity.ErrInvalidProductQuery) || errors.Is(err, entity.ErrInvalidExport) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+	}
+}
@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+)
+
+type productImportRoutes struct {
+	uc usecase.ProductImport
+	l  logger.Interface
+}
+
+func newProductImportRoutes(handler *gin.RouterGroup, uc usecase.ProductImport, l logger.Interface) {
+	r := &productImportRoutes{uc: uc, l: l}
+
+	h := handler.Group("/products/import")
+	{
+		h.POST("", r.startImport)
+		h.GET("/:id", r.getImportJob)
+		h.GET("/:id/errors", r.getImportErrors)
+	}
+}
+
+type startImportRequest struct {
+	File    *multipart.FileHeader `form:"file" binding:"required"`
+	Mapping string                `form:"mapping"` // json object of product field to csv header
+}
+
+type importJobResponse struct {
+	ID         string     `json:"id"`
+	FileName   string     `json:"file_name"`
+	Status     string     `json:"status"`
+	TotalRows  int        `json:"total_rows"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type importRowErrorResponse struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (r *productImportRoutes) startImport(c *gin.Context) {
+	var request startImportRequest
+	if err := c.ShouldBind(&request); err != nil {
+		r.l.Error(err, "http - v1 - productImportRoutes - startImport")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	mapping, err := parseImportMapping(request.Mapping)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productImportRoutes - startImport")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	job, err := r.uc.StartImport(c.Request.Context(), request.File, mapping, actor(c))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productImportRoutes - startImport")
+		if errors.Is(err, entity.ErrInvalidImportFile) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, newAcceptedSuccess(importJobEntityToResponse(*job)))
+}
+
+func (r *productImportRoutes) getImportJob(c *gin.Context) {
+	job, err := r.uc.GetImportJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productImportRoutes - getImportJob")
+		if errors.Is(err, entity.ErrImportJobNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(importJobEntityToResponse(*job)))
+}
+
+func (r *productImportRoutes) getImportErrors(c *gin.Context) {
+	var query paginationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - productImportRoutes - getImportErrors")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	rowErrors, nextCursor, err := r.uc.GetImportErrors(c.Request.Context(), c.Param("id"), paginationQueryToPagination(query))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productImportRoutes - getImportErrors")
+		switch {
+		case errors.Is(err, entity.ErrImportJobNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		case errors.Is(err, entity.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetPageSuccess(importRowErrorsToResponse(rowErrors), nextCursor))
+}
+
+// parseImportMapping decodes the mapping form field, an empty mapping reads every
+// field from the column named like it
+func parseImportMapping(raw string) (entity.ImportMapping, error) {
+	if raw == "" {
+		return entity.ImportMapping{}, nil
+	}
+
+	var mapping entity.ImportMapping
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil || mapping == nil {
+		return nil, fmt.Errorf("%w: mapping must be a json object of field to column", entity.ErrInvalidImportFile)
+	}
+	return mapping, nil
+}
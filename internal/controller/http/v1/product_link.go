@@ -0,0 +1,126 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+)
+
+type productLinkRoutes struct {
+	uc      usecase.ProductLink
+	locales *entity.Locales
+	l       logger.Interface
+}
+
+func newProductLinkRoutes(handler *gin.RouterGroup, uc usecase.ProductLink, locales *entity.Locales, l logger.Interface) {
+	r := &productLinkRoutes{uc: uc, locales: locales, l: l}
+
+	h := handler.Group("/products/:id")
+	{
+		h.GET("/related", r.getRelatedProducts)
+		h.POST("/links", r.linkProducts)
+		h.DELETE("/links/:type/:linked_id", r.unlinkProducts)
+	}
+}
+
+type linkProductsRequest struct {
+	LinkedProductID string `json:"linked_product_id" binding:"required"`
+	Type            string `json:"type" binding:"required,oneof=related accessory upsell"`
+	Position        int    `json:"position" binding:"gte=0"` // links of a type are listed by position
+}
+
+type productLinkResponse struct {
+	ProductID       string `json:"product_id"`
+	LinkedProductID string `json:"linked_product_id"`
+	Type            string `json:"type"`
+	Position        int    `json:"position"`
+}
+
+func (r *productLinkRoutes) linkProducts(c *gin.Context) {
+	var request linkProductsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - productLinkRoutes - linkProducts")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	link := linkProductsRequestToProductLinkEntity(request, c.Param("id"))
+	link.CreatedBy = actor(c)
+
+	err := r.uc.LinkProducts(c.Request.Context(), &link)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productLinkRoutes - linkProducts")
+		switch {
+		case errors.Is(err, entity.ErrInvalidProductLink):
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		case errors.Is(err, entity.ErrProductNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCreateSuccess(productLinkEntityToProductLinkResponse(link)))
+}
+
+func (r *productLinkRoutes) unlinkProducts(c *gin.Context) {
+	err := r.uc.UnlinkProducts(c.Request.Context(), c.Param("id"), entity.ProductLinkType(c.Param("type")), c.Param("linked_id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - productLinkRoutes - unlinkProducts")
+		if errors.Is(err, entity.ErrProductLinkNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newDeleteSuccess())
+}
+
+type getRelatedProductsQuery struct {
+	Type  string `form:"type" binding:"omitempty,oneof=related accessory upsell"` // every type when left out
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type relatedProductResponse struct {
+	getProductResponse
+	LinkType string  `json:"link_type"`
+	Source   string  `json:"source"` // link, similar or category
+	Score    float64 `json:"score,omitempty"`
+}
+
+func (r *productLinkRoutes) getRelatedProducts(c *gin.Context) {
+	var query getRelatedProductsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - productLinkRoutes - getRelatedProducts")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	related, err := r.uc.GetRelatedProducts(c.Request.Context(), c.Param("id"), entity.ProductLinkType(query.Type), query.Limit)
+	if err != nil {
+		r.l.Error(err, "http - v1 - productLinkRoutes - getRelatedProducts")
+		switch {
+		case errors.Is(err, entity.ErrInvalidProductLink):
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		case errors.Is(err, entity.ErrProductNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	chain := r.locales.Chain(requestLocale(c, r.locales))
+	for i := range related {
+		related[i].Product.Localize(chain)
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(relatedProductEntitiesToRelatedProductResponse(related)))
+}
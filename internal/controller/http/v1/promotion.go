@@ -0,0 +1,159 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+)
+
+type promotionRoutes struct {
+	uc usecase.Promotion
+	l  logger.Interface
+}
+
+func newPromotionRoutes(handler *gin.RouterGroup, uc usecase.Promotion, l logger.Interface) {
+	r := &promotionRoutes{uc: uc, l: l}
+
+	h := handler.Group("/promotions")
+	{
+		h.POST("", r.createPromotion)
+		h.GET("", r.getPromotions)
+		h.GET("/:id", r.getPromotionByID)
+		h.PUT("/:id", r.updatePromotion)
+		h.DELETE("/:id", r.deletePromotion)
+	}
+}
+
+type promotionTargetRequest struct {
+	Type string `json:"type" binding:"required,oneof=product category sku"`
+	ID   string `json:"id" binding:"required"`
+}
+
+type promotionRequest struct {
+	Name         string                   `json:"name" binding:"required"`
+	DiscountType string                   `json:"discount_type" binding:"required,oneof=percentage fixed"`
+	Value        float64                  `json:"value" binding:"required_if=DiscountType percentage"` // percent off
+	Amount       *moneyRequest            `json:"amount" binding:"required_if=DiscountType fixed"`     // amount off
+	Targets      []promotionTargetRequest `json:"targets" binding:"required,min=1,dive"`
+	Priority     int                      `json:"priority"`
+	Stackable    bool                     `json:"stackable"`
+	StartsAt     time.Time                `json:"starts_at" binding:"required"`
+	EndsAt       time.Time                `json:"ends_at" binding:"required"`
+}
+
+type promotionTargetResponse struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type promotionResponse struct {
+	ID           string                    `json:"id"`
+	Name         string                    `json:"name"`
+	DiscountType string                    `json:"discount_type"`
+	Value        float64                   `json:"value,omitempty"`
+	Amount       *moneyResponse            `json:"amount,omitempty"`
+	Targets      []promotionTargetResponse `json:"targets"`
+	Priority     int                       `json:"priority"`
+	Stackable    bool                      `json:"stackable"`
+	StartsAt     time.Time                 `json:"starts_at"`
+	EndsAt       time.Time                 `json:"ends_at"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+}
+
+func (r *promotionRoutes) createPromotion(c *gin.Context) {
+	var request promotionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - createPromotion")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	promotionEntity := promotionRequestToPromotionEntity(request, "")
+
+	promotion, err := r.uc.CreatePromotion(c.Request.Context(), &promotionEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - createPromotion")
+		if errors.Is(err, entity.ErrInvalidPromotion) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCreateSuccess(promotionEntityToResponse(*promotion)))
+}
+
+func (r *promotionRoutes) getPromotions(c *gin.Context) {
+	promotions, err := r.uc.GetPromotions(c.Request.Context())
+	if err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - getPromotions")
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(promotionEntitiesToResponse(promotions)))
+}
+
+func (r *promotionRoutes) getPromotionByID(c *gin.Context) {
+	promotion, err := r.uc.GetPromotionByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - getPromotionByID")
+		if errors.Is(err, entity.ErrPromotionNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(promotionEntityToResponse(*promotion)))
+}
+
+func (r *promotionRoutes) updatePromotion(c *gin.Context) {
+	var request promotionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - updatePromotion")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	promotionEntity := promotionRequestToPromotionEntity(request, c.Param("id"))
+
+	err := r.uc.UpdatePromotion(c.Request.Context(), &promotionEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - updatePromotion")
+		switch {
+		case errors.Is(err, entity.ErrInvalidPromotion):
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		case errors.Is(err, entity.ErrPromotionNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(promotionEntityToResponse(promotionEntity)))
+}
+
+func (r *promotionRoutes) deletePromotion(c *gin.Context) {
+	err := r.uc.DeletePromotion(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - deletePromotion")
+		if errors.Is(err, entity.ErrPromotionNotFound) {
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newDeleteSuccess())
+}
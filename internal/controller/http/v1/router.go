@@ -5,21 +5,35 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
 	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/pkg/imaging"
 	"github.com/idoyudha/eshop-product/pkg/logger"
 )
 
+// _actorHeader carries the id of the authenticated user, it is set by the api gateway
+const _actorHeader = "X-User-ID"
+
 func HTTPNewRouter(
 	handler *gin.Engine,
 	ucp usecase.Product,
+	ucl usecase.ProductLink,
+	uci usecase.ProductImport,
+	uce usecase.ProductExport,
+	ucv usecase.Variant,
+	ucpr usecase.Price,
+	ucpm usecase.Promotion,
 	ucg usecase.Category,
+	uct usecase.Trash,
+	iv *imaging.Validator,
+	locales *entity.Locales,
 	l logger.Interface,
 ) {
 	handler.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:3001"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Accept-Language", "Authorization", "If-Match", _actorHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Language", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * 3600,
 	}))
@@ -31,7 +45,22 @@ func HTTPNewRouter(
 
 	h := handler.Group("/v1")
 	{
-		newProductRoutes(h, ucp, l)
-		newCategoryRoutes(h, ucg, l)
+		newProductRoutes(h, ucp, iv, locales, l)
+		newProductLinkRoutes(h, ucl, locales, l)
+		newProductImportRoutes(h, uci, l)
+		newProductExportRoutes(h, uce, l)
+		newVariantRoutes(h, ucv, l)
+		newPriceRoutes(h, ucpr, l)
+		newPromotionRoutes(h, ucpm, l)
+		newCategoryRoutes(h, ucg, locales, l)
+		newTrashRoutes(h, uct, l)
+	}
+}
+
+// actor identifies who made a change, for audit records like the price history
+func actor(c *gin.Context) string {
+	if userID := c.GetHeader(_actorHeader); userID != "" {
+		return userID
 	}
+	return "anonymous"
 }
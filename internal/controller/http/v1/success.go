@@ -3,9 +3,10 @@ package v1
 import "net/http"
 
 type restSuccess struct {
-	Code    int    `json:"code"`
-	Data    any    `json:"data"`
-	Message string `json:"message"`
+	Code       int    `json:"code"`
+	Data       any    `json:"data"`
+	Message    string `json:"message"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func newCreateSuccess(data any) restSuccess {
@@ -24,6 +25,16 @@ func newGetSuccess(data any) restSuccess {
 	}
 }
 
+// newGetPageSuccess is newGetSuccess for paginated lists, an empty cursor means last page
+func newGetPageSuccess(data any, nextCursor string) restSuccess {
+	return restSuccess{
+		Code:       http.StatusOK,
+		Data:       data,
+		Message:    "success get",
+		NextCursor: nextCursor,
+	}
+}
+
 func newUpdateSuccess(data any) restSuccess {
 	return restSuccess{
 		Code:    http.StatusOK,
@@ -38,3 +49,21 @@ func newDeleteSuccess() restSuccess {
 		Message: "success delete",
 	}
 }
+
+// newAcceptedSuccess is for work that goes on after the response, like an import job
+func newAcceptedSuccess(data any) restSuccess {
+	return restSuccess{
+		Code:    http.StatusAccepted,
+		Data:    data,
+		Message: "success accept",
+	}
+}
+
+// newMovedSuccess points to where a resource is found now, like an old slug to the current one
+func newMovedSuccess(data any) restSuccess {
+	return restSuccess{
+		Code:    http.StatusMovedPermanently,
+		Data:    data,
+		Message: "moved permanently",
+	}
+}
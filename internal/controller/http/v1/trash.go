@@ -0,0 +1,114 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+)
+
+type trashRoutes struct {
+	uc usecase.Trash
+	l  logger.Interface
+}
+
+func newTrashRoutes(handler *gin.RouterGroup, uc usecase.Trash, l logger.Interface) {
+	r := &trashRoutes{uc: uc, l: l}
+
+	h := handler.Group("/trash")
+	{
+		h.GET("/products", r.getDeletedProducts)
+		h.GET("/categories", r.getDeletedCategories)
+		h.POST("/products/:id/restore", r.restoreProduct)
+		h.POST("/categories/:id/restore", r.restoreCategory)
+	}
+}
+
+type trashProductResponse struct {
+	ID         string     `json:"id"`
+	SKU        string     `json:"sku"`
+	Name       string     `json:"name"`
+	CategoryID string     `json:"category_id"`
+	Status     string     `json:"status"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"` // left out once restored
+}
+
+type trashCategoryResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	ParentID  *string    `json:"parent_id"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // left out once restored
+}
+
+func (r *trashRoutes) getDeletedProducts(c *gin.Context) {
+	var query paginationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		r.l.Error(err, "http - v1 - trashRoutes - getDeletedProducts")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	products, nextCursor, err := r.uc.GetDeletedProducts(c.Request.Context(), paginationQueryToPagination(query))
+	if err != nil {
+		r.l.Error(err, "http - v1 - trashRoutes - getDeletedProducts")
+		if errors.Is(err, entity.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetPageSuccess(productEntitiesToTrashResponse(products), nextCursor))
+}
+
+func (r *trashRoutes) getDeletedCategories(c *gin.Context) {
+	categories, err := r.uc.GetDeletedCategories(c.Request.Context())
+	if err != nil {
+		r.l.Error(err, "http - v1 - trashRoutes - getDeletedCategories")
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(categoryEntitiesToTrashResponse(categories)))
+}
+
+func (r *trashRoutes) restoreProduct(c *gin.Context) {
+	product, err := r.uc.RestoreProduct(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - trashRoutes - restoreProduct")
+		switch {
+		case errors.Is(err, entity.ErrProductNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		case errors.Is(err, entity.ErrParentDeleted):
+			c.JSON(http.StatusConflict, newConflictError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(productEntityToTrashResponse(*product)))
+}
+
+func (r *trashRoutes) restoreCategory(c *gin.Context) {
+	category, err := r.uc.RestoreCategory(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - trashRoutes - restoreCategory")
+		switch {
+		case errors.Is(err, entity.ErrCategoryNotFound):
+			c.JSON(http.StatusNotFound, newNotFoundError(err.Error()))
+		case errors.Is(err, entity.ErrParentDeleted):
+			c.JSON(http.StatusConflict, newConflictError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(categoryEntityToTrashResponse(*category)))
+}
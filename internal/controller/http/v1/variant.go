@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/internal/usecase"
+	"github.com/idoyudha/eshop-product/pkg/logger"
+)
+
+type variantRoutes struct {
+	uc usecase.Variant
+	l  logger.Interface
+}
+
+func newVariantRoutes(handler *gin.RouterGroup, uc usecase.Variant, l logger.Interface) {
+	r := &variantRoutes{uc: uc, l: l}
+
+	h := handler.Group("/products/:id/variants")
+	{
+		h.POST("", r.createVariant)
+		h.GET("", r.getVariants)
+		h.GET("/:variant_id", r.getVariantByID)
+		h.PUT("/:variant_id", r.updateVariant)
+		h.DELETE("/:variant_id", r.deleteVariant)
+	}
+}
+
+type createVariantRequest struct {
+	Options  map[string]string `json:"options" binding:"required,min=1"`
+	Price    *moneyRequest     `json:"price"` // overrides the product price, in its currency
+	Quantity int               `json:"quantity" binding:"gte=0"`
+}
+
+type variantResponse struct {
+	ID        string            `json:"id"`
+	ProductID string            `json:"product_id"`
+	SKU       string            `json:"sku"`
+	Options   map[string]string `json:"options"`
+	Price     *moneyResponse    `json:"price"`
+	Quantity  int               `json:"quantity"`
+}
+
+func (r *variantRoutes) createVariant(c *gin.Context) {
+	var request createVariantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - variantRoutes - createVariant")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	variantEntity := createVariantRequestToVariantEntity(request, c.Param("id"))
+
+	variant, err := r.uc.CreateVariant(c.Request.Context(), &variantEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - variantRoutes - createVariant")
+		if errors.Is(err, entity.ErrInvalidMoney) || errors.Is(err, entity.ErrCurrencyMismatch) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCreateSuccess(variantEntityToVariantResponse(*variant)))
+}
+
+func (r *variantRoutes) getVariants(c *gin.Context) {
+	variants, err := r.uc.GetVariants(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - variantRoutes - getVariants")
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(variantEntitiesToVariantResponse(variants)))
+}
+
+func (r *variantRoutes) getVariantByID(c *gin.Context) {
+	variant, err := r.uc.GetVariantByID(c.Request.Context(), c.Param("id"), c.Param("variant_id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - variantRoutes - getVariantByID")
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetSuccess(variantEntityToVariantResponse(*variant)))
+}
+
+type updateVariantRequest struct {
+	Options map[string]string `json:"options" binding:"required,min=1"`
+	Price   *moneyRequest     `json:"price"`
+}
+
+func (r *variantRoutes) updateVariant(c *gin.Context) {
+	var request updateVariantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		r.l.Error(err, "http - v1 - variantRoutes - updateVariant")
+		c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	variantEntity := updateVariantRequestToVariantEntity(request, c.Param("id"), c.Param("variant_id"))
+
+	err := r.uc.UpdateVariant(c.Request.Context(), &variantEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - variantRoutes - updateVariant")
+		if errors.Is(err, entity.ErrInvalidMoney) || errors.Is(err, entity.ErrCurrencyMismatch) {
+			c.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newUpdateSuccess(variantEntityToVariantResponse(variantEntity)))
+}
+
+func (r *variantRoutes) deleteVariant(c *gin.Context) {
+	err := r.uc.DeleteVariant(c.Request.Context(), c.Param("id"), c.Param("variant_id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - variantRoutes - deleteVariant")
+		c.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, newDeleteSuccess())
+}
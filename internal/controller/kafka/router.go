@@ -3,6 +3,8 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -19,16 +21,22 @@ import (
 
 type kafkaConsumerRoutes struct {
 	ucp usecase.Product
+	ucv usecase.Variant
+	ucr usecase.Reservation
 	l   logger.Interface
 }
 
 func KafkaNewRouter(
 	ucp usecase.Product,
+	ucv usecase.Variant,
+	ucr usecase.Reservation,
 	l logger.Interface,
 	c *kafkaConSrv.ConsumerServer,
 ) error {
 	routes := &kafkaConsumerRoutes{
 		ucp: ucp,
+		ucv: ucv,
+		ucr: ucr,
 		l:   l,
 	}
 
@@ -62,6 +70,18 @@ func KafkaNewRouter(
 				if err := routes.handleProductQuantityUpdated(ev); err != nil {
 					l.Error("Failed to handle product update: %w", err)
 				}
+			case kafkaConSrv.OrderCreatedTopic:
+				if err := routes.handleOrderCreated(ev); err != nil {
+					l.Error("Failed to handle order created: %w", err)
+				}
+			case kafkaConSrv.OrderCancelledTopic:
+				if err := routes.handleOrderCancelled(ev); err != nil {
+					l.Error("Failed to handle order cancelled: %w", err)
+				}
+			case kafkaConSrv.OrderPaidTopic:
+				if err := routes.handleOrderPaid(ev); err != nil {
+					l.Error("Failed to handle order paid: %w", err)
+				}
 			default:
 				l.Info("Unknown topic: %s", *ev.TopicPartition.Topic)
 			}
@@ -73,9 +93,15 @@ func KafkaNewRouter(
 	return nil
 }
 
+// kafkaProductQuantityUpdatedMessage carries the stock on hand of a product or variant. A
+// message without event_id is known by its topic, partition and offset, so a redelivery of
+// it is still recognized.
 type kafkaProductQuantityUpdatedMessage struct {
-	ProductID uuid.UUID `json:"product_id"`
-	Quantity  int       `json:"quantity"`
+	EventID   string     `json:"event_id,omitempty"`
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity"`
+	Reason    string     `json:"reason,omitempty"` // one of the stock movement reasons, adjustment when left out
 }
 
 func (r *kafkaConsumerRoutes) handleProductQuantityUpdated(msg *kafka.Message) error {
@@ -84,12 +110,46 @@ func (r *kafkaConsumerRoutes) handleProductQuantityUpdated(msg *kafka.Message) e
 		return err
 	}
 
-	product := &entity.Product{
-		ID:       message.ProductID.String(),
-		Quantity: message.Quantity,
+	movement := entity.StockMovement{
+		EventID: message.EventID,
+		Reason:  entity.StockMovementReason(message.Reason),
+		Source:  *msg.TopicPartition.Topic,
+	}
+	if movement.EventID == "" {
+		movement.EventID = fmt.Sprintf("%s:%d:%d", *msg.TopicPartition.Topic, msg.TopicPartition.Partition, msg.TopicPartition.Offset)
+	}
+	if movement.Reason == "" {
+		movement.Reason = entity.StockReasonAdjustment
+	}
+	// the ledger only knows its own reasons, a message with another one is not applied
+	if !movement.Reason.Valid() {
+		err := fmt.Errorf("%w: unknown reason %q, event: %s", entity.ErrInvalidStockMovement, message.Reason, movement.EventID)
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleProductQuantityUpdated")
+		return err
+	}
+
+	// the stock of a single variant when the message targets one
+	if message.VariantID != nil {
+		err := r.ucv.UpdateVariantQuantity(context.Background(), message.ProductID.String(), message.VariantID.String(), message.Quantity, movement)
+		if errors.Is(err, entity.ErrDuplicateStockEvent) {
+			r.l.Info("Duplicate stock event skipped", "http - v1 - kafkaConsumerRoutes - handleProductQuantityUpdated")
+			return nil
+		}
+		if err != nil {
+			r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleProductQuantityUpdated")
+			return err
+		}
+
+		r.l.Info("Variant quantity updated", "http - v1 - kafkaConsumerRoutes - handleProductQuantityUpdated")
+		return nil
 	}
 
-	if err := r.ucp.UpdateProductQuantity(context.Background(), product.ID, product.Quantity); err != nil {
+	err := r.ucp.UpdateProductQuantity(context.Background(), message.ProductID.String(), message.Quantity, movement)
+	if errors.Is(err, entity.ErrDuplicateStockEvent) {
+		r.l.Info("Duplicate stock event skipped", "http - v1 - kafkaConsumerRoutes - handleProductQuantityUpdated")
+		return nil
+	}
+	if err != nil {
 		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleProductQuantityUpdated")
 		return err
 	}
@@ -98,3 +158,77 @@ func (r *kafkaConsumerRoutes) handleProductQuantityUpdated(msg *kafka.Message) e
 
 	return nil
 }
+
+type kafkaOrderItem struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"` // the stock of the variant is reserved when set
+	Quantity  int        `json:"quantity"`
+}
+
+type kafkaOrderCreatedMessage struct {
+	OrderID uuid.UUID        `json:"order_id"`
+	Items   []kafkaOrderItem `json:"items"`
+}
+
+// kafkaOrderMessage is the message of order-cancelled and order-paid
+type kafkaOrderMessage struct {
+	OrderID uuid.UUID `json:"order_id"`
+}
+
+func (r *kafkaConsumerRoutes) handleOrderCreated(msg *kafka.Message) error {
+	var message kafkaOrderCreatedMessage
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		return err
+	}
+
+	items := make([]entity.OrderItem, 0, len(message.Items))
+	for _, item := range message.Items {
+		orderItem := entity.OrderItem{
+			ProductID: item.ProductID.String(),
+			Quantity:  item.Quantity,
+		}
+		if item.VariantID != nil {
+			orderItem.VariantID = item.VariantID.String()
+		}
+		items = append(items, orderItem)
+	}
+
+	// a failed reservation is sent to stock-reservation-failed by the usecase
+	if _, err := r.ucr.ReserveStock(context.Background(), message.OrderID.String(), items); err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleOrderCreated")
+		return err
+	}
+
+	r.l.Info("Stock reserved", "http - v1 - kafkaConsumerRoutes - handleOrderCreated")
+	return nil
+}
+
+func (r *kafkaConsumerRoutes) handleOrderCancelled(msg *kafka.Message) error {
+	var message kafkaOrderMessage
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		return err
+	}
+
+	if _, err := r.ucr.ReleaseReservation(context.Background(), message.OrderID.String()); err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleOrderCancelled")
+		return err
+	}
+
+	r.l.Info("Stock reservation released", "http - v1 - kafkaConsumerRoutes - handleOrderCancelled")
+	return nil
+}
+
+func (r *kafkaConsumerRoutes) handleOrderPaid(msg *kafka.Message) error {
+	var message kafkaOrderMessage
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		return err
+	}
+
+	if _, err := r.ucr.CommitReservation(context.Background(), message.OrderID.String()); err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleOrderPaid")
+		return err
+	}
+
+	r.l.Info("Stock reservation committed", "http - v1 - kafkaConsumerRoutes - handleOrderPaid")
+	return nil
+}
@@ -0,0 +1,174 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAttributeSchema = errors.New("invalid attribute schema")
+	ErrInvalidAttributes      = errors.New("invalid product attributes")
+)
+
+type AttributeType string
+
+const (
+	AttributeTypeString  AttributeType = "string"
+	AttributeTypeInteger AttributeType = "integer"
+	AttributeTypeDecimal AttributeType = "decimal"
+	AttributeTypeBoolean AttributeType = "boolean"
+	AttributeTypeEnum    AttributeType = "enum"
+)
+
+func (t AttributeType) valid() bool {
+	switch t {
+	case AttributeTypeString, AttributeTypeInteger, AttributeTypeDecimal, AttributeTypeBoolean, AttributeTypeEnum:
+		return true
+	}
+	return false
+}
+
+// AttributeDefinition is one attribute of a category schema, e.g. "ram" as an integer in GB
+type AttributeDefinition struct {
+	Name          string
+	Type          AttributeType
+	Required      bool
+	AllowedValues []string // only for enum
+	Unit          string
+}
+
+// ProductAttributes are the values of a product keyed by attribute name, a value is a
+// string (string and enum), an int64 (integer), a float64 (decimal) or a bool (boolean)
+type ProductAttributes map[string]interface{}
+
+// ValidateAttributeSchema checks that the definitions of a category are usable
+func ValidateAttributeSchema(schema []AttributeDefinition) error {
+	names := make(map[string]struct{}, len(schema))
+	for _, definition := range schema {
+		if strings.TrimSpace(definition.Name) == "" {
+			return fmt.Errorf("%w: attribute name is required", ErrInvalidAttributeSchema)
+		}
+		if _, ok := names[definition.Name]; ok {
+			return fmt.Errorf("%w: duplicate attribute %s", ErrInvalidAttributeSchema, definition.Name)
+		}
+		names[definition.Name] = struct{}{}
+
+		if !definition.Type.valid() {
+			return fmt.Errorf("%w: unknown type %s of attribute %s", ErrInvalidAttributeSchema, definition.Type, definition.Name)
+		}
+		if definition.Type == AttributeTypeEnum && len(definition.AllowedValues) == 0 {
+			return fmt.Errorf("%w: enum attribute %s needs allowed values", ErrInvalidAttributeSchema, definition.Name)
+		}
+		if definition.Type != AttributeTypeEnum && len(definition.AllowedValues) > 0 {
+			return fmt.Errorf("%w: only enum attributes have allowed values, %s is %s", ErrInvalidAttributeSchema, definition.Name, definition.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateAttributes checks the values against the schema of the category and returns
+// them converted to their attribute type. Every problem is reported, not only the first.
+func ValidateAttributes(schema []AttributeDefinition, values ProductAttributes) (ProductAttributes, error) {
+	definitions := make(map[string]AttributeDefinition, len(schema))
+	for _, definition := range schema {
+		definitions[definition.Name] = definition
+	}
+
+	var problems []string
+	validated := make(ProductAttributes, len(values))
+
+	for name, value := range values {
+		definition, ok := definitions[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not an attribute of the category", name))
+			continue
+		}
+
+		converted, err := convertAttribute(definition, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s %s", name, err.Error()))
+			continue
+		}
+		validated[name] = converted
+	}
+
+	for _, definition := range schema {
+		if _, ok := values[definition.Name]; definition.Required && !ok {
+			problems = append(problems, fmt.Sprintf("%s is required", definition.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		// map iteration order is random, keep the message stable
+		sort.Strings(problems)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAttributes, strings.Join(problems, ", "))
+	}
+	return validated, nil
+}
+
+func convertAttribute(definition AttributeDefinition, value interface{}) (interface{}, error) {
+	switch definition.Type {
+	case AttributeTypeString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		return s, nil
+
+	case AttributeTypeEnum:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		for _, allowed := range definition.AllowedValues {
+			if s == allowed {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(definition.AllowedValues, ", "))
+
+	case AttributeTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, errors.New("must be a boolean")
+		}
+		return b, nil
+
+	case AttributeTypeInteger:
+		f, ok := numberValue(value)
+		if !ok || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return nil, errors.New("must be an integer")
+		}
+		return int64(f), nil
+
+	case AttributeTypeDecimal:
+		f, ok := numberValue(value)
+		if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, errors.New("must be a number")
+		}
+		return f, nil
+	}
+
+	return nil, fmt.Errorf("has unknown type %s", definition.Type)
+}
+
+// numberValue accepts the number types a value can have after json decoding or a read from dynamo
+func numberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case json.Number:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidBundle = errors.New("invalid bundle")
+
+type ProductType string
+
+const (
+	ProductSimple ProductType = "simple"
+	ProductBundle ProductType = "bundle" // a kit of other products, its stock is derived from theirs
+)
+
+type BundlePricing string
+
+const (
+	BundlePriceFixed    BundlePricing = "fixed"    // the price of the bundle product
+	BundlePriceDiscount BundlePricing = "discount" // a percentage off the sum of the component prices
+)
+
+// BundleComponent is a product in a bundle and how many of it one bundle holds
+type BundleComponent struct {
+	ProductID string
+	Quantity  int
+}
+
+type Bundle struct {
+	Components      []BundleComponent
+	Pricing         BundlePricing
+	DiscountPercent float64 // for discount pricing
+}
+
+func (p *Product) IsBundle() bool {
+	return p.Type == ProductBundle
+}
+
+// Validate checks the bundle of the product, a bundle holds other products only once each
+func (b *Bundle) Validate(productID string) error {
+	if len(b.Components) == 0 {
+		return fmt.Errorf("%w: a bundle needs at least one component", ErrInvalidBundle)
+	}
+
+	seen := make(map[string]bool, len(b.Components))
+	for _, component := range b.Components {
+		if component.ProductID == "" || component.ProductID == productID {
+			return fmt.Errorf("%w: a component has to be another product", ErrInvalidBundle)
+		}
+		if seen[component.ProductID] {
+			return fmt.Errorf("%w: product %s is a component more than once", ErrInvalidBundle, component.ProductID)
+		}
+		if component.Quantity <= 0 {
+			return fmt.Errorf("%w: quantity of component %s must be greater than 0", ErrInvalidBundle, component.ProductID)
+		}
+		seen[component.ProductID] = true
+	}
+
+	switch b.Pricing {
+	case BundlePriceFixed:
+	case BundlePriceDiscount:
+		if b.DiscountPercent <= 0 || b.DiscountPercent >= 100 {
+			return fmt.Errorf("%w: discount must be greater than 0 and less than 100", ErrInvalidBundle)
+		}
+	default:
+		return fmt.Errorf("%w: unknown pricing %q", ErrInvalidBundle, b.Pricing)
+	}
+	return nil
+}
+
+// Availability is how many bundles the stock of the components makes up, the minimum
+// over the components of their quantity divided by the quantity one bundle holds.
+// A component that is missing from the map has no stock.
+func (b *Bundle) Availability(components map[string]Product) int {
+	available := math.MaxInt
+	for _, component := range b.Components {
+		product, ok := components[component.ProductID]
+		if !ok || product.Quantity <= 0 {
+			return 0
+		}
+		available = min(available, product.Quantity/component.Quantity)
+	}
+	if available == math.MaxInt {
+		return 0
+	}
+	return available
+}
+
+// Price returns the sum of the component prices less the discount, rounded half away
+// from zero to the minor unit. Every component has to be priced in the currency.
+func (b *Bundle) Price(components map[string]Product, currency string) (Money, error) {
+	var sum int64
+	for _, component := range b.Components {
+		product, ok := components[component.ProductID]
+		if !ok {
+			return Money{}, fmt.Errorf("%w: component %s not found", ErrInvalidBundle, component.ProductID)
+		}
+		if product.Price.Currency != currency {
+			return Money{}, fmt.Errorf("%w: component %s is priced in %s, the bundle in %s",
+				ErrCurrencyMismatch, component.ProductID, product.Price.Currency, currency)
+		}
+		sum += product.Price.Amount * int64(component.Quantity)
+	}
+
+	discount := int64(math.Round(float64(sum) * b.DiscountPercent / 100))
+	return Money{Amount: sum - discount, Currency: currency}, nil
+}
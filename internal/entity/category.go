@@ -1,18 +1,26 @@
 package entity
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var ErrCategoryNotFound = errors.New("category not found")
+
 type Category struct {
-	ID        string
-	Name      string
-	ParentID  *string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt *time.Time
+	ID         string
+	Name       string
+	Slug       string // unique among categories, empty for categories created before slugs
+	ParentID   *string
+	Attributes []AttributeDefinition // schema of the attributes of its products
+	// Name in the other locales, keyed by locale
+	Translations map[string]CategoryTranslation
+	Version      int64 // counts the edits
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    *time.Time
 }
 
 func (p *Category) GenerateCategoryID() error {
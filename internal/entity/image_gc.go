@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// StoredImage is an object in the product image bucket
+type StoredImage struct {
+	Key          string
+	URL          string
+	Size         int64
+	LastModified time.Time
+}
+
+// ImageGCReport is the outcome of one garbage collection run, on a dry run
+// the orphans are reported but not deleted
+type ImageGCReport struct {
+	DryRun        bool
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	Scanned       int
+	Referenced    int
+	InGracePeriod int
+	Orphans       []StoredImage
+	OrphanBytes   int64
+	Deleted       int
+
+	// uploads under upload/ that were never confirmed within the upload url ttl
+	ExpiredUploads []StoredImage
+	DeletedUploads int
+}
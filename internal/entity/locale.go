@@ -0,0 +1,197 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidLocale       = errors.New("invalid locale")
+	ErrTranslationNotFound = errors.New("translation not found")
+)
+
+// ProductTranslation is the content of a product in another locale than the default,
+// an empty field falls back to the next locale of the chain
+type ProductTranslation struct {
+	Name        string
+	Description string
+}
+
+func (t ProductTranslation) Validate() error {
+	if t.Name == "" && t.Description == "" {
+		return fmt.Errorf("%w: a translation needs a name or a description", ErrInvalidLocale)
+	}
+	return nil
+}
+
+type CategoryTranslation struct {
+	Name string
+}
+
+func (t CategoryTranslation) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("%w: a translation needs a name", ErrInvalidLocale)
+	}
+	return nil
+}
+
+// Locales is the locale setup of the catalog. The untranslated content is in the
+// default locale, the translations are in the other supported locales.
+type Locales struct {
+	Default   string
+	supported map[string]bool
+	fallbacks map[string]string
+}
+
+// NewLocales checks the setup, every locale a fallback names has to be supported
+func NewLocales(defaultLocale string, supported []string, fallbacks map[string]string) (*Locales, error) {
+	defaultLocale, ok := NormalizeLocale(defaultLocale)
+	if !ok {
+		return nil, fmt.Errorf("%w: default locale %q", ErrInvalidLocale, defaultLocale)
+	}
+
+	l := &Locales{
+		Default:   defaultLocale,
+		supported: map[string]bool{defaultLocale: true},
+		fallbacks: make(map[string]string, len(fallbacks)),
+	}
+	for _, locale := range supported {
+		normalized, ok := NormalizeLocale(locale)
+		if !ok {
+			return nil, fmt.Errorf("%w: supported locale %q", ErrInvalidLocale, locale)
+		}
+		l.supported[normalized] = true
+	}
+	for from, to := range fallbacks {
+		normalizedFrom, okFrom := NormalizeLocale(from)
+		normalizedTo, okTo := NormalizeLocale(to)
+		if !okFrom || !okTo || !l.supported[normalizedFrom] || !l.supported[normalizedTo] {
+			return nil, fmt.Errorf("%w: fallback %s to %s, both have to be supported", ErrInvalidLocale, from, to)
+		}
+		l.fallbacks[normalizedFrom] = normalizedTo
+	}
+	return l, nil
+}
+
+// NormalizeLocale writes a language tag like en or en-SG in its usual case, ok is false
+// for anything else
+func NormalizeLocale(locale string) (string, bool) {
+	language, region, hasRegion := strings.Cut(strings.TrimSpace(locale), "-")
+	if !isLetters(language, 2, 3) || (hasRegion && !isLetters(region, 2, 2)) {
+		return "", false
+	}
+	if hasRegion {
+		return strings.ToLower(language) + "-" + strings.ToUpper(region), true
+	}
+	return strings.ToLower(language), true
+}
+
+func isLetters(s string, minLen, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Locales) IsSupported(locale string) bool {
+	return l.supported[locale]
+}
+
+// Validate checks a locale a translation is stored under
+func (l *Locales) Validate(locale string) (string, error) {
+	normalized, ok := NormalizeLocale(locale)
+	if !ok || !l.supported[normalized] {
+		return "", fmt.Errorf("%w: %q is not supported", ErrInvalidLocale, locale)
+	}
+	if normalized == l.Default {
+		return "", fmt.Errorf("%w: %s is the default locale, the content itself is in it", ErrInvalidLocale, normalized)
+	}
+	return normalized, nil
+}
+
+// Match returns the first of the requested locales that is supported, by its language
+// when only that is supported, or the default locale when none is
+func (l *Locales) Match(requested ...string) string {
+	for _, locale := range requested {
+		normalized, ok := NormalizeLocale(locale)
+		if !ok {
+			continue
+		}
+		if l.supported[normalized] {
+			return normalized
+		}
+		if language, _, ok := strings.Cut(normalized, "-"); ok && l.supported[language] {
+			return language
+		}
+	}
+	return l.Default
+}
+
+// Chain is the order the translations are looked up in for a locale: the locale, its
+// fallbacks, its language and at last the default locale
+func (l *Locales) Chain(locale string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(locale string) bool {
+		if seen[locale] || !l.supported[locale] {
+			return false
+		}
+		seen[locale] = true
+		chain = append(chain, locale)
+		return true
+	}
+
+	for next := locale; next != "" && add(next); {
+		next = l.fallbacks[next]
+	}
+	if language, _, ok := strings.Cut(locale, "-"); ok {
+		for next := language; next != "" && add(next); {
+			next = l.fallbacks[next]
+		}
+	}
+	add(l.Default)
+
+	// the default locale is the content itself, nothing after it is looked at
+	for i, c := range chain {
+		if c == l.Default {
+			return chain[:i+1]
+		}
+	}
+	return chain
+}
+
+// Localize replaces the name and the description with the first translation of the chain
+// that has them, the content stays as it is for the default locale
+func (p *Product) Localize(chain []string) {
+	var name, description string
+	for _, locale := range chain {
+		translation := p.Translations[locale]
+		if name == "" {
+			name = translation.Name
+		}
+		if description == "" {
+			description = translation.Description
+		}
+	}
+	if name != "" {
+		p.Name = name
+	}
+	if description != "" {
+		p.Description = description
+	}
+}
+
+func (c *Category) Localize(chain []string) {
+	for _, locale := range chain {
+		if translation, ok := c.Translations[locale]; ok && translation.Name != "" {
+			c.Name = translation.Name
+			return
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidMoney     = errors.New("invalid money")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// DefaultCurrency is the currency of prices stored before they had one
+const DefaultCurrency = "USD"
+
+// _currencyExponents holds the number of digits of the minor unit of the supported ISO 4217 currencies
+var _currencyExponents = map[string]int{
+	"AUD": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"IDR": 2,
+	"INR": 2,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"MYR": 2,
+	"NZD": 2,
+	"OMR": 3,
+	"PHP": 2,
+	"SGD": 2,
+	"THB": 2,
+	"USD": 2,
+	"VND": 0,
+}
+
+// Money is an exact amount in the minor unit of an ISO 4217 currency, 19.99 USD is {1999, "USD"}
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// CurrencyExponent returns the number of digits of the minor unit of the currency
+func CurrencyExponent(currency string) (int, bool) {
+	exponent, ok := _currencyExponents[currency]
+	return exponent, ok
+}
+
+func (m Money) Validate() error {
+	if _, ok := CurrencyExponent(m.Currency); !ok {
+		return fmt.Errorf("%w: unsupported currency %q", ErrInvalidMoney, m.Currency)
+	}
+	if m.Amount < 0 {
+		return fmt.Errorf("%w: amount must not be negative", ErrInvalidMoney)
+	}
+	return nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Decimal formats the amount in the major unit, {1999, "USD"} is "19.99"
+func (m Money) Decimal() string {
+	exponent, _ := CurrencyExponent(m.Currency)
+	if exponent == 0 {
+		return fmt.Sprintf("%d", m.Amount)
+	}
+
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	unit := int64(math.Pow10(exponent))
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/unit, exponent, amount%unit)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// ParseMoney reads an amount the client gave in the major unit. Only a plain decimal like
+// "19.99" is accepted, an amount more precise than the minor unit of the currency is rejected
+// instead of rounded.
+func ParseMoney(amount, currency string) (Money, error) {
+	exponent, ok := CurrencyExponent(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: unsupported currency %q", ErrInvalidMoney, currency)
+	}
+
+	digits := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(strings.TrimPrefix(digits, "-"), "+")
+
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(fraction)) {
+		return Money{}, fmt.Errorf("%w: %q is not a decimal amount", ErrInvalidMoney, amount)
+	}
+	// trailing zeros add no precision, 19.990 is 19.99
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > exponent {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidMoney, amount, exponent, currency)
+	}
+
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidMoney, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseStoredMoney reads an amount stored in the major unit. Digits beyond the minor unit
+// are rounded half away from zero, prices stored as floats before can carry such noise.
+func ParseStoredMoney(amount, currency string) (Money, error) {
+	exponent, ok := CurrencyExponent(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: unsupported currency %q", ErrInvalidMoney, currency)
+	}
+
+	value, ok := new(big.Rat).SetString(strings.TrimSpace(amount))
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q is not a decimal amount", ErrInvalidMoney, amount)
+	}
+
+	value.Mul(value, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)))
+
+	// round half away from zero
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(value.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(value.Sign())))
+	}
+	if !quotient.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidMoney, amount)
+	}
+
+	return Money{Amount: quotient.Int64(), Currency: currency}, nil
+}
+
+// ValidatePriceList checks the prices of a product in other currencies, every currency
+// appears once and differs from the currency of the base price
+func ValidatePriceList(base Money, prices []Money) error {
+	seen := make(map[string]bool, len(prices))
+	for _, price := range prices {
+		if err := price.Validate(); err != nil {
+			return err
+		}
+		if price.Amount == 0 {
+			return fmt.Errorf("%w: price in %s must be greater than 0", ErrInvalidMoney, price.Currency)
+		}
+		if price.Currency == base.Currency {
+			return fmt.Errorf("%w: price list repeats the base currency %s", ErrInvalidMoney, price.Currency)
+		}
+		if seen[price.Currency] {
+			return fmt.Errorf("%w: price list has %s more than once", ErrInvalidMoney, price.Currency)
+		}
+		seen[price.Currency] = true
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"19.99", "USD", 1999, false},
+		{"19.9", "USD", 1990, false},
+		{"19", "USD", 1900, false},
+		{"0.01", "USD", 1, false},
+		{" 7 ", "USD", 700, false},
+		// trailing zeros add no precision
+		{"19.990", "USD", 1999, false},
+		{"100.000", "JPY", 100, false},
+		{"19.999", "USD", 0, true},
+		{"100", "JPY", 100, false},
+		{"100.5", "JPY", 0, true},
+		{"0.125", "KWD", 125, false},
+		{"1.2345", "KWD", 0, true},
+		{"+5.25", "USD", 525, false},
+		{"-1.5", "USD", -150, false},
+		{"--1", "USD", 0, true},
+		{"+-1", "USD", 0, true},
+		{".5", "USD", 0, true},
+		{"5.", "USD", 0, true},
+		{"1/3", "USD", 0, true},
+		{"1e3", "USD", 0, true},
+		{"1,000", "USD", 0, true},
+		{"", "USD", 0, true},
+		{"92233720368547758.07", "USD", 9223372036854775807, false},
+		{"92233720368547758.08", "USD", 0, true},
+		{"99999999999999999999", "USD", 0, true},
+		{"1", "XXX", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.amount, tt.currency)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidMoney) {
+				t.Errorf("ParseMoney(%q, %s) = %v, %v, want ErrInvalidMoney", tt.amount, tt.currency, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %s): %v", tt.amount, tt.currency, err)
+			continue
+		}
+		if got != (Money{Amount: tt.want, Currency: tt.currency}) {
+			t.Errorf("ParseMoney(%q, %s) = %+v, want %d", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestParseStoredMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"19.99", "USD", 1999, false},
+		// noise of a price stored as float
+		{"19.989999999999998", "USD", 1999, false},
+		{"0.30000000000000004", "USD", 30, false},
+		// half away from zero
+		{"0.005", "USD", 1, false},
+		{"0.0049", "USD", 0, false},
+		{"-0.005", "USD", -1, false},
+		{"2.5", "JPY", 3, false},
+		{"-2.5", "JPY", -3, false},
+		{"0.0015", "KWD", 2, false},
+		{"1.999E1", "USD", 1999, false},
+		{"not a number", "USD", 0, true},
+		{"1e30", "USD", 0, true},
+		{"1", "XXX", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStoredMoney(tt.amount, tt.currency)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidMoney) {
+				t.Errorf("ParseStoredMoney(%q, %s) = %v, %v, want ErrInvalidMoney", tt.amount, tt.currency, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseStoredMoney(%q, %s): %v", tt.amount, tt.currency, err)
+			continue
+		}
+		if got != (Money{Amount: tt.want, Currency: tt.currency}) {
+			t.Errorf("ParseStoredMoney(%q, %s) = %+v, want %d", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{1999, "USD"}, "19.99"},
+		{Money{5, "USD"}, "0.05"},
+		{Money{-150, "USD"}, "-1.50"},
+		{Money{100, "JPY"}, "100"},
+		{Money{125, "KWD"}, "0.125"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.want {
+			t.Errorf("%+v.Decimal() = %q, want %q", tt.money, got, tt.want)
+		}
+		// what is stored reads back the same
+		parsed, err := ParseMoney(tt.money.Decimal(), tt.money.Currency)
+		if err != nil || parsed != tt.money {
+			t.Errorf("ParseMoney(%q) = %+v, %v, want %+v", tt.money.Decimal(), parsed, err, tt.money)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "errors"
+
+const (
+	DefaultPageLimit int32 = 20
+	MaxPageLimit     int32 = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Pagination is the page size and the opaque cursor returned with the previous page.
+// An empty cursor starts from the beginning.
+type Pagination struct {
+	Limit  int32
+	Cursor string
+}
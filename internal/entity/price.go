@@ -0,0 +1,100 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPriceChange    = errors.New("invalid price change")
+	ErrPriceChangeNotFound   = errors.New("scheduled price change not found")
+	ErrPriceChangeNotPending = errors.New("scheduled price change is not pending")
+	ErrPriceHistoryNotFound  = errors.New("no price recorded at that time")
+)
+
+type PriceSource string
+
+const (
+	PriceSourceManual    PriceSource = "manual"
+	PriceSourceScheduled PriceSource = "scheduled"
+	PriceSourceImport    PriceSource = "import"
+	PriceSourceBundle    PriceSource = "bundle" // a discount bundle following the prices of its components
+)
+
+// PriceHistoryEntry records one price of a product, entries are never changed or removed.
+// The price is valid from EffectiveFrom until the EffectiveFrom of the next entry.
+type PriceHistoryEntry struct {
+	ID                string
+	ProductID         string
+	Price             Money
+	PreviousPrice     Money // zero for the first entry
+	EffectiveFrom     time.Time
+	Actor             string
+	Source            PriceSource
+	ScheduledChangeID string // set when Source is scheduled
+}
+
+func NewPriceHistoryEntry(product *Product, previousPrice Money, actor string, source PriceSource) (PriceHistoryEntry, error) {
+	entryID, err := uuid.NewV7()
+	if err != nil {
+		return PriceHistoryEntry{}, err
+	}
+
+	return PriceHistoryEntry{
+		ID:            entryID.String(),
+		ProductID:     product.ID,
+		Price:         product.Price,
+		PreviousPrice: previousPrice,
+		EffectiveFrom: time.Now(),
+		Actor:         actor,
+		Source:        source,
+	}, nil
+}
+
+type ScheduledPriceStatus string
+
+const (
+	ScheduledPricePending   ScheduledPriceStatus = "pending"
+	ScheduledPriceApplied   ScheduledPriceStatus = "applied"
+	ScheduledPriceCancelled ScheduledPriceStatus = "cancelled"
+	ScheduledPriceFailed    ScheduledPriceStatus = "failed"
+)
+
+// ScheduledPriceChange sets the price of a product once EffectiveFrom is reached
+type ScheduledPriceChange struct {
+	ID            string
+	ProductID     string
+	Price         Money
+	EffectiveFrom time.Time
+	Actor         string
+	Status        ScheduledPriceStatus
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (c *ScheduledPriceChange) GenerateID() error {
+	changeID, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	c.ID = changeID.String()
+	return nil
+}
+
+// Validate checks a new change, it has to take effect in the future
+func (c *ScheduledPriceChange) Validate(now time.Time) error {
+	if err := c.Price.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPriceChange, err)
+	}
+	if c.Price.Amount == 0 {
+		return fmt.Errorf("%w: price must be greater than 0", ErrInvalidPriceChange)
+	}
+	if !c.EffectiveFrom.After(now) {
+		return fmt.Errorf("%w: effective_from must be in the future", ErrInvalidPriceChange)
+	}
+	return nil
+}
@@ -1,24 +1,50 @@
 package entity
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/idoyudha/eshop-product/internal/utils"
 )
 
+var ErrProductNotFound = errors.New("product not found")
+
 type Product struct {
 	ID          string
 	SKU         string
 	Name        string
+	Slug        string // unique among products, empty for products created before slugs
 	ImageURL    string
 	Description string
-	Price       float64
-	Quantity    int
+	Price       Money
+	Prices      []Money // optional price list in other currencies
+	Quantity    int     // available, the stock on hand less what is reserved
+	Reserved    int     // held for open orders
+	Version     int64   // counts the edits, stock changes do not count
 	CategoryID  string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   *time.Time
+	Images      []ProductImage // ordered by position, ImageURL is the url of the primary one
+	Attributes  ProductAttributes
+	Type        ProductType // simple when empty
+	Bundle      *Bundle     // the components of a bundle, nil for a simple product
+	// Name and Description in the other locales, keyed by locale
+	Translations map[string]ProductTranslation
+	// ReorderThreshold is the available quantity at which the stock counts as low, nil for
+	// the default threshold
+	ReorderThreshold *int
+	StockAlert       StockAlert
+	// SalePrice and AppliedPromotions are computed from the active promotions on read, not
+	// stored. They cover the base price only, the price list is not discounted
+	SalePrice         Money
+	AppliedPromotions []AppliedPromotion
+	Status            ProductStatus
+	PublishAt         *time.Time // when a scheduled product gets published
+	PublishedAt       *time.Time // first publication, nil while the product was never published
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	UpdatedBy         string // actor of the last change
+	DeletedAt         *time.Time
 }
 
 func (p *Product) GenerateProductID() error {
@@ -38,3 +64,68 @@ func (p *Product) GenerateSKU() {
 func (p *Product) SetImageURL(imageURL string) {
 	p.ImageURL = imageURL
 }
+
+// ValidatePrices checks the base price and the price list
+func (p *Product) ValidatePrices() error {
+	if err := p.Price.Validate(); err != nil {
+		return err
+	}
+	if p.Price.Amount == 0 {
+		return fmt.Errorf("%w: price must be greater than 0", ErrInvalidMoney)
+	}
+	return ValidatePriceList(p.Price, p.Prices)
+}
+
+// PriceIn returns the price in the currency, from the price list when it is not the base currency
+func (p *Product) PriceIn(currency string) (Money, bool) {
+	if p.Price.Currency == currency {
+		return p.Price, true
+	}
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			return price, true
+		}
+	}
+	return Money{}, false
+}
+
+// ApplyUpdate copies the fields set on a partial update, like the dynamo update does
+func (p *Product) ApplyUpdate(update *Product) {
+	if update.Name != "" {
+		p.Name = update.Name
+	}
+	if update.Slug != "" {
+		p.Slug = update.Slug
+	}
+	if update.ImageURL != "" {
+		p.ImageURL = update.ImageURL
+	}
+	if update.Description != "" {
+		p.Description = update.Description
+	}
+	if update.Price.Amount > 0 {
+		p.Price = update.Price
+	}
+	if update.Prices != nil {
+		p.Prices = update.Prices
+	}
+	if update.Attributes != nil {
+		p.Attributes = update.Attributes
+	}
+	if update.ReorderThreshold != nil {
+		p.ReorderThreshold = update.ReorderThreshold
+	}
+	p.UpdatedAt = update.UpdatedAt
+	if update.UpdatedBy != "" {
+		p.UpdatedBy = update.UpdatedBy
+	}
+	if update.Version > 0 {
+		p.Version = update.Version
+	}
+}
+
+// ProductSearchHit is a product matched by a full-text search with its relevance score
+type ProductSearchHit struct {
+	Product Product
+	Score   float64
+}
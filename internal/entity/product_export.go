@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidExport = errors.New("invalid export")
+
+type ExportFormat string
+
+const (
+	ExportCSV       ExportFormat = "csv"
+	ExportJSONLines ExportFormat = "jsonl" // one json object per line, also known as ndjson
+)
+
+// ParseExportFormat reads the format of an export, ndjson is another name of jsonl
+func ParseExportFormat(format string) (ExportFormat, error) {
+	switch format {
+	case "", string(ExportCSV):
+		return ExportCSV, nil
+	case string(ExportJSONLines), "ndjson":
+		return ExportJSONLines, nil
+	}
+	return "", fmt.Errorf("%w: unknown format %q, use csv or jsonl", ErrInvalidExport, format)
+}
+
+func (f ExportFormat) ContentType() string {
+	if f == ExportJSONLines {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// ExportOptions selects the products of an export like the filters of a listing
+type ExportOptions struct {
+	Format          ExportFormat
+	Filter          ProductFilter
+	Sort            ProductSort
+	IncludeCategory bool // adds the category name of every product
+}
@@ -0,0 +1,206 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrProductImageNotFound = errors.New("product image not found")
+	ErrImageUploadNotFound  = errors.New("image upload not found or expired")
+	ErrImageSourceRequired  = errors.New("either image or upload_token is required")
+	ErrInvalidImageKey      = errors.New("image key must be an upload like upload/<token>")
+)
+
+// ImageUploadPrefix is where the presigned uploads are put, an image given by key has
+// to be one of them so no other object of the bucket can be copied into a product
+const ImageUploadPrefix = "upload/"
+
+type ProductImage struct {
+	ID         string
+	URL        string // the full size image with its metadata stripped
+	AltText    string
+	Position   int
+	IsPrimary  bool
+	Renditions []ImageRendition
+}
+
+// ImageRendition is a resized and re-encoded copy of a product image
+type ImageRendition struct {
+	Name   string
+	Format string
+	Width  int
+	Height int
+	URL    string
+}
+
+// ImageSource is where a new product image comes from, either a multipart file
+// or the token of an object uploaded with a presigned url. An import can also
+// point at an image by url or at an object already in the product bucket.
+type ImageSource struct {
+	File        *multipart.FileHeader
+	UploadToken string
+	URL         string
+	Key         string
+}
+
+func (s ImageSource) Validate() error {
+	set := 0
+	for _, ok := range []bool{s.File != nil, s.UploadToken != "", s.URL != "", s.Key != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return ErrImageSourceRequired
+	}
+	if s.Key != "" {
+		token, ok := strings.CutPrefix(s.Key, ImageUploadPrefix)
+		if parsed, err := uuid.Parse(token); !ok || err != nil || parsed.String() != token {
+			return fmt.Errorf("%w: %s", ErrInvalidImageKey, s.Key)
+		}
+	}
+	return nil
+}
+
+// ImageUpload is a presigned url the client puts the image to before creating
+// or updating a product with the token
+type ImageUpload struct {
+	Token     string
+	URL       string
+	Headers   map[string]string // headers the client must send with the PUT
+	ExpiresAt time.Time
+}
+
+// NewProductImage creates an image with a new id, the url is set once it is uploaded
+func NewProductImage(altText string) (ProductImage, error) {
+	imageID, err := uuid.NewV7()
+	if err != nil {
+		return ProductImage{}, err
+	}
+
+	return ProductImage{
+		ID:      imageID.String(),
+		AltText: altText,
+	}, nil
+}
+
+// AddImage appends an image to the gallery, the first image is always the primary one
+func (p *Product) AddImage(image ProductImage, primary bool) {
+	image.Position = len(p.Images)
+	p.Images = append(p.Images, image)
+	if primary || len(p.Images) == 1 {
+		p.setPrimary(image.ID)
+	}
+	p.normalizeImages()
+}
+
+// RemoveImage removes an image from the gallery and returns it,
+// the next image becomes primary when the primary one is removed
+func (p *Product) RemoveImage(imageID string) (ProductImage, error) {
+	for i, image := range p.Images {
+		if image.ID != imageID {
+			continue
+		}
+
+		p.Images = append(p.Images[:i:i], p.Images[i+1:]...)
+		if image.IsPrimary && len(p.Images) > 0 {
+			p.setPrimary(p.Images[0].ID)
+		}
+		p.normalizeImages()
+		return image, nil
+	}
+	return ProductImage{}, fmt.Errorf("%w: %s", ErrProductImageNotFound, imageID)
+}
+
+// ReorderImages sorts the gallery in the order of the given ids, every image id must be given once
+func (p *Product) ReorderImages(imageIDs []string) error {
+	if len(imageIDs) != len(p.Images) {
+		return fmt.Errorf("%w: expected %d image ids, got %d", ErrProductImageNotFound, len(p.Images), len(imageIDs))
+	}
+
+	positions := make(map[string]int, len(imageIDs))
+	for i, id := range imageIDs {
+		positions[id] = i
+	}
+	for _, image := range p.Images {
+		if _, ok := positions[image.ID]; !ok {
+			return fmt.Errorf("%w: %s", ErrProductImageNotFound, image.ID)
+		}
+	}
+
+	for i := range p.Images {
+		p.Images[i].Position = positions[p.Images[i].ID]
+	}
+	p.normalizeImages()
+	return nil
+}
+
+// UpdateImage changes the alt text of an image and makes it primary when asked
+func (p *Product) UpdateImage(imageID string, altText *string, primary bool) error {
+	for i := range p.Images {
+		if p.Images[i].ID != imageID {
+			continue
+		}
+
+		if altText != nil {
+			p.Images[i].AltText = *altText
+		}
+		if primary {
+			p.setPrimary(imageID)
+		}
+		p.normalizeImages()
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrProductImageNotFound, imageID)
+}
+
+// ReplacePrimaryImage swaps the primary image for a new one at the same position
+func (p *Product) ReplacePrimaryImage(image ProductImage) {
+	for i := range p.Images {
+		if p.Images[i].IsPrimary {
+			image.Position = p.Images[i].Position
+			image.IsPrimary = true
+			p.Images[i] = image
+			p.normalizeImages()
+			return
+		}
+	}
+	p.AddImage(image, true)
+}
+
+func (p *Product) PrimaryImage() (ProductImage, bool) {
+	for _, image := range p.Images {
+		if image.IsPrimary {
+			return image, true
+		}
+	}
+	return ProductImage{}, false
+}
+
+func (p *Product) setPrimary(imageID string) {
+	for i := range p.Images {
+		p.Images[i].IsPrimary = p.Images[i].ID == imageID
+	}
+}
+
+// normalizeImages sorts by position, renumbers positions from zero and keeps ImageURL on the primary image
+func (p *Product) normalizeImages() {
+	sort.SliceStable(p.Images, func(a, b int) bool {
+		return p.Images[a].Position < p.Images[b].Position
+	})
+	for i := range p.Images {
+		p.Images[i].Position = i
+	}
+
+	p.ImageURL = ""
+	if primary, ok := p.PrimaryImage(); ok {
+		p.ImageURL = primary.URL
+	}
+}
@@ -0,0 +1,258 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrImportJobNotFound = errors.New("import job not found")
+	ErrInvalidImportFile = errors.New("invalid import file")
+)
+
+type ImportJobStatus string
+
+const (
+	ImportPending   ImportJobStatus = "pending"
+	ImportRunning   ImportJobStatus = "running"
+	ImportCompleted ImportJobStatus = "completed"
+	ImportFailed    ImportJobStatus = "failed" // the file could not be read to the end
+)
+
+// the product fields a csv column can be mapped to
+const (
+	ImportFieldName        = "name"
+	ImportFieldDescription = "description"
+	ImportFieldPrice       = "price" // a decimal in the major unit, 19.99
+	ImportFieldCurrency    = "currency"
+	ImportFieldQuantity    = "quantity"
+	ImportFieldCategoryID  = "category_id"
+	ImportFieldAttributes  = "attributes" // json object of attribute values
+	ImportFieldStatus      = "status"
+	ImportFieldPublishAt   = "publish_at"
+	ImportFieldImageURL    = "image_url" // an image to download
+	ImportFieldImageKey    = "image_key" // an upload in the product bucket, upload/<token>
+)
+
+var (
+	_importFields = []string{
+		ImportFieldName, ImportFieldDescription, ImportFieldPrice, ImportFieldCurrency,
+		ImportFieldQuantity, ImportFieldCategoryID, ImportFieldAttributes, ImportFieldStatus,
+		ImportFieldPublishAt, ImportFieldImageURL, ImportFieldImageKey,
+	}
+	_importRequiredFields = []string{ImportFieldName, ImportFieldPrice, ImportFieldQuantity, ImportFieldCategoryID}
+)
+
+// ImportMapping maps a product field to the header of the csv column holding it,
+// a field left out of the mapping is read from the column named like the field
+type ImportMapping map[string]string
+
+// ImportColumns is the index of the column of every mapped field
+type ImportColumns map[string]int
+
+// Columns resolves the mapping against the header row of the file
+func (m ImportMapping) Columns(header []string) (ImportColumns, error) {
+	known := make(map[string]bool, len(_importFields))
+	for _, field := range _importFields {
+		known[field] = true
+	}
+
+	var unknown []string
+	for field := range m {
+		if !known[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: unknown fields in mapping: %s", ErrInvalidImportFile, strings.Join(unknown, ", "))
+	}
+
+	indexes := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if i == 0 {
+			// spreadsheets like to start the file with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		indexes[name] = i
+	}
+
+	columns := make(ImportColumns, len(_importFields))
+	for _, field := range _importFields {
+		column, ok := m[field]
+		if !ok {
+			column = field
+		}
+		if index, ok := indexes[column]; ok {
+			columns[field] = index
+		}
+	}
+
+	var missing []string
+	for _, field := range _importRequiredFields {
+		if _, ok := columns[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: no column for %s", ErrInvalidImportFile, strings.Join(missing, ", "))
+	}
+
+	return columns, nil
+}
+
+// ImportJob is an asynchronous import of a csv file, the counts grow while it runs
+type ImportJob struct {
+	ID         string
+	FileName   string
+	Status     ImportJobStatus
+	TotalRows  int
+	Succeeded  int
+	Failed     int
+	Error      string // why the job failed, the row errors are kept apart
+	CreatedBy  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt *time.Time
+}
+
+func NewImportJob(fileName, createdBy string) (ImportJob, error) {
+	jobID, err := uuid.NewV7()
+	if err != nil {
+		return ImportJob{}, err
+	}
+
+	now := time.Now()
+	return ImportJob{
+		ID:        jobID.String(),
+		FileName:  fileName,
+		Status:    ImportPending,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// ImportRowError is why a row of the file was not imported, Row is the line in the file
+type ImportRowError struct {
+	JobID   string
+	Row     int
+	Field   string // empty when the row failed as a whole
+	Message string
+}
+
+// ImportFieldError is a value of a row that can not be imported
+type ImportFieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *ImportFieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ImportRow is a product read from a row, together with where its image comes from
+type ImportRow struct {
+	Row      int
+	Product  Product
+	ImageURL string
+	ImageKey string
+}
+
+// ParseImportRow reads the product of a row, the checks that need the category are left to the caller
+func ParseImportRow(row int, record []string, columns ImportColumns) (ImportRow, error) {
+	value := func(field string) string {
+		index, ok := columns[field]
+		if !ok || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+
+	parsed := ImportRow{
+		Row:      row,
+		ImageURL: value(ImportFieldImageURL),
+		ImageKey: value(ImportFieldImageKey),
+	}
+	product := &parsed.Product
+
+	product.Name = value(ImportFieldName)
+	if product.Name == "" {
+		return parsed, &ImportFieldError{Field: ImportFieldName, Message: "is required"}
+	}
+	product.Description = value(ImportFieldDescription)
+
+	product.CategoryID = value(ImportFieldCategoryID)
+	if product.CategoryID == "" {
+		return parsed, &ImportFieldError{Field: ImportFieldCategoryID, Message: "is required"}
+	}
+
+	currency := strings.ToUpper(value(ImportFieldCurrency))
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	price, err := ParseMoney(value(ImportFieldPrice), currency)
+	if err != nil {
+		return parsed, &ImportFieldError{Field: ImportFieldPrice, Message: err.Error()}
+	}
+	if price.Amount <= 0 {
+		return parsed, &ImportFieldError{Field: ImportFieldPrice, Message: "must be greater than 0"}
+	}
+	product.Price = price
+
+	quantity, err := strconv.Atoi(value(ImportFieldQuantity))
+	if err != nil {
+		return parsed, &ImportFieldError{Field: ImportFieldQuantity, Message: "must be a whole number"}
+	}
+	if quantity < 0 {
+		return parsed, &ImportFieldError{Field: ImportFieldQuantity, Message: "must not be negative"}
+	}
+	product.Quantity = quantity
+
+	if raw := value(ImportFieldAttributes); raw != "" {
+		decoder := json.NewDecoder(strings.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&product.Attributes); err != nil || product.Attributes == nil {
+			return parsed, &ImportFieldError{Field: ImportFieldAttributes, Message: "must be a json object"}
+		}
+	}
+
+	product.Status = ProductStatus(value(ImportFieldStatus))
+	if raw := value(ImportFieldPublishAt); raw != "" {
+		publishAt, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return parsed, &ImportFieldError{Field: ImportFieldPublishAt, Message: "must be an RFC 3339 time"}
+		}
+		product.PublishAt = &publishAt
+	}
+
+	if parsed.ImageURL != "" && parsed.ImageKey != "" {
+		return parsed, &ImportFieldError{Field: ImportFieldImageURL, Message: "only one of image_url and image_key can be set"}
+	}
+	if parsed.ImageKey != "" {
+		if err := (ImageSource{Key: parsed.ImageKey}).Validate(); err != nil {
+			return parsed, &ImportFieldError{Field: ImportFieldImageKey, Message: err.Error()}
+		}
+	}
+
+	return parsed, nil
+}
+
+// ImageSource returns where the image of the row comes from, ok is false when it has none
+func (r ImportRow) ImageSource() (ImageSource, bool) {
+	switch {
+	case r.ImageURL != "":
+		return ImageSource{URL: r.ImageURL}, true
+	case r.ImageKey != "":
+		return ImageSource{Key: r.ImageKey}, true
+	}
+	return ImageSource{}, false
+}
@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidProductLink  = errors.New("invalid product link")
+	ErrProductLinkNotFound = errors.New("product link not found")
+)
+
+// DefaultRelatedLimit is how many related products are returned when no limit is asked for
+const DefaultRelatedLimit = 10
+
+type ProductLinkType string
+
+const (
+	ProductLinkRelated   ProductLinkType = "related"
+	ProductLinkAccessory ProductLinkType = "accessory"
+	ProductLinkUpsell    ProductLinkType = "upsell"
+)
+
+func (t ProductLinkType) IsValid() bool {
+	switch t {
+	case ProductLinkRelated, ProductLinkAccessory, ProductLinkUpsell:
+		return true
+	}
+	return false
+}
+
+// ProductLink is a link a merchandiser made from a product to another one, links are
+// one way and listed by position
+type ProductLink struct {
+	ProductID       string
+	LinkedProductID string
+	Type            ProductLinkType
+	Position        int
+	CreatedAt       time.Time
+	CreatedBy       string
+}
+
+func (l *ProductLink) Validate() error {
+	if !l.Type.IsValid() {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidProductLink, l.Type)
+	}
+	if l.LinkedProductID == "" || l.LinkedProductID == l.ProductID {
+		return fmt.Errorf("%w: a product can only be linked to another product", ErrInvalidProductLink)
+	}
+	if l.Position < 0 {
+		return fmt.Errorf("%w: position can not be negative", ErrInvalidProductLink)
+	}
+	return nil
+}
+
+// RelatedSource tells where a related product comes from
+type RelatedSource string
+
+const (
+	RelatedSourceLink     RelatedSource = "link"     // linked by a merchandiser
+	RelatedSourceSimilar  RelatedSource = "similar"  // alike by name, description and category
+	RelatedSourceCategory RelatedSource = "category" // in the same category
+)
+
+// RelatedProduct is a product shown next to another one. Score is the similarity for
+// similar products and zero otherwise.
+type RelatedProduct struct {
+	Product Product
+	Type    ProductLinkType
+	Source  RelatedSource
+	Score   float64
+}
@@ -0,0 +1,94 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+type ProductSortField string
+
+const (
+	ProductSortPrice     ProductSortField = "price"
+	ProductSortName      ProductSortField = "name"
+	ProductSortCreatedAt ProductSortField = "created_at"
+	ProductSortUpdatedAt ProductSortField = "updated_at"
+)
+
+var ErrInvalidProductQuery = errors.New("invalid product query")
+
+// ProductFilter narrows a product listing, zero values mean no filter
+type ProductFilter struct {
+	CategoryID string
+	Name       string // case sensitive substring of the name
+	MinPrice   *Money // only products priced in the currency of the bound match
+	MaxPrice   *Money
+	InStock    *bool
+	Status     ProductStatus
+}
+
+// PriceCurrency returns the currency of the price bounds, empty when there are none
+func (f ProductFilter) PriceCurrency() string {
+	if f.MinPrice != nil {
+		return f.MinPrice.Currency
+	}
+	if f.MaxPrice != nil {
+		return f.MaxPrice.Currency
+	}
+	return ""
+}
+
+// ProductSort orders a product listing, an empty field keeps the storage order
+type ProductSort struct {
+	Field ProductSortField
+	Desc  bool
+}
+
+func (s ProductSort) IsSet() bool {
+	return s.Field != ""
+}
+
+// Validate rejects filter and sort combinations the product table can not serve. Sorting
+// is backed by the category_id-<field>-index GSIs, or by the catalog-<field>-index GSIs
+// when there is no category.
+func (f ProductFilter) Validate(sort ProductSort) error {
+	if f.MinPrice != nil {
+		if err := f.MinPrice.Validate(); err != nil {
+			return fmt.Errorf("%w: min_price: %w", ErrInvalidProductQuery, err)
+		}
+	}
+	if f.MaxPrice != nil {
+		if err := f.MaxPrice.Validate(); err != nil {
+			return fmt.Errorf("%w: max_price: %w", ErrInvalidProductQuery, err)
+		}
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil {
+		if f.MinPrice.Currency != f.MaxPrice.Currency {
+			return fmt.Errorf("%w: min_price and max_price must have the same currency", ErrInvalidProductQuery)
+		}
+		if f.MinPrice.Amount > f.MaxPrice.Amount {
+			return fmt.Errorf("%w: min_price must not be greater than max_price", ErrInvalidProductQuery)
+		}
+	}
+
+	if f.Status != "" && !f.Status.IsValid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidProductQuery, f.Status)
+	}
+
+	if !sort.IsSet() {
+		return nil
+	}
+
+	switch sort.Field {
+	case ProductSortPrice, ProductSortName, ProductSortCreatedAt, ProductSortUpdatedAt:
+	default:
+		return fmt.Errorf("%w: unsupported sort field %q", ErrInvalidProductQuery, sort.Field)
+	}
+
+	// the sort key of the index can only be used in the key condition,
+	// which does not support substring matching
+	if sort.Field == ProductSortName && f.Name != "" {
+		return fmt.Errorf("%w: name filter can not be combined with sorting by name", ErrInvalidProductQuery)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidStatusTransition = errors.New("invalid product status transition")
+	ErrProductStatusChanged    = errors.New("product status changed concurrently")
+)
+
+type ProductStatus string
+
+const (
+	ProductDraft     ProductStatus = "draft"
+	ProductScheduled ProductStatus = "scheduled" // published once PublishAt is reached
+	ProductPublished ProductStatus = "published"
+	ProductArchived  ProductStatus = "archived"
+)
+
+// _statusTransitions lists the statuses a product can move to from each status
+var _statusTransitions = map[ProductStatus][]ProductStatus{
+	ProductDraft:     {ProductScheduled, ProductPublished, ProductArchived},
+	ProductScheduled: {ProductDraft, ProductPublished, ProductArchived},
+	ProductPublished: {ProductDraft, ProductArchived},
+	ProductArchived:  {ProductDraft, ProductPublished},
+}
+
+func (s ProductStatus) IsValid() bool {
+	_, ok := _statusTransitions[s]
+	return ok
+}
+
+func (s ProductStatus) CanTransitionTo(to ProductStatus) bool {
+	for _, allowed := range _statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Product) IsPublished() bool {
+	return p.Status == ProductPublished
+}
+
+// WasPublished reports whether the product was ever published, only then the
+// consumers of the product events know about it
+func (p *Product) WasPublished() bool {
+	return p.PublishedAt != nil
+}
+
+// InitStatus sets the status of a new product, published when none is given
+func (p *Product) InitStatus(now time.Time) error {
+	if p.Status == "" {
+		p.Status = ProductPublished
+	}
+
+	switch p.Status {
+	case ProductDraft:
+		p.PublishAt = nil
+	case ProductPublished:
+		p.PublishAt = nil
+		p.PublishedAt = &now
+	case ProductScheduled:
+		if p.PublishAt == nil || !p.PublishAt.After(now) {
+			return fmt.Errorf("%w: publish_at must be in the future", ErrInvalidStatusTransition)
+		}
+	default:
+		return fmt.Errorf("%w: a new product can not be %s", ErrInvalidStatusTransition, p.Status)
+	}
+
+	return nil
+}
+
+// TransitionTo moves the product to the status, publishAt is required when scheduling
+func (p *Product) TransitionTo(status ProductStatus, publishAt *time.Time, now time.Time) error {
+	if !status.IsValid() {
+		return fmt.Errorf("%w: unknown status %s", ErrInvalidStatusTransition, status)
+	}
+	if !p.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: from %s to %s", ErrInvalidStatusTransition, p.Status, status)
+	}
+
+	p.PublishAt = nil
+	switch status {
+	case ProductScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return fmt.Errorf("%w: publish_at must be in the future", ErrInvalidStatusTransition)
+		}
+		p.PublishAt = publishAt
+	case ProductPublished:
+		if p.PublishedAt == nil {
+			p.PublishedAt = &now
+		}
+	}
+
+	p.Status = status
+	p.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,244 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPromotion  = errors.New("invalid promotion")
+	ErrPromotionNotFound = errors.New("promotion not found")
+)
+
+type DiscountType string
+
+const (
+	DiscountPercentage DiscountType = "percentage"
+	DiscountFixed      DiscountType = "fixed"
+)
+
+type PromotionTargetType string
+
+const (
+	PromotionTargetProduct  PromotionTargetType = "product"
+	PromotionTargetCategory PromotionTargetType = "category" // the category and all its descendants
+	PromotionTargetSKU      PromotionTargetType = "sku"
+)
+
+// PromotionPhase is where a promotion stands in time
+type PromotionPhase string
+
+const (
+	PromotionUpcoming PromotionPhase = "upcoming"
+	PromotionRunning  PromotionPhase = "running"
+	PromotionEnded    PromotionPhase = "ended"
+)
+
+type PromotionTarget struct {
+	Type PromotionTargetType
+	ID   string // product id, category id or sku
+}
+
+// Promotion is a discount valid from StartsAt until EndsAt.
+// When several promotions match a product the highest priority applies first. A
+// promotion that is not stackable applies alone, stackable ones are combined.
+type Promotion struct {
+	ID           string
+	Name         string
+	DiscountType DiscountType
+	Value        float64 // percent off, for percentage
+	Amount       Money   // amount off, for fixed. It only applies to products priced in its currency
+	Targets      []PromotionTarget
+	Priority     int
+	Stackable    bool
+	StartsAt     time.Time
+	EndsAt       time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    *time.Time
+
+	// Announced is the phase the sale prices of the targeted products were last sent for,
+	// a promotion starting or ending is announced once by the scheduler
+	Announced PromotionPhase
+}
+
+// AppliedPromotion is a promotion that lowered the price of a product, Discount is what it took off
+type AppliedPromotion struct {
+	PromotionID  string
+	Name         string
+	DiscountType DiscountType
+	Value        float64
+	Amount       Money
+	Discount     Money
+}
+
+func (p *Promotion) GeneratePromotionID() error {
+	promotionID, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	p.ID = promotionID.String()
+	return nil
+}
+
+func (p *Promotion) Validate() error {
+	switch p.DiscountType {
+	case DiscountPercentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return fmt.Errorf("%w: percentage must be greater than 0 and at most 100", ErrInvalidPromotion)
+		}
+	case DiscountFixed:
+		if err := p.Amount.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPromotion, err)
+		}
+		if p.Amount.Amount == 0 {
+			return fmt.Errorf("%w: fixed amount must be greater than 0", ErrInvalidPromotion)
+		}
+	default:
+		return fmt.Errorf("%w: unknown discount type %s", ErrInvalidPromotion, p.DiscountType)
+	}
+
+	if !p.EndsAt.After(p.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromotion)
+	}
+
+	if len(p.Targets) == 0 {
+		return fmt.Errorf("%w: at least one target is required", ErrInvalidPromotion)
+	}
+	for _, target := range p.Targets {
+		switch target.Type {
+		case PromotionTargetProduct, PromotionTargetCategory, PromotionTargetSKU:
+		default:
+			return fmt.Errorf("%w: unknown target type %s", ErrInvalidPromotion, target.Type)
+		}
+		if target.ID == "" {
+			return fmt.Errorf("%w: target id is required", ErrInvalidPromotion)
+		}
+	}
+
+	return nil
+}
+
+func (p *Promotion) ActiveAt(t time.Time) bool {
+	return p.DeletedAt == nil && !t.Before(p.StartsAt) && t.Before(p.EndsAt)
+}
+
+func (p *Promotion) Phase(t time.Time) PromotionPhase {
+	switch {
+	case t.Before(p.StartsAt):
+		return PromotionUpcoming
+	case t.Before(p.EndsAt):
+		return PromotionRunning
+	default:
+		return PromotionEnded
+	}
+}
+
+// TargetsCategory reports whether any target is a category, only then the category tree is needed
+func (p *Promotion) TargetsCategory() bool {
+	for _, target := range p.Targets {
+		if target.Type == PromotionTargetCategory {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the promotion targets the product, categoryIDs are the
+// category of the product followed by its ancestors. A sku target matches the sku of
+// the product or of one of its variants.
+func (p *Promotion) Matches(product *Product, categoryIDs, variantSKUs []string) bool {
+	for _, target := range p.Targets {
+		switch target.Type {
+		case PromotionTargetProduct:
+			if target.ID == product.ID {
+				return true
+			}
+		case PromotionTargetSKU:
+			if target.ID == product.SKU {
+				return true
+			}
+			for _, sku := range variantSKUs {
+				if target.ID == sku {
+					return true
+				}
+			}
+		case PromotionTargetCategory:
+			for _, categoryID := range categoryIDs {
+				if target.ID == categoryID {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// appliesTo reports whether the discount can be taken off a price in the currency
+func (p *Promotion) appliesTo(currency string) bool {
+	return p.DiscountType != DiscountFixed || p.Amount.Currency == currency
+}
+
+// discount returns the minor units taken off the price, never more than the price.
+// A percentage is rounded half away from zero to the minor unit.
+func (p *Promotion) discount(price Money) int64 {
+	amount := p.Amount.Amount
+	if p.DiscountType == DiscountPercentage {
+		amount = int64(math.Round(float64(price.Amount) * p.Value / 100))
+	}
+	return min(amount, price.Amount)
+}
+
+// ApplyPromotions sets the sale price and the applied promotions of the product.
+// The matching promotions are ordered by priority, a bigger discount and then the id
+// break ties. The first one always applies; if it is not stackable it applies alone,
+// otherwise every following stackable promotion is applied on the discounted price.
+// Promotions apply to the base price only, the prices in other currencies are left as they are.
+func (p *Product) ApplyPromotions(promotions []Promotion, categoryIDs, variantSKUs []string, now time.Time) {
+	p.SalePrice = p.Price
+	p.AppliedPromotions = []AppliedPromotion{}
+
+	var matching []Promotion
+	for i := range promotions {
+		if promotions[i].ActiveAt(now) && promotions[i].appliesTo(p.Price.Currency) && promotions[i].Matches(p, categoryIDs, variantSKUs) {
+			matching = append(matching, promotions[i])
+		}
+	}
+	if len(matching) == 0 {
+		return
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		if matching[i].Priority != matching[j].Priority {
+			return matching[i].Priority > matching[j].Priority
+		}
+		di, dj := matching[i].discount(p.Price), matching[j].discount(p.Price)
+		if di != dj {
+			return di > dj
+		}
+		return matching[i].ID < matching[j].ID
+	})
+
+	for i, promotion := range matching {
+		if i > 0 && (!matching[0].Stackable || !promotion.Stackable) {
+			continue
+		}
+
+		discount := promotion.discount(p.SalePrice)
+		p.SalePrice.Amount -= discount
+		p.AppliedPromotions = append(p.AppliedPromotions, AppliedPromotion{
+			PromotionID:  promotion.ID,
+			Name:         promotion.Name,
+			DiscountType: promotion.DiscountType,
+			Value:        promotion.Value,
+			Amount:       promotion.Amount,
+			Discount:     Money{Amount: discount, Currency: p.Price.Currency},
+		})
+	}
+}
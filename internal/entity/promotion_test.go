@@ -0,0 +1,207 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyPromotions(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := now.Add(-time.Minute)
+
+	// promotion is active at now and targets the test product
+	promotion := func(id string, priority int, stackable bool) Promotion {
+		return Promotion{
+			ID:        id,
+			Priority:  priority,
+			Stackable: stackable,
+			Targets:   []PromotionTarget{{Type: PromotionTargetProduct, ID: "product"}},
+			StartsAt:  now.Add(-time.Hour),
+			EndsAt:    now.Add(time.Hour),
+		}
+	}
+	percentage := func(p Promotion, value float64) Promotion {
+		p.DiscountType = DiscountPercentage
+		p.Value = value
+		return p
+	}
+	fixed := func(p Promotion, amount int64, currency string) Promotion {
+		p.DiscountType = DiscountFixed
+		p.Amount = Money{Amount: amount, Currency: currency}
+		return p
+	}
+	targets := func(p Promotion, targets ...PromotionTarget) Promotion {
+		p.Targets = targets
+		return p
+	}
+
+	type applied struct {
+		id       string
+		discount int64
+	}
+	tests := []struct {
+		name        string
+		price       int64
+		promotions  []Promotion
+		wantSale    int64
+		wantApplied []applied
+	}{
+		{
+			name:     "no promotions",
+			price:    10000,
+			wantSale: 10000,
+		},
+		{
+			name:  "higher priority applies first",
+			price: 10000,
+			promotions: []Promotion{
+				percentage(promotion("low", 1, true), 10),
+				percentage(promotion("high", 5, true), 20),
+			},
+			wantSale:    7200,
+			wantApplied: []applied{{"high", 2000}, {"low", 800}},
+		},
+		{
+			name:  "a non-stackable winner applies alone",
+			price: 10000,
+			promotions: []Promotion{
+				percentage(promotion("stackable", 1, true), 50),
+				fixed(promotion("alone", 5, false), 500, "USD"),
+			},
+			wantSale:    9500,
+			wantApplied: []applied{{"alone", 500}},
+		},
+		{
+			name:  "a non-stackable promotion after the winner is skipped",
+			price: 10000,
+			promotions: []Promotion{
+				percentage(promotion("first", 5, true), 10),
+				percentage(promotion("alone", 1, false), 50),
+				fixed(promotion("last", 0, true), 100, "USD"),
+			},
+			wantSale:    8900,
+			wantApplied: []applied{{"first", 1000}, {"last", 100}},
+		},
+		{
+			name:  "the bigger discount breaks a priority tie",
+			price: 10000,
+			promotions: []Promotion{
+				percentage(promotion("a", 1, false), 10),
+				fixed(promotion("b", 1, false), 1500, "USD"),
+			},
+			wantSale:    8500,
+			wantApplied: []applied{{"b", 1500}},
+		},
+		{
+			name:  "the id breaks a tie of priority and discount",
+			price: 10000,
+			promotions: []Promotion{
+				percentage(promotion("b", 1, false), 10),
+				fixed(promotion("a", 1, false), 1000, "USD"),
+			},
+			wantSale:    9000,
+			wantApplied: []applied{{"a", 1000}},
+		},
+		{
+			name:  "a fixed discount in another currency is ignored",
+			price: 10000,
+			promotions: []Promotion{
+				fixed(promotion("euro", 9, false), 500, "EUR"),
+				percentage(promotion("percent", 1, false), 10),
+			},
+			wantSale:    9000,
+			wantApplied: []applied{{"percent", 1000}},
+		},
+		{
+			name:  "a discount is capped at the price",
+			price: 10000,
+			promotions: []Promotion{
+				fixed(promotion("big", 1, true), 20000, "USD"),
+				fixed(promotion("more", 0, true), 100, "USD"),
+			},
+			wantSale:    0,
+			wantApplied: []applied{{"big", 10000}, {"more", 0}},
+		},
+		{
+			name:        "a percentage is rounded to the minor unit",
+			price:       999,
+			promotions:  []Promotion{percentage(promotion("p", 1, false), 12.5)},
+			wantSale:    874,
+			wantApplied: []applied{{"p", 125}},
+		},
+		{
+			name:        "a half minor unit is rounded away from zero",
+			price:       10,
+			promotions:  []Promotion{percentage(promotion("p", 1, false), 15)},
+			wantSale:    8,
+			wantApplied: []applied{{"p", 2}},
+		},
+		{
+			name:  "promotions that are not running are ignored",
+			price: 10000,
+			promotions: func() []Promotion {
+				ended := percentage(promotion("ended", 1, true), 10)
+				ended.EndsAt = now
+				upcoming := percentage(promotion("upcoming", 1, true), 10)
+				upcoming.StartsAt = now.Add(time.Second)
+				deleted := percentage(promotion("deleted", 1, true), 10)
+				deleted.DeletedAt = &deletedAt
+				return []Promotion{ended, upcoming, deleted}
+			}(),
+			wantSale: 10000,
+		},
+		{
+			name:  "a sku target matches the product or a variant",
+			price: 10000,
+			promotions: []Promotion{
+				targets(fixed(promotion("product-sku", 1, true), 100, "USD"), PromotionTarget{Type: PromotionTargetSKU, ID: "SKU-1"}),
+				targets(fixed(promotion("variant-sku", 0, true), 200, "USD"), PromotionTarget{Type: PromotionTargetSKU, ID: "SKU-1-RED"}),
+				targets(fixed(promotion("other-sku", 0, true), 300, "USD"), PromotionTarget{Type: PromotionTargetSKU, ID: "SKU-2"}),
+			},
+			wantSale:    9700,
+			wantApplied: []applied{{"product-sku", 100}, {"variant-sku", 200}},
+		},
+		{
+			name:  "a category target matches the descendants",
+			price: 10000,
+			promotions: []Promotion{
+				targets(percentage(promotion("ancestor", 1, true), 10), PromotionTarget{Type: PromotionTargetCategory, ID: "apparel"}),
+				targets(percentage(promotion("sibling", 2, true), 10), PromotionTarget{Type: PromotionTargetCategory, ID: "hiking"}),
+			},
+			wantSale:    9000,
+			wantApplied: []applied{{"ancestor", 1000}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := Product{
+				ID:         "product",
+				SKU:        "SKU-1",
+				Price:      Money{Amount: tt.price, Currency: "USD"},
+				Prices:     []Money{{Amount: tt.price, Currency: "EUR"}},
+				CategoryID: "running",
+			}
+			categoryIDs := []string{"running", "shoes", "apparel"}
+			variantSKUs := []string{"SKU-1-RED", "SKU-1-BLUE"}
+
+			product.ApplyPromotions(tt.promotions, categoryIDs, variantSKUs, now)
+
+			if product.SalePrice != (Money{Amount: tt.wantSale, Currency: "USD"}) {
+				t.Errorf("sale price = %+v, want %d USD", product.SalePrice, tt.wantSale)
+			}
+			if len(product.AppliedPromotions) != len(tt.wantApplied) {
+				t.Fatalf("applied %+v, want %+v", product.AppliedPromotions, tt.wantApplied)
+			}
+			for i, want := range tt.wantApplied {
+				got := product.AppliedPromotions[i]
+				if got.PromotionID != want.id || got.Discount != (Money{Amount: want.discount, Currency: "USD"}) {
+					t.Errorf("applied[%d] = %s off %+v, want %s off %d", i, got.PromotionID, got.Discount, want.id, want.discount)
+				}
+			}
+			// the price list is not discounted
+			if product.Prices[0] != (Money{Amount: tt.price, Currency: "EUR"}) {
+				t.Errorf("price list changed to %+v", product.Prices)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidReservation  = errors.New("invalid reservation")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationExists   = errors.New("reservation already exists")
+	ErrReservationClosed   = errors.New("reservation is not open")
+	ErrInsufficientStock   = errors.New("insufficient stock")
+)
+
+// MaxReservationLines keeps a reservation in one dynamodb transaction of at most 100 items,
+// the reservation itself is one of them and every line writes its product or variant and its
+// ledger entry
+const MaxReservationLines = 49
+
+type ReservationStatus string
+
+const (
+	ReservationReserved  ReservationStatus = "reserved"  // the stock is held for the order
+	ReservationCommitted ReservationStatus = "committed" // the order is paid, the stock is sold
+	ReservationReleased  ReservationStatus = "released"  // the order is cancelled, the stock is back
+	ReservationExpired   ReservationStatus = "expired"   // the order was not paid in time, the stock is back
+)
+
+// OrderItem is a line of an order asking for stock
+type OrderItem struct {
+	ProductID string
+	VariantID string // empty when the stock of the product itself is ordered
+	Quantity  int
+}
+
+// ReservationLine is the stock held on one product, or on one of its variants when VariantID
+// is set. The items of a bundle are held on its components.
+type ReservationLine struct {
+	ProductID  string
+	VariantID  string
+	CategoryID string
+	Quantity   int
+}
+
+// Reservation holds stock for an order until it is paid, cancelled or expires. While it
+// is open its quantities are taken out of the product quantity and counted as reserved.
+type Reservation struct {
+	OrderID   string
+	Items     []OrderItem
+	Lines     []ReservationLine
+	Status    ReservationStatus
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (r *Reservation) IsOpen() bool {
+	return r.Status == ReservationReserved
+}
+
+func ValidateOrderItems(items []OrderItem) error {
+	if len(items) == 0 {
+		return fmt.Errorf("%w: an order needs at least one item", ErrInvalidReservation)
+	}
+	for _, item := range items {
+		if item.ProductID == "" || item.Quantity <= 0 {
+			return fmt.Errorf("%w: every item needs a product and a quantity greater than 0", ErrInvalidReservation)
+		}
+	}
+	return nil
+}
+
+// AddLine holds the quantity on the product or on its variant, lines of the same product
+// and variant are merged
+func (r *Reservation) AddLine(productID, variantID, categoryID string, quantity int) {
+	for i := range r.Lines {
+		if r.Lines[i].ProductID == productID && r.Lines[i].VariantID == variantID {
+			r.Lines[i].Quantity += quantity
+			return
+		}
+	}
+	r.Lines = append(r.Lines, ReservationLine{ProductID: productID, VariantID: variantID, CategoryID: categoryID, Quantity: quantity})
+}
+
+// StockShortageError names the products and the variants that have less stock than the
+// order asks for
+type StockShortageError struct {
+	ProductIDs []string
+	VariantIDs []string
+}
+
+// Add names the line that is short of stock
+func (e *StockShortageError) Add(line ReservationLine) {
+	if line.VariantID != "" {
+		e.VariantIDs = append(e.VariantIDs, line.VariantID)
+		return
+	}
+	e.ProductIDs = append(e.ProductIDs, line.ProductID)
+}
+
+func (e *StockShortageError) Empty() bool {
+	return len(e.ProductIDs) == 0 && len(e.VariantIDs) == 0
+}
+
+func (e *StockShortageError) Error() string {
+	if len(e.VariantIDs) == 0 {
+		return fmt.Sprintf("%s, products: %s", ErrInsufficientStock, strings.Join(e.ProductIDs, ", "))
+	}
+	return fmt.Sprintf("%s, products: %s, variants: %s", ErrInsufficientStock, strings.Join(e.ProductIDs, ", "), strings.Join(e.VariantIDs, ", "))
+}
+
+func (e *StockShortageError) Unwrap() error {
+	return ErrInsufficientStock
+}
@@ -0,0 +1,110 @@
+package entity
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrSlugNotFound = errors.New("slug not found")
+	ErrSlugTaken    = errors.New("slug is taken")
+)
+
+// MaxSlugLength keeps the generated slugs readable in urls, the collision suffix comes on top
+const MaxSlugLength = 80
+
+type SlugKind string
+
+const (
+	SlugProduct  SlugKind = "product"
+	SlugCategory SlugKind = "category"
+)
+
+// Slug reserves a slug of a kind for one product or category. A slug stays reserved
+// after the target got a new one, so old urls still lead to the target.
+type Slug struct {
+	Kind      SlugKind
+	Slug      string
+	TargetID  string
+	CreatedAt time.Time
+}
+
+// _transliterations covers the letters that do not decompose into a latin letter and a mark
+var _transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "e", 'є': "ye",
+	'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh",
+	'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i",
+	'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Slugify turns a name into lowercase ascii words joined by hyphens, "Crème Brûlée Set"
+// becomes "creme-brulee-set". Letters without a transliteration are dropped, so the
+// result is empty for a name in an unsupported script.
+func Slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	// the decomposition splits é into e and a combining accent, the accent is skipped
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		case _transliterations[r] != "":
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteString(_transliterations[r])
+		default:
+			hyphen = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > MaxSlugLength {
+		slug = slug[:MaxSlugLength]
+		// cut at a word boundary when there is one
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	return slug
+}
+
+// SlugCandidate is the n-th slug tried for a base, the first is the base itself
+// and the later ones get the number as suffix, like blue-shirt-2
+func SlugCandidate(base string, n int) string {
+	if n <= 1 {
+		return base
+	}
+	return base + "-" + strconv.Itoa(n)
+}
+
+// SlugHasBase reports whether the slug is the base or the base with a collision suffix,
+// a rename that keeps the base keeps the slug
+func SlugHasBase(slug, base string) bool {
+	if slug == base {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(slug, base+"-")
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(suffix)
+	return err == nil && n > 1
+}
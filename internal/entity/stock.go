@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDuplicateStockEvent  = errors.New("stock event already applied")
+	ErrStockConflict        = errors.New("stock changed concurrently")
+	ErrInvalidStockMovement = errors.New("invalid stock movement")
+)
+
+type StockMovementReason string
+
+const (
+	StockReasonAdjustment StockMovementReason = "adjustment" // the stock on hand reported by the inventory
+	StockReasonReserved   StockMovementReason = "reserved"
+	StockReasonReleased   StockMovementReason = "released"
+	StockReasonExpired    StockMovementReason = "expired"
+)
+
+// Valid reports whether the reason is one of the reasons above
+func (r StockMovementReason) Valid() bool {
+	switch r {
+	case StockReasonAdjustment, StockReasonReserved, StockReasonReleased, StockReasonExpired:
+		return true
+	}
+	return false
+}
+
+// StockMovement is an entry of the inventory ledger, one change of the available quantity
+// of a product, or of one of its variants when VariantID is set. Entries are never changed
+// or removed, an event is applied only once.
+type StockMovement struct {
+	EventID   string // unique per product
+	ProductID string
+	VariantID string
+	Delta     int // the change of the available quantity
+	Quantity  int // the available quantity after the change
+	Reason    StockMovementReason
+	Source    string // the topic or the order the change came from
+	CreatedAt time.Time
+}
+
+// StockLevel is how the available quantity of a product stands against its reorder threshold
+type StockLevel string
+
+const (
+	StockLevelOK  StockLevel = "ok"
+	StockLevelLow StockLevel = "low" // at or below the reorder threshold
+	StockLevelOut StockLevel = "out" // nothing available
+)
+
+func (l StockLevel) rank() int {
+	switch l {
+	case StockLevelLow:
+		return 1
+	case StockLevelOut:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// StockAlert is the alert state of a product, the level it was last seen at and the
+// last alert sent for it
+type StockAlert struct {
+	Level     StockLevel // ok when empty
+	SentLevel StockLevel
+	SentAt    *time.Time
+}
+
+// StockAlertPolicy decides when a change of stock is alerted. Products without their own
+// reorder threshold use the default one. An alert is sent when the level gets worse, and
+// the same alert is not sent again within the cooldown, so stock hovering around the
+// threshold does not alert on every change.
+type StockAlertPolicy struct {
+	DefaultThreshold int
+	Cooldown         time.Duration
+}
+
+func (p StockAlertPolicy) Threshold(product *Product) int {
+	if product.ReorderThreshold != nil {
+		return *product.ReorderThreshold
+	}
+	return p.DefaultThreshold
+}
+
+func (p StockAlertPolicy) Level(product *Product) StockLevel {
+	switch {
+	case product.Quantity <= 0:
+		return StockLevelOut
+	case product.Quantity <= p.Threshold(product):
+		return StockLevelLow
+	default:
+		return StockLevelOK
+	}
+}
+
+// Next returns the alert state of the product after its quantity changed and whether
+// an alert for the new level is due
+func (p StockAlertPolicy) Next(product *Product, now time.Time) (StockAlert, bool) {
+	current := product.StockAlert
+	if current.Level == "" {
+		current.Level = StockLevelOK
+	}
+
+	next := current
+	next.Level = p.Level(product)
+	if next.Level.rank() <= current.Level.rank() {
+		return next, false
+	}
+	if current.SentLevel == next.Level && current.SentAt != nil && now.Sub(*current.SentAt) < p.Cooldown {
+		return next, false
+	}
+
+	next.SentLevel = next.Level
+	next.SentAt = &now
+	return next, true
+}
+
+// LowStockProduct is a product of the low stock report
+type LowStockProduct struct {
+	Product   Product
+	Threshold int // the reorder threshold of the product, or the default one
+	Level     StockLevel
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "errors"
+
+// ErrParentDeleted is returned when restoring an item whose category is still deleted
+var ErrParentDeleted = errors.New("parent is deleted")
+
+// TrashPurgeReport is the outcome of one purge run, the items were in the
+// trash for longer than the retention period
+type TrashPurgeReport struct {
+	Products   int
+	Categories int
+	Images     int
+}
@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-product/internal/utils"
+)
+
+var ErrVariantNotFound = errors.New("variant not found")
+
+// Variant is a sellable option of a product, like a size or a color of a shirt
+type Variant struct {
+	ID        string
+	ProductID string
+	SKU       string
+	Options   map[string]string
+	Price     *Money // overrides the product price when set, in the currency of the product
+	Quantity  int    // available, the stock on hand less what is reserved
+	Reserved  int    // held for open orders
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+}
+
+func (v *Variant) GenerateVariantID() error {
+	variantID, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	v.ID = variantID.String()
+	return nil
+}
+
+func (v *Variant) GenerateSKU() {
+	v.SKU = utils.GenerateSKU()
+}
+
+// EffectivePrice returns the price override, or the product price when there is none
+func (v *Variant) EffectivePrice(productPrice Money) Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return productPrice
+}
+
+// ValidatePrice checks the price override, it has to be in the currency of the product
+func (v *Variant) ValidatePrice(product *Product) error {
+	if v.Price == nil {
+		return nil
+	}
+	if err := v.Price.Validate(); err != nil {
+		return err
+	}
+	if v.Price.Amount == 0 {
+		return fmt.Errorf("%w: price must be greater than 0", ErrInvalidMoney)
+	}
+	if v.Price.Currency != product.Price.Currency {
+		return fmt.Errorf("%w: variant price is in %s, the product in %s", ErrCurrencyMismatch, v.Price.Currency, product.Price.Currency)
+	}
+	return nil
+}
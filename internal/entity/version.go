@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrConcurrentUpdate is returned when a write without an expected version lost the race
+	// against another write, the item changed after it was read
+	ErrConcurrentUpdate = errors.New("updated concurrently, retry the update")
+)
+
+// VersionMismatchError is returned when a write expects a version the item no longer has,
+// Current is the version it has now
+type VersionMismatchError struct {
+	Current int64
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("%s, current version: %d", ErrVersionMismatch, e.Current)
+}
+
+func (e *VersionMismatchError) Unwrap() error {
+	return ErrVersionMismatch
+}
@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/pkg/kafka"
+)
+
+const bundleStockUpdatedTopic = "bundle-stock-updated"
+
+// kafkaBundleStockUpdatedMessage carries the stock of a bundle derived again after the
+// quantity of one of its components changed
+type kafkaBundleStockUpdatedMessage struct {
+	ProductID        string    `json:"product_id"`
+	Quantity         int       `json:"quantity"`
+	PreviousQuantity int       `json:"previous_quantity"`
+	ComponentID      string    `json:"component_id"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// loadBundleComponents reads the component products of a bundle, a deleted component is
+// left out so it counts as out of stock
+func loadBundleComponents(ctx context.Context, productRepo ProductDynamoRepo, bundle *entity.Bundle) (map[string]entity.Product, error) {
+	components := make(map[string]entity.Product, len(bundle.Components))
+	for _, component := range bundle.Components {
+		product, err := productRepo.GetProductByID(ctx, component.ProductID)
+		if errors.Is(err, entity.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		components[product.ID] = *product
+	}
+	return components, nil
+}
+
+// prepareBundle checks the bundle of the product and derives its stock from the components,
+// and its price too for discount pricing. Bundles can not be nested.
+func prepareBundle(ctx context.Context, productRepo ProductDynamoRepo, product *entity.Product) error {
+	if product.Bundle == nil {
+		return fmt.Errorf("%w: a bundle needs components", entity.ErrInvalidBundle)
+	}
+	err := product.Bundle.Validate(product.ID)
+	if err != nil {
+		return err
+	}
+
+	components, err := loadBundleComponents(ctx, productRepo, product.Bundle)
+	if err != nil {
+		return err
+	}
+	for _, component := range product.Bundle.Components {
+		c, ok := components[component.ProductID]
+		if !ok {
+			return fmt.Errorf("%w: component %w, id: %s", entity.ErrInvalidBundle, entity.ErrProductNotFound, component.ProductID)
+		}
+		if c.IsBundle() {
+			return fmt.Errorf("%w: component %s is a bundle itself", entity.ErrInvalidBundle, c.ID)
+		}
+	}
+
+	if product.Bundle.Pricing == entity.BundlePriceDiscount {
+		product.Price, err = product.Bundle.Price(components, product.Price.Currency)
+		if err != nil {
+			return err
+		}
+	}
+	product.Quantity = product.Bundle.Availability(components)
+	return nil
+}
+
+// bundleComponentIDs returns the ids of the components
+func bundleComponentIDs(bundle *entity.Bundle) []string {
+	if bundle == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(bundle.Components))
+	for _, component := range bundle.Components {
+		ids = append(ids, component.ProductID)
+	}
+	return ids
+}
+
+// SetBundle replaces the components and the pricing of a bundle, its stock and for discount
+// pricing its price are derived again
+func (u *ProductUseCase) SetBundle(ctx context.Context, productID string, bundle entity.Bundle, actor string) (*entity.Product, error) {
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set bundle: %w", err)
+	}
+	if !product.IsBundle() {
+		return nil, fmt.Errorf("%w: product %s is not a bundle", entity.ErrInvalidBundle, productID)
+	}
+
+	previous := product.Bundle
+	previousPrice := product.Price
+	product.Bundle = &bundle
+	err = prepareBundle(ctx, u.productRepoDynamo, product)
+	if err != nil {
+		return nil, err
+	}
+	err = product.ValidatePrices()
+	if err != nil {
+		return nil, err
+	}
+
+	product.UpdatedAt = time.Now()
+	product.UpdatedBy = actor
+	err = u.productRepoDynamo.UpdateBundle(ctx, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set bundle: %w", err)
+	}
+
+	err = u.bundleRepoDynamo.SaveComponents(ctx, product.ID, bundle.Components)
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[string]bool, len(bundle.Components))
+	for _, id := range bundleComponentIDs(&bundle) {
+		kept[id] = true
+	}
+	var removed []string
+	for _, id := range bundleComponentIDs(previous) {
+		if !kept[id] {
+			removed = append(removed, id)
+		}
+	}
+	err = u.bundleRepoDynamo.DeleteComponents(ctx, product.ID, removed)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.Price != previousPrice {
+		err = recordPrice(ctx, u.priceRepoDynamo, product, previousPrice, entity.PriceSourceManual, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to record product price: %w", err)
+		}
+	}
+
+	err = indexProduct(ctx, u.productRepoSearch, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index product: %w", err)
+	}
+
+	err = produceProductUpdated(ctx, u.producer, u.variantRepoDynamo, u.promotions, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return product, nil
+}
+
+// refreshBundles derives the stock of the bundles the product is a component of again,
+// and the price of the discount bundles. A changed stock is stored and sent to
+// bundle-stock-updated, a changed price is recorded in the price history and sent to
+// product-updated.
+func refreshBundles(
+	ctx context.Context,
+	bundleRepo BundleDynamoRepo,
+	productRepo ProductDynamoRepo,
+	searchRepo ProductSearchRepo,
+	priceRepo PriceDynamoRepo,
+	variantRepo VariantDynamoRepo,
+	promotions *PromotionEvaluator,
+	producer *kafka.ProducerServer,
+	componentID string,
+) error {
+	bundleIDs, err := bundleRepo.GetBundleIDs(ctx, componentID)
+	if err != nil {
+		return err
+	}
+
+	for _, bundleID := range bundleIDs {
+		bundle, err := productRepo.GetProductByID(ctx, bundleID)
+		// the entries of a deleted bundle stay behind, they are skipped
+		if errors.Is(err, entity.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if bundle.Bundle == nil {
+			continue
+		}
+
+		components, err := loadBundleComponents(ctx, productRepo, bundle.Bundle)
+		if err != nil {
+			return err
+		}
+
+		previousQuantity := bundle.Quantity
+		previousPrice := bundle.Price
+		bundle.Quantity = bundle.Bundle.Availability(components)
+		if bundle.Bundle.Pricing == entity.BundlePriceDiscount {
+			price, err := bundle.Bundle.Price(components, bundle.Price.Currency)
+			// a deleted component or one priced in another currency keeps the last price,
+			// the bundle is out of stock or has to be set again
+			if err != nil && !errors.Is(err, entity.ErrInvalidBundle) && !errors.Is(err, entity.ErrCurrencyMismatch) {
+				return err
+			}
+			if err == nil {
+				bundle.Price = price
+			}
+		}
+
+		if bundle.Price == previousPrice && bundle.Quantity == previousQuantity {
+			continue
+		}
+
+		if bundle.Price != previousPrice {
+			bundle.UpdatedAt = time.Now()
+			err = productRepo.UpdateBundle(ctx, bundle)
+			if err != nil {
+				return err
+			}
+
+			// the component changed the price, not whoever last edited the bundle
+			entry, err := entity.NewPriceHistoryEntry(bundle, previousPrice, "", entity.PriceSourceBundle)
+			if err != nil {
+				return err
+			}
+			err = priceRepo.AppendHistory(ctx, &entry)
+			if err != nil {
+				return fmt.Errorf("failed to record product price: %w", err)
+			}
+
+			err = indexProduct(ctx, searchRepo, bundle)
+			if err != nil {
+				return fmt.Errorf("failed to index product: %w", err)
+			}
+
+			err = produceProductUpdated(ctx, producer, variantRepo, promotions, bundle)
+			if err != nil {
+				return fmt.Errorf("failed to produce kafka message: %w", err)
+			}
+		} else {
+			// a bundle is never reserved itself, its quantity is stored as derived
+			_, err = productRepo.UpdateProductQty(ctx, bundle.ID, bundle.CategoryID, bundle.Quantity)
+			if err != nil {
+				return err
+			}
+			err = searchRepo.UpdateQuantity(ctx, bundle.ID, bundle.Quantity)
+			if err != nil {
+				return err
+			}
+		}
+
+		if bundle.Quantity == previousQuantity {
+			continue
+		}
+		message := kafkaBundleStockUpdatedMessage{
+			ProductID:        bundle.ID,
+			Quantity:         bundle.Quantity,
+			PreviousQuantity: previousQuantity,
+			ComponentID:      componentID,
+			UpdatedAt:        time.Now(),
+		}
+		err = producer.Produce(
+			bundleStockUpdatedTopic,
+			[]byte(bundle.ID),
+			message,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to produce kafka message: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+)
+
+type CatalogUseCase struct {
+	productRepoDynamo ProductDynamoRepo
+}
+
+func NewCatalogUseCase(productRepoDynamo ProductDynamoRepo) *CatalogUseCase {
+	return &CatalogUseCase{
+		productRepoDynamo: productRepoDynamo,
+	}
+}
+
+// BackfillCatalog gives the products saved before the sorted listing across all categories
+// existed the attribute that listing is indexed by, products saved since have it already
+func (u *CatalogUseCase) BackfillCatalog(ctx context.Context) (int, error) {
+	backfilled, err := u.productRepoDynamo.BackfillCatalog(ctx)
+	if err != nil {
+		return backfilled, fmt.Errorf("failed to backfill catalog: %w", err)
+	}
+	return backfilled, nil
+}
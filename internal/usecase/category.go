@@ -2,6 +2,9 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/idoyudha/eshop-product/internal/entity"
 	"github.com/redis/go-redis/v9"
@@ -10,15 +13,21 @@ import (
 type CategoryUseCase struct {
 	categoryRepoDynamo CategoryDynamoRepo
 	categoryRepoRedis  CategoryRedisRepo
+	slugRepoDynamo     SlugDynamoRepo
+	locales            *entity.Locales
 }
 
 func NewCategoryUseCase(
 	categoryRepoRedis CategoryRedisRepo,
 	categoryRepoDynamo CategoryDynamoRepo,
+	slugRepoDynamo SlugDynamoRepo,
+	locales *entity.Locales,
 ) *CategoryUseCase {
 	return &CategoryUseCase{
 		categoryRepoRedis:  categoryRepoRedis,
 		categoryRepoDynamo: categoryRepoDynamo,
+		slugRepoDynamo:     slugRepoDynamo,
+		locales:            locales,
 	}
 }
 
@@ -58,6 +67,26 @@ func (u *CategoryUseCase) GetCategoryByID(ctx context.Context, id string) (*enti
 	return category, nil
 }
 
+// GetCategoryBySlug finds the category by its current slug or by one it had before,
+// the slug of the returned category tells which one it was
+func (u *CategoryUseCase) GetCategoryBySlug(ctx context.Context, slug string) (*entity.Category, error) {
+	category, err := u.categoryRepoDynamo.GetBySlug(ctx, slug)
+	if errors.Is(err, entity.ErrCategoryNotFound) {
+		reserved, slugErr := u.slugRepoDynamo.GetBySlug(ctx, entity.SlugCategory, slug)
+		if errors.Is(slugErr, entity.ErrSlugNotFound) {
+			return nil, err
+		}
+		if slugErr != nil {
+			return nil, slugErr
+		}
+		category, err = u.categoryRepoDynamo.GetByID(ctx, reserved.TargetID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
 func (u *CategoryUseCase) GetCategoriesByParentID(ctx context.Context, id string) (*[]entity.Category, error) {
 	// get from redis
 	categories, err := u.categoryRepoRedis.GetByParentID(ctx, id)
@@ -70,15 +99,26 @@ func (u *CategoryUseCase) GetCategoriesByParentID(ctx context.Context, id string
 }
 
 func (u *CategoryUseCase) CreateCategory(ctx context.Context, category *entity.Category) (*entity.Category, error) {
-	err := category.GenerateCategoryID()
+	err := entity.ValidateAttributeSchema(category.Attributes)
 	if err != nil {
 		return nil, err
 	}
 
+	err = category.GenerateCategoryID()
+	if err != nil {
+		return nil, err
+	}
+
+	slug, err := reserveSlug(ctx, u.slugRepoDynamo, entity.SlugCategory, category.Name, category.ID)
+	if err != nil {
+		return nil, err
+	}
+	category.Slug = slug.slug
+
 	// create new in dynamodb
 	err = u.categoryRepoDynamo.Save(ctx, category)
 	if err != nil {
-		return nil, err
+		return nil, slug.releaseOnError(ctx, u.slugRepoDynamo, err)
 	}
 
 	// set new in redis
@@ -91,14 +131,45 @@ func (u *CategoryUseCase) CreateCategory(ctx context.Context, category *entity.C
 }
 
 func (u *CategoryUseCase) UpdateCategory(ctx context.Context, category *entity.Category) error {
-	// update in dynamodb
-	err := u.categoryRepoDynamo.Update(ctx, category)
+	err := entity.ValidateAttributeSchema(category.Attributes)
 	if err != nil {
 		return err
 	}
 
+	current, err := u.categoryRepoDynamo.GetByID(ctx, category.ID)
+	if err != nil {
+		return err
+	}
+	// a stale version fails before the slug is renamed, the update itself checks it again
+	if category.Version > 0 && category.Version != current.Version {
+		return &entity.VersionMismatchError{Current: current.Version}
+	}
+	// without If-Match the update is written only if no other write came in after the read
+	implicitVersion := category.Version == 0
+	if implicitVersion {
+		category.Version = current.Version
+	}
+
+	// categories created before slugs get one with their next update, a new slug is given
+	// back when the update fails
+	slug, err := renameSlug(ctx, u.slugRepoDynamo, entity.SlugCategory, current.Slug, category.Name, category.ID)
+	if err != nil {
+		return err
+	}
+	category.Slug = slug.slug
+
+	// update in dynamodb
+	err = u.categoryRepoDynamo.Update(ctx, category)
+	if err != nil {
+		var mismatch *entity.VersionMismatchError
+		if implicitVersion && errors.As(err, &mismatch) {
+			err = entity.ErrConcurrentUpdate
+		}
+		return slug.releaseOnError(ctx, u.slugRepoDynamo, err)
+	}
+
 	// update in redis
-	err = u.categoryRepoRedis.Update(ctx, category.ID, category.Name)
+	err = u.categoryRepoRedis.Update(ctx, category)
 	if err != nil {
 		return err
 	}
@@ -120,3 +191,69 @@ func (u *CategoryUseCase) DeleteCategory(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// SetCategoryTranslation stores the name of the category in a locale other than the default one
+func (u *CategoryUseCase) SetCategoryTranslation(
+	ctx context.Context,
+	id string,
+	locale string,
+	translation entity.CategoryTranslation,
+) (*entity.Category, error) {
+	locale, err := u.locales.Validate(locale)
+	if err != nil {
+		return nil, err
+	}
+	err = translation.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := u.categoryRepoDynamo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Translations[locale] = translation
+	err = u.saveCategoryTranslations(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (u *CategoryUseCase) DeleteCategoryTranslation(ctx context.Context, id, locale string) (*entity.Category, error) {
+	locale, err := u.locales.Validate(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := u.categoryRepoDynamo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := category.Translations[locale]; !ok {
+		return nil, fmt.Errorf("%w, category: %s, locale: %s", entity.ErrTranslationNotFound, id, locale)
+	}
+
+	delete(category.Translations, locale)
+	err = u.saveCategoryTranslations(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// saveCategoryTranslations stores the translations in dynamodb and in the cache
+func (u *CategoryUseCase) saveCategoryTranslations(ctx context.Context, category *entity.Category) error {
+	category.UpdatedAt = time.Now()
+
+	err := u.categoryRepoDynamo.UpdateTranslations(ctx, category)
+	if err != nil {
+		return err
+	}
+
+	return u.categoryRepoRedis.Update(ctx, category)
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+)
+
+type ImageGCUseCase struct {
+	productRepoImage  ProductS3Repo
+	productRepoDynamo ProductDynamoRepo
+	gracePeriod       time.Duration
+	uploadTTL         time.Duration
+}
+
+func NewImageGCUseCase(
+	productRepoImage ProductS3Repo,
+	productRepoDynamo ProductDynamoRepo,
+	gracePeriod time.Duration,
+	uploadTTL time.Duration,
+) *ImageGCUseCase {
+	return &ImageGCUseCase{
+		productRepoImage:  productRepoImage,
+		productRepoDynamo: productRepoDynamo,
+		gracePeriod:       gracePeriod,
+		uploadTTL:         uploadTTL,
+	}
+}
+
+// CollectOrphanedImages deletes the objects under product/ that no product references.
+// Objects younger than the grace period are kept, so an image uploaded for a product
+// that is not saved yet survives. The images of a soft-deleted product stay referenced
+// while it is in the trash, the trash purge deletes them. Objects under upload/ that
+// were never confirmed are deleted once they are older than the upload url ttl. On a dry
+// run the orphans and expired uploads are only reported.
+func (u *ImageGCUseCase) CollectOrphanedImages(ctx context.Context, dryRun bool) (*entity.ImageGCReport, error) {
+	report := &entity.ImageGCReport{
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
+	}
+	cutoff := report.StartedAt.Add(-u.gracePeriod)
+
+	// list the bucket before reading the table, an image saved in between is
+	// then either missing from the listing or referenced in the table
+	images, err := u.productRepoImage.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect orphaned images: %w", err)
+	}
+	report.Scanned = len(images)
+
+	products, err := u.productRepoDynamo.GetAllProductImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect orphaned images: %w", err)
+	}
+
+	referenced := make(map[string]struct{})
+	for _, product := range products {
+		for _, url := range productImageURLs(product) {
+			referenced[url] = struct{}{}
+		}
+	}
+
+	for _, image := range images {
+		if _, ok := referenced[image.URL]; ok {
+			report.Referenced++
+			continue
+		}
+		if image.LastModified.After(cutoff) {
+			report.InGracePeriod++
+			continue
+		}
+		report.Orphans = append(report.Orphans, image)
+		report.OrphanBytes += image.Size
+	}
+
+	if !dryRun && len(report.Orphans) > 0 {
+		err = u.productRepoImage.DeleteImages(ctx, report.Orphans)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete orphaned images: %w", err)
+		}
+		report.Deleted = len(report.Orphans)
+	}
+
+	// an upload is written while its url is valid and confirmed right after, so one older
+	// than the ttl is never confirmed any more
+	uploads, err := u.productRepoImage.ListUploads(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect expired uploads: %w", err)
+	}
+	uploadCutoff := report.StartedAt.Add(-u.uploadTTL)
+	for _, upload := range uploads {
+		if upload.LastModified.Before(uploadCutoff) {
+			report.ExpiredUploads = append(report.ExpiredUploads, upload)
+		}
+	}
+
+	if !dryRun && len(report.ExpiredUploads) > 0 {
+		err = u.productRepoImage.DeleteImages(ctx, report.ExpiredUploads)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete expired uploads: %w", err)
+		}
+		report.DeletedUploads = len(report.ExpiredUploads)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// productImageURLs lists every url a product points at, renditions included
+func productImageURLs(product entity.Product) []string {
+	urls := []string{}
+	if product.ImageURL != "" {
+		urls = append(urls, product.ImageURL)
+	}
+	for _, image := range product.Images {
+		urls = append(urls, image.URL)
+		for _, rendition := range image.Renditions {
+			urls = append(urls, rendition.URL)
+		}
+	}
+	return urls
+}
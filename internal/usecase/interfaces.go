@@ -2,34 +2,157 @@ package usecase
 
 import (
 	"context"
+	"io"
 	"mime/multipart"
+	"time"
 
 	"github.com/idoyudha/eshop-product/internal/entity"
 )
 
 type (
 	ProductS3Repo interface {
-		UploadImage(context.Context, *multipart.FileHeader) (string, error)
+		PresignUpload(context.Context, string, int64) (*entity.ImageUpload, error)
+		UploadImage(context.Context, string, *entity.ProductImage, entity.ImageSource) error
+		ListImages(context.Context) ([]entity.StoredImage, error)
+		ListUploads(context.Context) ([]entity.StoredImage, error)
+		DeleteImages(context.Context, []entity.StoredImage) error
+		DeleteImage(context.Context, entity.ProductImage) error
+		SaveImportFile(context.Context, string, io.Reader, int64) error
+		OpenImportFile(context.Context, string) (io.ReadCloser, error)
+		DeleteImportFile(context.Context, string) error
+		UploadExport(context.Context, string, string, func(io.Writer) error) error
 	}
 
 	ProductDynamoRepo interface {
 		Save(context.Context, *entity.Product) error
-		GetProducts(context.Context) (*[]entity.Product, error)
+		SaveBatch(context.Context, []*entity.Product) ([]int, error)
+		Discard(context.Context, string, string) error
+		GetProducts(context.Context, entity.ProductFilter, entity.ProductSort, entity.Pagination) (*[]entity.Product, string, error)
 		GetProductByID(context.Context, string) (*entity.Product, error)
-		GetProductsByCategory(context.Context, string) ([]entity.Product, error)
-		GetProductsByCategories(context.Context, []string) ([]entity.Product, error)
+		GetProductBySlug(context.Context, string) (*entity.Product, error)
+		GetProductsByCategory(context.Context, string, entity.ProductStatus, entity.Pagination) ([]entity.Product, string, error)
+		GetProductsByCategories(context.Context, []string, entity.ProductStatus) ([]entity.Product, error)
+		GetProductsBySKUs(context.Context, []string) ([]entity.Product, error)
+		GetDueScheduledProducts(context.Context, time.Time) ([]entity.Product, error)
+		GetAllProductImages(context.Context) ([]entity.Product, error)
 		Update(context.Context, *entity.Product) error
+		UpdateImages(context.Context, *entity.Product) error
+		UpdateTranslations(context.Context, *entity.Product) error
+		UpdateBundle(context.Context, *entity.Product) error
+		UpdateStatus(context.Context, *entity.Product, entity.ProductStatus) error
 		GetCategoryByProductId(context.Context, string) (*string, error)
-		UpdateProductQty(context.Context, string, string, int) error
+		UpdateProductQty(context.Context, string, string, int) (int, error)
+		UpdateStockAlert(context.Context, *entity.Product, entity.StockAlert) error
+		GetLowStock(context.Context, int, entity.Pagination) ([]entity.Product, string, error)
+		Delete(context.Context, string, string) error
+		GetDeleted(context.Context, entity.Pagination) ([]entity.Product, string, error)
+		GetDeletedBefore(context.Context, time.Time) ([]entity.Product, error)
+		GetDeletedProductByID(context.Context, string) (*entity.Product, error)
+		Restore(context.Context, *entity.Product) error
+		Purge(context.Context, string, string) error
+		HasProducts(context.Context, string) (bool, error)
+		BackfillCatalog(context.Context) (int, error)
+	}
+
+	ProductSearchRepo interface {
+		Index(context.Context, *entity.Product) error
+		UpdateQuantity(context.Context, string, int) error
+		Remove(context.Context, string) error
+		Search(context.Context, string, int) ([]entity.ProductSearchHit, error)
+		Similar(context.Context, string, int) ([]entity.ProductSearchHit, error)
+	}
+
+	VariantDynamoRepo interface {
+		Save(context.Context, *entity.Variant) error
+		GetByProductID(context.Context, string) ([]entity.Variant, error)
+		GetByID(context.Context, string, string) (*entity.Variant, error)
+		Update(context.Context, *entity.Variant) error
 		Delete(context.Context, string, string) error
+		PurgeByProductID(context.Context, string) error
+		GetBySKUs(context.Context, []string) ([]entity.Variant, error)
+	}
+
+	PriceDynamoRepo interface {
+		AppendHistory(context.Context, *entity.PriceHistoryEntry) error
+		ApplyPrice(context.Context, *entity.Product, *entity.PriceHistoryEntry) error
+		GetHistory(context.Context, string, entity.Pagination) ([]entity.PriceHistoryEntry, string, error)
+		GetPriceAt(context.Context, string, time.Time) (*entity.PriceHistoryEntry, error)
+		SaveScheduledChange(context.Context, *entity.ScheduledPriceChange) error
+		GetScheduledChanges(context.Context, string) ([]entity.ScheduledPriceChange, error)
+		GetScheduledChange(context.Context, string, string) (*entity.ScheduledPriceChange, error)
+		GetDueScheduledChanges(context.Context, time.Time) ([]entity.ScheduledPriceChange, error)
+		UpdateScheduledChangeStatus(context.Context, *entity.ScheduledPriceChange, entity.ScheduledPriceStatus) error
+		PurgeByProductID(context.Context, string) error
+	}
+
+	PromotionDynamoRepo interface {
+		Save(context.Context, *entity.Promotion) error
+		GetAll(context.Context) ([]entity.Promotion, error)
+		GetNotEnded(context.Context, time.Time) ([]entity.Promotion, error)
+		GetUnannounced(context.Context) ([]entity.Promotion, error)
+		GetByID(context.Context, string) (*entity.Promotion, error)
+		Update(context.Context, *entity.Promotion) error
+		UpdateAnnounced(context.Context, *entity.Promotion, entity.PromotionPhase) error
+		Delete(context.Context, string) error
+	}
+
+	ImportDynamoRepo interface {
+		SaveJob(context.Context, *entity.ImportJob) error
+		GetJobByID(context.Context, string) (*entity.ImportJob, error)
+		UpdateJob(context.Context, *entity.ImportJob) error
+		GetStaleJobs(context.Context, time.Time) ([]entity.ImportJob, error)
+		FailStaleJob(context.Context, *entity.ImportJob, time.Time) (bool, error)
+		SaveRowErrors(context.Context, []entity.ImportRowError) error
+		GetRowErrors(context.Context, string, entity.Pagination) ([]entity.ImportRowError, string, error)
 	}
 
 	CategoryDynamoRepo interface {
 		Save(context.Context, *entity.Category) error
 		GetAll(context.Context) (*[]entity.Category, error)
+		GetByID(context.Context, string) (*entity.Category, error)
+		GetBySlug(context.Context, string) (*entity.Category, error)
 		GetByParentID(context.Context, string) (*[]entity.Category, error)
 		Update(context.Context, *entity.Category) error
+		UpdateTranslations(context.Context, *entity.Category) error
 		Delete(context.Context, string) error
+		GetDeleted(context.Context) ([]entity.Category, error)
+		GetDeletedByID(context.Context, string) (*entity.Category, error)
+		Restore(context.Context, *entity.Category) error
+		Purge(context.Context, string) error
+	}
+
+	SlugDynamoRepo interface {
+		Reserve(context.Context, *entity.Slug) (bool, error)
+		Release(context.Context, entity.SlugKind, string, string) error
+		GetBySlug(context.Context, entity.SlugKind, string) (*entity.Slug, error)
+		PurgeByTarget(context.Context, entity.SlugKind, string) error
+	}
+
+	BundleDynamoRepo interface {
+		SaveComponents(context.Context, string, []entity.BundleComponent) error
+		DeleteComponents(context.Context, string, []string) error
+		GetBundleIDs(context.Context, string) ([]string, error)
+	}
+
+	StockMovementDynamoRepo interface {
+		Apply(context.Context, *entity.StockMovement, *entity.Product) error
+		ApplyVariant(context.Context, *entity.StockMovement, *entity.Variant) error
+		GetByProductID(context.Context, string, entity.Pagination) ([]entity.StockMovement, string, error)
+	}
+
+	ReservationDynamoRepo interface {
+		Reserve(context.Context, *entity.Reservation, []entity.StockMovement) error
+		Release(context.Context, *entity.Reservation, []entity.StockMovement) error
+		Commit(context.Context, *entity.Reservation) error
+		GetByOrderID(context.Context, string) (*entity.Reservation, error)
+		GetExpired(context.Context, time.Time) ([]entity.Reservation, error)
+	}
+
+	ProductLinkDynamoRepo interface {
+		Save(context.Context, *entity.ProductLink) error
+		Delete(context.Context, string, entity.ProductLinkType, string) error
+		GetByProductID(context.Context, string, entity.ProductLinkType) ([]entity.ProductLink, error)
+		PurgeByProductID(context.Context, string) error
 	}
 
 	CategoryRedisRepo interface {
@@ -38,27 +161,112 @@ type (
 		GetByID(context.Context, string) (*entity.Category, error)
 		GetByParentID(context.Context, string) (*[]entity.Category, error)
 		Add(context.Context, *entity.Category) error
-		Update(context.Context, string, string) error
+		Update(context.Context, *entity.Category) error
 		Delete(context.Context, string) error
 	}
 
 	Product interface {
-		CreateProduct(context.Context, *entity.Product, *multipart.FileHeader) (*entity.Product, error)
-		GetProducts(context.Context) (*[]entity.Product, error)
+		CreateImageUpload(context.Context, string, int64) (*entity.ImageUpload, error)
+		CreateProduct(context.Context, *entity.Product, entity.ImageSource) (*entity.Product, error)
+		GetProducts(context.Context, entity.ProductFilter, entity.ProductSort, entity.Pagination) (*[]entity.Product, string, error)
 		GetProductByID(context.Context, string) (*entity.Product, error)
-		GetProductsByCategory(context.Context, string) ([]entity.Product, error)
+		GetAdminProductByID(context.Context, string) (*entity.Product, error)
+		GetProductBySlug(context.Context, string) (*entity.Product, error)
+		GetProductsByCategory(context.Context, string, entity.Pagination) ([]entity.Product, string, error)
 		GetProductsByCategories(context.Context, []string) ([]entity.Product, error)
-		UpdateProduct(context.Context, *entity.Product, *multipart.FileHeader) error
-		UpdateProductQuantity(context.Context, string, int) error
+		SearchProducts(context.Context, string, int) ([]entity.ProductSearchHit, error)
+		BuildSearchIndex(context.Context) error
+		UpdateProduct(context.Context, *entity.Product, entity.ImageSource) error
+		UpdateProductQuantity(context.Context, string, int, entity.StockMovement) error
+		GetStockMovements(context.Context, string, entity.Pagination) ([]entity.StockMovement, string, error)
+		GetLowStockProducts(context.Context, entity.Pagination) ([]entity.LowStockProduct, string, error)
+		ChangeProductStatus(context.Context, string, entity.ProductStatus, *time.Time, string) (*entity.Product, error)
+		SetProductTranslation(context.Context, string, string, entity.ProductTranslation, string) (*entity.Product, error)
+		DeleteProductTranslation(context.Context, string, string, string) (*entity.Product, error)
+		SetBundle(context.Context, string, entity.Bundle, string) (*entity.Product, error)
+		PublishDueProducts(context.Context) (int, error)
+		AddProductImage(context.Context, string, entity.ImageSource, string, bool) (*entity.Product, error)
+		UpdateProductImage(context.Context, string, string, *string, bool) (*entity.Product, error)
+		ReorderProductImages(context.Context, string, []string) (*entity.Product, error)
+		RemoveProductImage(context.Context, string, string) (*entity.Product, error)
 		DeleteProduct(context.Context, string, string) error
 	}
 
+	ProductImport interface {
+		StartImport(context.Context, *multipart.FileHeader, entity.ImportMapping, string) (*entity.ImportJob, error)
+		GetImportJob(context.Context, string) (*entity.ImportJob, error)
+		GetImportErrors(context.Context, string, entity.Pagination) ([]entity.ImportRowError, string, error)
+	}
+
+	ProductLink interface {
+		LinkProducts(context.Context, *entity.ProductLink) error
+		UnlinkProducts(context.Context, string, entity.ProductLinkType, string) error
+		GetRelatedProducts(context.Context, string, entity.ProductLinkType, int) ([]entity.RelatedProduct, error)
+	}
+
+	Reservation interface {
+		ReserveStock(context.Context, string, []entity.OrderItem) (*entity.Reservation, error)
+		ReleaseReservation(context.Context, string) (*entity.Reservation, error)
+		CommitReservation(context.Context, string) (*entity.Reservation, error)
+		ReleaseExpiredReservations(context.Context) (int, error)
+	}
+
+	ProductExport interface {
+		ExportProducts(context.Context, io.Writer, entity.ExportOptions) (int, error)
+		ExportProductsToS3(context.Context, string, entity.ExportOptions) (int, error)
+	}
+
+	Variant interface {
+		CreateVariant(context.Context, *entity.Variant) (*entity.Variant, error)
+		GetVariants(context.Context, string) ([]entity.Variant, error)
+		GetVariantByID(context.Context, string, string) (*entity.Variant, error)
+		UpdateVariant(context.Context, *entity.Variant) error
+		UpdateVariantQuantity(context.Context, string, string, int, entity.StockMovement) error
+		DeleteVariant(context.Context, string, string) error
+	}
+
+	Price interface {
+		GetPriceHistory(context.Context, string, entity.Pagination) ([]entity.PriceHistoryEntry, string, error)
+		GetPriceAt(context.Context, string, time.Time) (*entity.PriceHistoryEntry, error)
+		SchedulePriceChange(context.Context, *entity.ScheduledPriceChange) (*entity.ScheduledPriceChange, error)
+		GetScheduledPriceChanges(context.Context, string) ([]entity.ScheduledPriceChange, error)
+		CancelScheduledPriceChange(context.Context, string, string) error
+		ApplyDuePriceChanges(context.Context) (int, error)
+	}
+
+	Promotion interface {
+		CreatePromotion(context.Context, *entity.Promotion) (*entity.Promotion, error)
+		GetPromotions(context.Context) ([]entity.Promotion, error)
+		GetPromotionByID(context.Context, string) (*entity.Promotion, error)
+		UpdatePromotion(context.Context, *entity.Promotion) error
+		DeletePromotion(context.Context, string) error
+	}
+
+	ImageGC interface {
+		CollectOrphanedImages(context.Context, bool) (*entity.ImageGCReport, error)
+	}
+
+	Catalog interface {
+		BackfillCatalog(context.Context) (int, error)
+	}
+
+	Trash interface {
+		GetDeletedProducts(context.Context, entity.Pagination) ([]entity.Product, string, error)
+		GetDeletedCategories(context.Context) ([]entity.Category, error)
+		RestoreProduct(context.Context, string) (*entity.Product, error)
+		RestoreCategory(context.Context, string) (*entity.Category, error)
+		PurgeExpired(context.Context) (*entity.TrashPurgeReport, error)
+	}
+
 	Category interface {
 		CreateCategory(context.Context, *entity.Category) (*entity.Category, error)
 		GetCategories(context.Context) (*[]entity.Category, error)
 		GetCategoryByID(context.Context, string) (*entity.Category, error)
+		GetCategoryBySlug(context.Context, string) (*entity.Category, error)
 		GetCategoriesByParentID(context.Context, string) (*[]entity.Category, error)
 		UpdateCategory(context.Context, *entity.Category) error
 		DeleteCategory(context.Context, string) error
+		SetCategoryTranslation(context.Context, string, string, entity.CategoryTranslation) (*entity.Category, error)
+		DeleteCategoryTranslation(context.Context, string, string) (*entity.Category, error)
 	}
 )
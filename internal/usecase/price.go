@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/pkg/kafka"
+)
+
+type PriceUseCase struct {
+	priceRepoDynamo   PriceDynamoRepo
+	productRepoDynamo ProductDynamoRepo
+	productRepoSearch ProductSearchRepo
+	variantRepoDynamo VariantDynamoRepo
+	bundleRepoDynamo  BundleDynamoRepo
+	promotions        *PromotionEvaluator
+	producer          *kafka.ProducerServer
+}
+
+func NewPriceUseCase(
+	priceRepoDynamo PriceDynamoRepo,
+	productRepoDynamo ProductDynamoRepo,
+	productRepoSearch ProductSearchRepo,
+	variantRepoDynamo VariantDynamoRepo,
+	bundleRepoDynamo BundleDynamoRepo,
+	promotions *PromotionEvaluator,
+	producer *kafka.ProducerServer,
+) *PriceUseCase {
+	return &PriceUseCase{
+		priceRepoDynamo:   priceRepoDynamo,
+		productRepoDynamo: productRepoDynamo,
+		productRepoSearch: productRepoSearch,
+		variantRepoDynamo: variantRepoDynamo,
+		bundleRepoDynamo:  bundleRepoDynamo,
+		promotions:        promotions,
+		producer:          producer,
+	}
+}
+
+// recordPrice appends the current price of the product to its history
+func recordPrice(
+	ctx context.Context,
+	priceRepo PriceDynamoRepo,
+	product *entity.Product,
+	previousPrice entity.Money,
+	source entity.PriceSource,
+	scheduledChangeID string,
+) error {
+	entry, err := entity.NewPriceHistoryEntry(product, previousPrice, product.UpdatedBy, source)
+	if err != nil {
+		return err
+	}
+	entry.ScheduledChangeID = scheduledChangeID
+
+	return priceRepo.AppendHistory(ctx, &entry)
+}
+
+func (u *PriceUseCase) GetPriceHistory(ctx context.Context, productID string, page entity.Pagination) ([]entity.PriceHistoryEntry, string, error) {
+	return u.priceRepoDynamo.GetHistory(ctx, productID, page)
+}
+
+func (u *PriceUseCase) GetPriceAt(ctx context.Context, productID string, at time.Time) (*entity.PriceHistoryEntry, error) {
+	return u.priceRepoDynamo.GetPriceAt(ctx, productID, at)
+}
+
+func (u *PriceUseCase) SchedulePriceChange(ctx context.Context, change *entity.ScheduledPriceChange) (*entity.ScheduledPriceChange, error) {
+	now := time.Now()
+	if err := change.Validate(now); err != nil {
+		return nil, err
+	}
+
+	product, err := u.productRepoDynamo.GetProductByID(ctx, change.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule price change: %w", err)
+	}
+	if change.Price.Currency != product.Price.Currency {
+		return nil, fmt.Errorf("%w: the product is priced in %s", entity.ErrCurrencyMismatch, product.Price.Currency)
+	}
+
+	err = change.GenerateID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule price change: %w", err)
+	}
+	change.Status = entity.ScheduledPricePending
+	change.CreatedAt = now
+	change.UpdatedAt = now
+
+	err = u.priceRepoDynamo.SaveScheduledChange(ctx, change)
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule price change: %w", err)
+	}
+
+	return change, nil
+}
+
+func (u *PriceUseCase) GetScheduledPriceChanges(ctx context.Context, productID string) ([]entity.ScheduledPriceChange, error) {
+	return u.priceRepoDynamo.GetScheduledChanges(ctx, productID)
+}
+
+func (u *PriceUseCase) CancelScheduledPriceChange(ctx context.Context, productID, changeID string) error {
+	change, err := u.priceRepoDynamo.GetScheduledChange(ctx, productID, changeID)
+	if err != nil {
+		return err
+	}
+
+	change.Status = entity.ScheduledPriceCancelled
+	change.UpdatedAt = time.Now()
+
+	return u.priceRepoDynamo.UpdateScheduledChangeStatus(ctx, change, entity.ScheduledPricePending)
+}
+
+// ApplyDuePriceChanges applies every pending change that reached its effective time and
+// returns how many were applied. A failed change does not stop the others.
+func (u *PriceUseCase) ApplyDuePriceChanges(ctx context.Context) (int, error) {
+	changes, err := u.priceRepoDynamo.GetDueScheduledChanges(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply due price changes: %w", err)
+	}
+
+	applied := 0
+	var errs []error
+	for i := range changes {
+		ok, err := u.applyScheduledChange(ctx, &changes[i])
+		if ok {
+			applied++
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to apply price change %s: %w", changes[i].ID, err))
+		}
+	}
+
+	return applied, errors.Join(errs...)
+}
+
+// applyScheduledChange claims the change first, so when several instances run the
+// scheduler only one of them applies it. It reports false when another one got it.
+func (u *PriceUseCase) applyScheduledChange(ctx context.Context, change *entity.ScheduledPriceChange) (bool, error) {
+	change.Status = entity.ScheduledPriceApplied
+	change.UpdatedAt = time.Now()
+
+	err := u.priceRepoDynamo.UpdateScheduledChangeStatus(ctx, change, entity.ScheduledPricePending)
+	if errors.Is(err, entity.ErrPriceChangeNotPending) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	product, err := u.productRepoDynamo.GetProductByID(ctx, change.ProductID)
+	if errors.Is(err, entity.ErrProductNotFound) {
+		// the product was deleted after the change was scheduled
+		return false, u.setScheduledChangeStatus(ctx, change, entity.ScheduledPriceFailed)
+	}
+	if err != nil {
+		return false, errors.Join(err, u.setScheduledChangeStatus(ctx, change, entity.ScheduledPricePending))
+	}
+
+	if change.Price.Currency != product.Price.Currency {
+		// the currency of the product changed after the change was scheduled
+		return false, u.setScheduledChangeStatus(ctx, change, entity.ScheduledPriceFailed)
+	}
+
+	previousPrice := product.Price
+	product.Price = change.Price
+	product.UpdatedAt = time.Now()
+	product.UpdatedBy = change.Actor
+
+	entry, err := entity.NewPriceHistoryEntry(product, previousPrice, change.Actor, entity.PriceSourceScheduled)
+	if err != nil {
+		return false, errors.Join(err, u.setScheduledChangeStatus(ctx, change, entity.ScheduledPricePending))
+	}
+	entry.ScheduledChangeID = change.ID
+
+	// the price and its history entry are written together, a failure writes neither
+	err = u.priceRepoDynamo.ApplyPrice(ctx, product, &entry)
+	if errors.Is(err, entity.ErrProductNotFound) {
+		return false, u.setScheduledChangeStatus(ctx, change, entity.ScheduledPriceFailed)
+	}
+	if err != nil {
+		// put it back, the next run tries again
+		return false, errors.Join(err, u.setScheduledChangeStatus(ctx, change, entity.ScheduledPricePending))
+	}
+	product.Version++
+
+	err = indexProduct(ctx, u.productRepoSearch, product)
+	if err != nil {
+		return true, fmt.Errorf("failed to index product: %w", err)
+	}
+
+	err = produceProductUpdated(ctx, u.producer, u.variantRepoDynamo, u.promotions, product)
+	if err != nil {
+		return true, fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	// the price of the discount bundles holding the product follows it
+	err = refreshBundles(
+		ctx,
+		u.bundleRepoDynamo,
+		u.productRepoDynamo,
+		u.productRepoSearch,
+		u.priceRepoDynamo,
+		u.variantRepoDynamo,
+		u.promotions,
+		u.producer,
+		product.ID,
+	)
+	if err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// setScheduledChangeStatus moves a claimed change out of applied
+func (u *PriceUseCase) setScheduledChangeStatus(ctx context.Context, change *entity.ScheduledPriceChange, status entity.ScheduledPriceStatus) error {
+	change.Status = status
+	change.UpdatedAt = time.Now()
+	return u.priceRepoDynamo.UpdateScheduledChangeStatus(ctx, change, entity.ScheduledPriceApplied)
+}
@@ -2,8 +2,9 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"mime/multipart"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/idoyudha/eshop-product/internal/entity"
@@ -11,150 +12,1002 @@ import (
 )
 
 const (
-	productCreatedTopic = "product-created"
-	productUpdatedTopic = "product-updated"
+	productCreatedTopic       = "product-created"
+	productUpdatedTopic       = "product-updated"
+	productStatusChangedTopic = "product-status-changed"
 )
 
 type ProductUseCase struct {
-	productRepoImage  ProductS3Repo
-	productRepoDynamo ProductDynamoRepo
-	producer          *kafka.ProducerServer
+	productRepoImage   ProductS3Repo
+	productRepoDynamo  ProductDynamoRepo
+	productRepoSearch  ProductSearchRepo
+	variantRepoDynamo  VariantDynamoRepo
+	categoryRepoDynamo CategoryDynamoRepo
+	priceRepoDynamo    PriceDynamoRepo
+	slugRepoDynamo     SlugDynamoRepo
+	bundleRepoDynamo   BundleDynamoRepo
+	stockRepoDynamo    StockMovementDynamoRepo
+	promotions         *PromotionEvaluator
+	producer           *kafka.ProducerServer
+	locales            *entity.Locales
+	stockAlerts        entity.StockAlertPolicy
 }
 
 func NewProductUseCase(
 	productRepoImage ProductS3Repo,
 	productRepoDynamo ProductDynamoRepo,
+	productRepoSearch ProductSearchRepo,
+	variantRepoDynamo VariantDynamoRepo,
+	categoryRepoDynamo CategoryDynamoRepo,
+	priceRepoDynamo PriceDynamoRepo,
+	slugRepoDynamo SlugDynamoRepo,
+	bundleRepoDynamo BundleDynamoRepo,
+	stockRepoDynamo StockMovementDynamoRepo,
+	promotions *PromotionEvaluator,
 	producer *kafka.ProducerServer,
+	locales *entity.Locales,
+	stockAlerts entity.StockAlertPolicy,
 ) *ProductUseCase {
 	return &ProductUseCase{
-		productRepoImage:  productRepoImage,
-		productRepoDynamo: productRepoDynamo,
-		producer:          producer,
+		productRepoImage:   productRepoImage,
+		productRepoDynamo:  productRepoDynamo,
+		productRepoSearch:  productRepoSearch,
+		variantRepoDynamo:  variantRepoDynamo,
+		categoryRepoDynamo: categoryRepoDynamo,
+		priceRepoDynamo:    priceRepoDynamo,
+		slugRepoDynamo:     slugRepoDynamo,
+		bundleRepoDynamo:   bundleRepoDynamo,
+		stockRepoDynamo:    stockRepoDynamo,
+		promotions:         promotions,
+		producer:           producer,
+		locales:            locales,
+		stockAlerts:        stockAlerts,
 	}
 }
 
 type kafkaProductCreatedMessage struct {
-	ID          string  `json:"id"`
-	SKU         string  `json:"sku"`
-	Name        string  `json:"name"`
-	ImageURL    string  `json:"image_url"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Quantity    int     `json:"quantity"`
-	CategoryID  string  `json:"category_id"`
+	ID                string                             `json:"id"`
+	SKU               string                             `json:"sku"`
+	Name              string                             `json:"name"`
+	ImageURL          string                             `json:"image_url"`
+	Description       string                             `json:"description"`
+	Price             kafkaMoney                         `json:"price"`
+	Prices            []kafkaMoney                       `json:"prices"`
+	SalePrice         kafkaMoney                         `json:"sale_price"`
+	AppliedPromotions []kafkaAppliedPromotion            `json:"applied_promotions"`
+	Quantity          int                                `json:"quantity"`
+	CategoryID        string                             `json:"category_id"`
+	VariantIDs        []string                           `json:"variant_ids"`
+	Images            []kafkaProductImage                `json:"images"`
+	Attributes        map[string]interface{}             `json:"attributes"`
+	Translations      map[string]kafkaProductTranslation `json:"translations"`
 }
 
-func (u *ProductUseCase) CreateProduct(ctx context.Context, product *entity.Product, imageFile *multipart.FileHeader) (*entity.Product, error) {
+// kafkaMoney is an amount in the minor unit of an ISO 4217 currency
+type kafkaMoney struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func moneyListToKafkaMoney(prices []entity.Money) []kafkaMoney {
+	kafkaPrices := make([]kafkaMoney, 0, len(prices))
+	for _, price := range prices {
+		kafkaPrices = append(kafkaPrices, kafkaMoney(price))
+	}
+	return kafkaPrices
+}
+
+// kafkaProductTranslation is the name and description in a locale other than the default
+type kafkaProductTranslation struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func productTranslationsToKafka(translations map[string]entity.ProductTranslation) map[string]kafkaProductTranslation {
+	kafkaTranslations := make(map[string]kafkaProductTranslation, len(translations))
+	for locale, translation := range translations {
+		kafkaTranslations[locale] = kafkaProductTranslation(translation)
+	}
+	return kafkaTranslations
+}
+
+type kafkaAppliedPromotion struct {
+	PromotionID  string      `json:"promotion_id"`
+	Name         string      `json:"name"`
+	DiscountType string      `json:"discount_type"`
+	Value        float64     `json:"value,omitempty"`
+	Amount       *kafkaMoney `json:"amount,omitempty"`
+	Discount     kafkaMoney  `json:"discount"`
+}
+
+func appliedPromotionsToKafkaAppliedPromotions(promotions []entity.AppliedPromotion) []kafkaAppliedPromotion {
+	kafkaPromotions := make([]kafkaAppliedPromotion, 0, len(promotions))
+	for _, promotion := range promotions {
+		kafkaPromotion := kafkaAppliedPromotion{
+			PromotionID:  promotion.PromotionID,
+			Name:         promotion.Name,
+			DiscountType: string(promotion.DiscountType),
+			Value:        promotion.Value,
+			Discount:     kafkaMoney(promotion.Discount),
+		}
+		if promotion.DiscountType == entity.DiscountFixed {
+			amount := kafkaMoney(promotion.Amount)
+			kafkaPromotion.Amount = &amount
+		}
+		kafkaPromotions = append(kafkaPromotions, kafkaPromotion)
+	}
+	return kafkaPromotions
+}
+
+type kafkaProductImage struct {
+	ID         string                `json:"id"`
+	URL        string                `json:"url"`
+	AltText    string                `json:"alt_text"`
+	Position   int                   `json:"position"`
+	IsPrimary  bool                  `json:"is_primary"`
+	Renditions []kafkaImageRendition `json:"renditions"`
+}
+
+type kafkaImageRendition struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url"`
+}
+
+func productImagesToKafkaProductImages(images []entity.ProductImage) []kafkaProductImage {
+	kafkaImages := make([]kafkaProductImage, 0, len(images))
+	for _, image := range images {
+		renditions := make([]kafkaImageRendition, 0, len(image.Renditions))
+		for _, rendition := range image.Renditions {
+			renditions = append(renditions, kafkaImageRendition(rendition))
+		}
+
+		kafkaImages = append(kafkaImages, kafkaProductImage{
+			ID:         image.ID,
+			URL:        image.URL,
+			AltText:    image.AltText,
+			Position:   image.Position,
+			IsPrimary:  image.IsPrimary,
+			Renditions: renditions,
+		})
+	}
+	return kafkaImages
+}
+
+// CreateImageUpload returns a presigned url for uploading an image directly to s3,
+// the token is then passed instead of a file when creating or updating a product
+func (u *ProductUseCase) CreateImageUpload(ctx context.Context, contentType string, size int64) (*entity.ImageUpload, error) {
+	return u.productRepoImage.PresignUpload(ctx, contentType, size)
+}
+
+func (u *ProductUseCase) CreateProduct(ctx context.Context, product *entity.Product, imageSource entity.ImageSource) (*entity.Product, error) {
 	err := product.GenerateProductID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
 	product.GenerateSKU()
 
+	if product.IsBundle() {
+		err = prepareBundle(ctx, u.productRepoDynamo, product)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create product: %w", err)
+		}
+	} else {
+		product.Type = entity.ProductSimple
+		product.Bundle = nil
+	}
+
+	err = product.ValidatePrices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product: %w", err)
+	}
+
+	err = u.validateAttributes(ctx, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product: %w", err)
+	}
+
+	err = product.InitStatus(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	// save image to s3
-	imageURL, err := u.productRepoImage.UploadImage(ctx, imageFile)
+	image, err := entity.NewProductImage(product.Name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
-	product.SetImageURL(imageURL)
+
+	err = u.productRepoImage.UploadImage(ctx, product.ID, &image, imageSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product: %w", err)
+	}
+	product.AddImage(image, true)
+
+	slug, err := reserveSlug(ctx, u.slugRepoDynamo, entity.SlugProduct, product.Name, product.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product: %w", err)
+	}
+	product.Slug = slug.slug
 
 	// save product to dynamo
 	err = u.productRepoDynamo.Save(ctx, product)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create product: %w", err)
+		return nil, slug.releaseOnError(ctx, u.slugRepoDynamo, fmt.Errorf("failed to create product: %w", err))
+	}
+
+	if product.IsBundle() {
+		err = u.bundleRepoDynamo.SaveComponents(ctx, product.ID, product.Bundle.Components)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create product: %w", err)
+		}
+	}
+
+	err = recordPrice(ctx, u.priceRepoDynamo, product, entity.Money{Currency: product.Price.Currency}, entity.PriceSourceManual, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to record product price: %w", err)
+	}
+
+	err = indexProduct(ctx, u.productRepoSearch, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index product: %w", err)
+	}
+
+	err = u.promotions.apply(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	// drafts and scheduled products are announced once they get published
+	if !product.IsPublished() {
+		return product, nil
+	}
+
+	err = produceProductCreated(ctx, u.producer, u.variantRepoDynamo, u.promotions, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send product data to kafka: %w", err)
+	}
+
+	// sent product data to main warehouse
+	return product, nil
+}
+
+// produceProductCreated sends the product to product-created, when it is published for the first time
+func produceProductCreated(
+	ctx context.Context,
+	producer *kafka.ProducerServer,
+	variantRepo VariantDynamoRepo,
+	promotions *PromotionEvaluator,
+	product *entity.Product,
+) error {
+	variantIDs, err := productVariantIDs(ctx, variantRepo, product.ID)
+	if err != nil {
+		return err
+	}
+
+	err = promotions.apply(ctx, product)
+	if err != nil {
+		return err
 	}
 
 	message := kafkaProductCreatedMessage{
-		ID:          product.ID,
-		SKU:         product.SKU,
-		Name:        product.Name,
-		ImageURL:    product.ImageURL,
-		Description: product.Description,
-		Price:       product.Price,
-		Quantity:    product.Quantity,
-		CategoryID:  product.CategoryID,
+		ID:                product.ID,
+		SKU:               product.SKU,
+		Name:              product.Name,
+		ImageURL:          product.ImageURL,
+		Description:       product.Description,
+		Price:             kafkaMoney(product.Price),
+		Prices:            moneyListToKafkaMoney(product.Prices),
+		SalePrice:         kafkaMoney(product.SalePrice),
+		AppliedPromotions: appliedPromotionsToKafkaAppliedPromotions(product.AppliedPromotions),
+		Quantity:          product.Quantity,
+		CategoryID:        product.CategoryID,
+		VariantIDs:        variantIDs,
+		Images:            productImagesToKafkaProductImages(product.Images),
+		Attributes:        product.Attributes,
+		Translations:      productTranslationsToKafka(product.Translations),
 	}
 
-	err = u.producer.Produce(
+	return producer.Produce(
 		productCreatedTopic,
 		[]byte(product.ID),
 		message,
 	)
+}
+
+type kafkaProductStatusChangedMessage struct {
+	ProductID      string    `json:"product_id"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status"`
+	Actor          string    `json:"actor"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+// indexProduct keeps the search index to the published products, it backs a public endpoint
+func indexProduct(ctx context.Context, searchRepo ProductSearchRepo, product *entity.Product) error {
+	if product.IsPublished() {
+		return searchRepo.Index(ctx, product)
+	}
+	return searchRepo.Remove(ctx, product.ID)
+}
+
+func productVariantIDs(ctx context.Context, variantRepo VariantDynamoRepo, productID string) ([]string, error) {
+	variants, err := variantRepo.GetByProductID(ctx, productID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send product data to kafka: %w", err)
+		return nil, err
 	}
 
-	// sent product data to main warehouse
-	return product, nil
+	variantIDs := make([]string, 0, len(variants))
+	for _, v := range variants {
+		variantIDs = append(variantIDs, v.ID)
+	}
+	return variantIDs, nil
 }
 
-func (u *ProductUseCase) GetProducts(ctx context.Context) (*[]entity.Product, error) {
-	return u.productRepoDynamo.GetProducts(ctx)
+func (u *ProductUseCase) GetProducts(
+	ctx context.Context,
+	filter entity.ProductFilter,
+	sort entity.ProductSort,
+	page entity.Pagination,
+) (*[]entity.Product, string, error) {
+	if err := filter.Validate(sort); err != nil {
+		return nil, "", err
+	}
+	products, nextCursor, err := u.productRepoDynamo.GetProducts(ctx, filter, sort, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = u.promotions.applyAll(ctx, *products)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return products, nextCursor, nil
 }
 
+// GetProductByID returns the product when it is published, it backs a public lookup
 func (u *ProductUseCase) GetProductByID(ctx context.Context, id string) (*entity.Product, error) {
-	return u.productRepoDynamo.GetProductByID(ctx, id)
+	product, err := u.productRepoDynamo.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsPublished() {
+		return nil, fmt.Errorf("%w, id: %s", entity.ErrProductNotFound, id)
+	}
+
+	err = u.promotions.apply(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// GetAdminProductByID returns the product in any status
+func (u *ProductUseCase) GetAdminProductByID(ctx context.Context, id string) (*entity.Product, error) {
+	product, err := u.productRepoDynamo.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.promotions.apply(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// GetProductBySlug finds the published product by its current slug or by one it had
+// before, the slug of the returned product tells which one it was
+func (u *ProductUseCase) GetProductBySlug(ctx context.Context, slug string) (*entity.Product, error) {
+	product, err := u.productRepoDynamo.GetProductBySlug(ctx, slug)
+	if errors.Is(err, entity.ErrProductNotFound) {
+		reserved, slugErr := u.slugRepoDynamo.GetBySlug(ctx, entity.SlugProduct, slug)
+		if errors.Is(slugErr, entity.ErrSlugNotFound) {
+			return nil, err
+		}
+		if slugErr != nil {
+			return nil, slugErr
+		}
+		product, err = u.productRepoDynamo.GetProductByID(ctx, reserved.TargetID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsPublished() {
+		return nil, fmt.Errorf("%w, slug: %s", entity.ErrProductNotFound, slug)
+	}
+
+	err = u.promotions.apply(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
 }
 
-func (u *ProductUseCase) GetProductsByCategory(ctx context.Context, categoryID string) ([]entity.Product, error) {
-	return u.productRepoDynamo.GetProductsByCategory(ctx, categoryID)
+// GetProductsByCategory returns the published products of the category, it backs a public listing
+func (u *ProductUseCase) GetProductsByCategory(ctx context.Context, categoryID string, page entity.Pagination) ([]entity.Product, string, error) {
+	products, nextCursor, err := u.productRepoDynamo.GetProductsByCategory(ctx, categoryID, entity.ProductPublished, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = u.promotions.applyAll(ctx, products)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return products, nextCursor, nil
 }
 
+// GetProductsByCategories returns the published products of the categories, it backs a public listing
 func (u *ProductUseCase) GetProductsByCategories(ctx context.Context, categoryIDs []string) ([]entity.Product, error) {
-	return u.productRepoDynamo.GetProductsByCategories(ctx, categoryIDs)
+	products, err := u.productRepoDynamo.GetProductsByCategories(ctx, categoryIDs, entity.ProductPublished)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.promotions.applyAll(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func (u *ProductUseCase) SearchProducts(ctx context.Context, query string, limit int) ([]entity.ProductSearchHit, error) {
+	hits, err := u.productRepoSearch.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]*entity.Product, 0, len(hits))
+	for i := range hits {
+		products = append(products, &hits[i].Product)
+	}
+
+	err = u.promotions.apply(ctx, products...)
+	if err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+// BuildSearchIndex loads every product from dynamo into the search index, called once at startup
+func (u *ProductUseCase) BuildSearchIndex(ctx context.Context) error {
+	page := entity.Pagination{Limit: entity.MaxPageLimit}
+	for {
+		filter := entity.ProductFilter{Status: entity.ProductPublished}
+		products, nextCursor, err := u.productRepoDynamo.GetProducts(ctx, filter, entity.ProductSort{}, page)
+		if err != nil {
+			return fmt.Errorf("failed to build search index: %w", err)
+		}
+
+		for i := range *products {
+			if err := u.productRepoSearch.Index(ctx, &(*products)[i]); err != nil {
+				return fmt.Errorf("failed to build search index: %w", err)
+			}
+		}
+
+		if nextCursor == "" {
+			return nil
+		}
+		page.Cursor = nextCursor
+	}
 }
 
 type kafkaProductUpdatedMessage struct {
-	ProductID          uuid.UUID `json:"product_id"`
-	ProductName        string    `json:"product_name"`
-	ProductImageURL    string    `json:"product_image_url"`
-	ProductDescription string    `json:"product_description"`
-	ProductPrice       float64   `json:"product_price"`
-	ProductCategoryID  uuid.UUID `json:"product_category_id"`
+	ProductID                uuid.UUID                          `json:"product_id"`
+	ProductName              string                             `json:"product_name"`
+	ProductImageURL          string                             `json:"product_image_url"`
+	ProductDescription       string                             `json:"product_description"`
+	ProductPrice             kafkaMoney                         `json:"product_price"`
+	ProductPrices            []kafkaMoney                       `json:"product_prices"`
+	ProductSalePrice         kafkaMoney                         `json:"product_sale_price"`
+	ProductAppliedPromotions []kafkaAppliedPromotion            `json:"product_applied_promotions"`
+	ProductCategoryID        uuid.UUID                          `json:"product_category_id"`
+	ProductVariantIDs        []string                           `json:"product_variant_ids"`
+	ProductImages            []kafkaProductImage                `json:"product_images"`
+	ProductAttributes        map[string]interface{}             `json:"product_attributes"`
+	ProductTranslations      map[string]kafkaProductTranslation `json:"product_translations"`
 }
 
-func (u *ProductUseCase) UpdateProduct(ctx context.Context, product *entity.Product, imageFile *multipart.FileHeader) error {
-	imageURL, err := u.productRepoImage.UploadImage(ctx, imageFile)
+// produceProductUpdated sends the product with its sale price and the ids of its current
+// variants to product-updated. Products that were never published are not sent, the
+// consumers do not know them yet.
+func produceProductUpdated(
+	ctx context.Context,
+	producer *kafka.ProducerServer,
+	variantRepo VariantDynamoRepo,
+	promotions *PromotionEvaluator,
+	product *entity.Product,
+) error {
+	if !product.WasPublished() {
+		return nil
+	}
+
+	variantIDs, err := productVariantIDs(ctx, variantRepo, product.ID)
 	if err != nil {
-		return fmt.Errorf("failed to update product: %w", err)
+		return err
 	}
-	product.SetImageURL(imageURL)
 
-	err = u.productRepoDynamo.Update(ctx, product)
+	err = promotions.apply(ctx, product)
 	if err != nil {
-		return fmt.Errorf("failed to update product: %w", err)
+		return err
 	}
 
 	message := kafkaProductUpdatedMessage{
-		ProductID:          uuid.MustParse(product.ID),
-		ProductName:        product.Name,
-		ProductImageURL:    product.ImageURL,
-		ProductDescription: product.Description,
-		ProductPrice:       product.Price,
-		ProductCategoryID:  uuid.MustParse(product.CategoryID),
+		ProductID:                uuid.MustParse(product.ID),
+		ProductName:              product.Name,
+		ProductImageURL:          product.ImageURL,
+		ProductDescription:       product.Description,
+		ProductPrice:             kafkaMoney(product.Price),
+		ProductPrices:            moneyListToKafkaMoney(product.Prices),
+		ProductSalePrice:         kafkaMoney(product.SalePrice),
+		ProductAppliedPromotions: appliedPromotionsToKafkaAppliedPromotions(product.AppliedPromotions),
+		ProductCategoryID:        uuid.MustParse(product.CategoryID),
+		ProductVariantIDs:        variantIDs,
+		ProductImages:            productImagesToKafkaProductImages(product.Images),
+		ProductAttributes:        product.Attributes,
+		ProductTranslations:      productTranslationsToKafka(product.Translations),
 	}
 
-	err = u.producer.Produce(
+	return producer.Produce(
 		productUpdatedTopic,
 		[]byte(product.ID),
 		message,
 	)
+}
+
+func (u *ProductUseCase) UpdateProduct(ctx context.Context, product *entity.Product, imageSource entity.ImageSource) error {
+	current, err := u.productRepoDynamo.GetProductByID(ctx, product.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+	// a stale version fails before anything is uploaded, the update itself checks it again
+	if product.Version > 0 && product.Version != current.Version {
+		return &entity.VersionMismatchError{Current: current.Version}
+	}
+	// without If-Match the update is based on the product read here, so it is written only
+	// if no other write came in between
+	implicitVersion := product.Version == 0
+	if implicitVersion {
+		product.Version = current.Version
+	}
+
+	// the price of a discount bundle follows its components
+	if current.IsBundle() && current.Bundle != nil && current.Bundle.Pricing == entity.BundlePriceDiscount {
+		product.Price = current.Price
+	}
+
+	// prices are checked as they will be stored, the price list may be kept from before
+	updated := *current
+	updated.ApplyUpdate(product)
+	err = updated.ValidatePrices()
+	if err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+
+	// attributes left out of the update are kept as they are
+	if product.Attributes != nil {
+		err = u.validateAttributes(ctx, product)
+		if err != nil {
+			return fmt.Errorf("failed to update product: %w", err)
+		}
+	}
+
+	// the uploaded image replaces the primary image of the gallery
+	image, err := entity.NewProductImage(product.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+
+	err = u.productRepoImage.UploadImage(ctx, product.ID, &image, imageSource)
+	if err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+	current.ReplacePrimaryImage(image)
+	product.Images = current.Images
+	product.SetImageURL(current.ImageURL)
+
+	// products created before slugs get one with their next update, a new slug is given
+	// back when the update fails
+	slug, err := renameSlug(ctx, u.slugRepoDynamo, entity.SlugProduct, current.Slug, updated.Name, product.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+	product.Slug = slug.slug
+
+	err = u.productRepoDynamo.Update(ctx, product)
+	if err != nil {
+		var mismatch *entity.VersionMismatchError
+		if implicitVersion && errors.As(err, &mismatch) {
+			err = entity.ErrConcurrentUpdate
+		}
+		return slug.releaseOnError(ctx, u.slugRepoDynamo, fmt.Errorf("failed to update product: %w", err))
+	}
+
+	previousPrice := current.Price
+	current.ApplyUpdate(product)
+
+	if current.Price != previousPrice {
+		err = recordPrice(ctx, u.priceRepoDynamo, current, previousPrice, entity.PriceSourceManual, "")
+		if err != nil {
+			return fmt.Errorf("failed to record product price: %w", err)
+		}
+	}
+	err = indexProduct(ctx, u.productRepoSearch, current)
+	if err != nil {
+		return fmt.Errorf("failed to index product: %w", err)
+	}
+
+	err = produceProductUpdated(ctx, u.producer, u.variantRepoDynamo, u.promotions, current)
 	if err != nil {
 		// TODO: handle error, cancel the update if failed. or try use retry mechanism
 		return fmt.Errorf("failed to produce kafka message: %w", err)
 	}
 
+	// the price of the discount bundles holding the product follows it
+	if current.Price != previousPrice {
+		return refreshBundles(
+			ctx,
+			u.bundleRepoDynamo,
+			u.productRepoDynamo,
+			u.productRepoSearch,
+			u.priceRepoDynamo,
+			u.variantRepoDynamo,
+			u.promotions,
+			u.producer,
+			current.ID,
+		)
+	}
+
+	return nil
+}
+
+// validateAttributes checks the attributes against the schema of the product category
+// and replaces them with their typed values
+func (u *ProductUseCase) validateAttributes(ctx context.Context, product *entity.Product) error {
+	category, err := u.categoryRepoDynamo.GetByID(ctx, product.CategoryID)
+	if err != nil {
+		return err
+	}
+
+	attributes, err := entity.ValidateAttributes(category.Attributes, product.Attributes)
+	if err != nil {
+		return err
+	}
+
+	product.Attributes = attributes
 	return nil
 }
 
-func (u *ProductUseCase) UpdateProductQuantity(ctx context.Context, productID string, quantity int) error {
-	categoryID, err := u.productRepoDynamo.GetCategoryByProductId(ctx, productID)
+func (u *ProductUseCase) AddProductImage(
+	ctx context.Context,
+	productID string,
+	imageSource entity.ImageSource,
+	altText string,
+	primary bool,
+) (*entity.Product, error) {
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add product image: %w", err)
+	}
+
+	image, err := entity.NewProductImage(altText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add product image: %w", err)
+	}
+
+	err = u.productRepoImage.UploadImage(ctx, productID, &image, imageSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add product image: %w", err)
+	}
+	product.AddImage(image, primary)
+
+	err = u.saveProductImages(ctx, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add product image: %w", err)
+	}
+
+	return product, nil
+}
+
+func (u *ProductUseCase) UpdateProductImage(
+	ctx context.Context,
+	productID string,
+	imageID string,
+	altText *string,
+	primary bool,
+) (*entity.Product, error) {
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update product image: %w", err)
+	}
+
+	err = product.UpdateImage(imageID, altText, primary)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.saveProductImages(ctx, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update product image: %w", err)
+	}
+
+	return product, nil
+}
+
+func (u *ProductUseCase) ReorderProductImages(ctx context.Context, productID string, imageIDs []string) (*entity.Product, error) {
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reorder product images: %w", err)
+	}
+
+	err = product.ReorderImages(imageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.saveProductImages(ctx, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reorder product images: %w", err)
+	}
+
+	return product, nil
+}
+
+func (u *ProductUseCase) RemoveProductImage(ctx context.Context, productID, imageID string) (*entity.Product, error) {
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove product image: %w", err)
+	}
+
+	image, err := product.RemoveImage(imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.saveProductImages(ctx, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove product image: %w", err)
+	}
+
+	// the gallery no longer points at the object, so it is safe to delete it
+	err = u.productRepoImage.DeleteImage(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove product image: %w", err)
+	}
+
+	return product, nil
+}
+
+// saveProductImages stores the gallery and propagates it to the search index and kafka
+func (u *ProductUseCase) saveProductImages(ctx context.Context, product *entity.Product) error {
+	product.UpdatedAt = time.Now()
+
+	err := u.productRepoDynamo.UpdateImages(ctx, product)
+	if err != nil {
+		return err
+	}
+
+	err = indexProduct(ctx, u.productRepoSearch, product)
+	if err != nil {
+		return err
+	}
+
+	return produceProductUpdated(ctx, u.producer, u.variantRepoDynamo, u.promotions, product)
+}
+
+// SetProductTranslation stores the name and description of the product in a locale
+// other than the default one
+func (u *ProductUseCase) SetProductTranslation(
+	ctx context.Context,
+	productID string,
+	locale string,
+	translation entity.ProductTranslation,
+	actor string,
+) (*entity.Product, error) {
+	locale, err := u.locales.Validate(locale)
+	if err != nil {
+		return nil, err
+	}
+	err = translation.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set product translation: %w", err)
+	}
+
+	product.Translations[locale] = translation
+	err = u.saveProductTranslations(ctx, product, actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set product translation: %w", err)
+	}
+
+	return product, nil
+}
+
+func (u *ProductUseCase) DeleteProductTranslation(ctx context.Context, productID, locale, actor string) (*entity.Product, error) {
+	locale, err := u.locales.Validate(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete product translation: %w", err)
+	}
+
+	if _, ok := product.Translations[locale]; !ok {
+		return nil, fmt.Errorf("%w, product: %s, locale: %s", entity.ErrTranslationNotFound, productID, locale)
+	}
+
+	delete(product.Translations, locale)
+	err = u.saveProductTranslations(ctx, product, actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete product translation: %w", err)
+	}
+
+	return product, nil
+}
+
+// saveProductTranslations stores the translations and sends them to product-updated
+func (u *ProductUseCase) saveProductTranslations(ctx context.Context, product *entity.Product, actor string) error {
+	product.UpdatedAt = time.Now()
+	product.UpdatedBy = actor
+
+	err := u.productRepoDynamo.UpdateTranslations(ctx, product)
 	if err != nil {
-		return fmt.Errorf("failed to update product quantity: %w", err)
+		return err
 	}
-	return u.productRepoDynamo.UpdateProductQty(ctx, productID, *categoryID, quantity)
+
+	return produceProductUpdated(ctx, u.producer, u.variantRepoDynamo, u.promotions, product)
+}
+
+// ChangeProductStatus moves the product through its lifecycle. The first publication is
+// announced on product-created, later changes of a published product on product-status-changed.
+func (u *ProductUseCase) ChangeProductStatus(
+	ctx context.Context,
+	productID string,
+	status entity.ProductStatus,
+	publishAt *time.Time,
+	actor string,
+) (*entity.Product, error) {
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change product status: %w", err)
+	}
+
+	err = u.transitionProduct(ctx, product, status, publishAt, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.promotions.apply(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// PublishDueProducts publishes every scheduled product whose publish_at was reached and
+// returns how many were published. A failed product does not stop the others.
+func (u *ProductUseCase) PublishDueProducts(ctx context.Context) (int, error) {
+	products, err := u.productRepoDynamo.GetDueScheduledProducts(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish due products: %w", err)
+	}
+
+	published := 0
+	var errs []error
+	for i := range products {
+		err := u.transitionProduct(ctx, &products[i], entity.ProductPublished, nil, "scheduler")
+		if errors.Is(err, entity.ErrProductStatusChanged) {
+			// another instance published it, or it was changed meanwhile
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to publish product %s: %w", products[i].ID, err))
+			continue
+		}
+		published++
+	}
+
+	return published, errors.Join(errs...)
+}
+
+// transitionProduct stores the new status, conditioned on the status it was read with,
+// then updates the search index and sends the events
+func (u *ProductUseCase) transitionProduct(
+	ctx context.Context,
+	product *entity.Product,
+	status entity.ProductStatus,
+	publishAt *time.Time,
+	actor string,
+) error {
+	previousStatus := product.Status
+	wasPublished := product.WasPublished()
+
+	err := product.TransitionTo(status, publishAt, time.Now())
+	if err != nil {
+		return err
+	}
+	product.UpdatedBy = actor
+
+	err = u.productRepoDynamo.UpdateStatus(ctx, product, previousStatus)
+	if err != nil {
+		return err
+	}
+
+	err = indexProduct(ctx, u.productRepoSearch, product)
+	if err != nil {
+		return fmt.Errorf("failed to index product: %w", err)
+	}
+
+	if !wasPublished {
+		if !product.IsPublished() {
+			// the consumers have not seen the product yet
+			return nil
+		}
+		err = produceProductCreated(ctx, u.producer, u.variantRepoDynamo, u.promotions, product)
+		if err != nil {
+			return fmt.Errorf("failed to produce kafka message: %w", err)
+		}
+		return nil
+	}
+
+	message := kafkaProductStatusChangedMessage{
+		ProductID:      product.ID,
+		Status:         string(product.Status),
+		PreviousStatus: string(previousStatus),
+		Actor:          actor,
+		ChangedAt:      product.UpdatedAt,
+	}
+
+	err = u.producer.Produce(
+		productStatusChangedTopic,
+		[]byte(product.ID),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
 }
 
 func (u *ProductUseCase) DeleteProduct(ctx context.Context, productID string, categoryID string) error {
-	return u.productRepoDynamo.Delete(ctx, productID, categoryID)
+	err := u.productRepoDynamo.Delete(ctx, productID, categoryID)
+	if err != nil {
+		return err
+	}
+
+	err = u.productRepoSearch.Remove(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	// bundles holding the product are out of stock without it
+	return refreshBundles(
+		ctx,
+		u.bundleRepoDynamo,
+		u.productRepoDynamo,
+		u.productRepoSearch,
+		u.priceRepoDynamo,
+		u.variantRepoDynamo,
+		u.promotions,
+		u.producer,
+		productID,
+	)
 }
@@ -0,0 +1,315 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+type ExportUseCase struct {
+	productRepoImage   ProductS3Repo
+	productRepoDynamo  ProductDynamoRepo
+	categoryRepoDynamo CategoryDynamoRepo
+	categoryRepoRedis  CategoryRedisRepo
+}
+
+func NewExportUseCase(
+	productRepoImage ProductS3Repo,
+	productRepoDynamo ProductDynamoRepo,
+	categoryRepoDynamo CategoryDynamoRepo,
+	categoryRepoRedis CategoryRedisRepo,
+) *ExportUseCase {
+	return &ExportUseCase{
+		productRepoImage:   productRepoImage,
+		productRepoDynamo:  productRepoDynamo,
+		categoryRepoDynamo: categoryRepoDynamo,
+		categoryRepoRedis:  categoryRepoRedis,
+	}
+}
+
+// ExportProducts writes every product matching the filter to w, reading the table
+// page by page so only one page is held in memory. Nothing is written when the
+// options are rejected. It returns the number of exported products.
+func (u *ExportUseCase) ExportProducts(ctx context.Context, w io.Writer, options entity.ExportOptions) (int, error) {
+	if err := options.Filter.Validate(options.Sort); err != nil {
+		return 0, err
+	}
+
+	var categoryNames map[string]string
+	if options.IncludeCategory {
+		var err error
+		categoryNames, err = u.categoryNames(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	encoder := newProductEncoder(w, options)
+	err := encoder.header()
+	if err != nil {
+		return 0, err
+	}
+
+	exported := 0
+	page := entity.Pagination{Limit: entity.MaxPageLimit}
+	for {
+		products, nextCursor, err := u.productRepoDynamo.GetProducts(ctx, options.Filter, options.Sort, page)
+		if err != nil {
+			return exported, err
+		}
+
+		for _, product := range *products {
+			err = encoder.encode(product, categoryNames[product.CategoryID])
+			if err != nil {
+				return exported, err
+			}
+			exported++
+		}
+
+		// hand every page to the client right away
+		err = encoder.flush()
+		if err != nil {
+			return exported, err
+		}
+
+		if nextCursor == "" {
+			return exported, nil
+		}
+		page.Cursor = nextCursor
+	}
+}
+
+// ExportProductsToS3 runs the export into an object of the product bucket
+func (u *ExportUseCase) ExportProductsToS3(ctx context.Context, key string, options entity.ExportOptions) (int, error) {
+	if err := options.Filter.Validate(options.Sort); err != nil {
+		return 0, err
+	}
+
+	exported := 0
+	err := u.productRepoImage.UploadExport(ctx, key, options.Format.ContentType(), func(w io.Writer) error {
+		var err error
+		exported, err = u.ExportProducts(ctx, w, options)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return exported, nil
+}
+
+// categoryNames reads the names from the cached categories, dynamo is the fallback
+// while the cache is empty
+func (u *ExportUseCase) categoryNames(ctx context.Context) (map[string]string, error) {
+	categories, err := u.categoryRepoRedis.GetAll(ctx)
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	if categories == nil {
+		categories, err = u.categoryRepoDynamo.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	names := make(map[string]string, len(*categories))
+	for _, category := range *categories {
+		names[category.ID] = category.Name
+	}
+	return names, nil
+}
+
+type productEncoder interface {
+	header() error
+	encode(product entity.Product, categoryName string) error
+	flush() error
+}
+
+func newProductEncoder(w io.Writer, options entity.ExportOptions) productEncoder {
+	if options.Format == entity.ExportJSONLines {
+		return &jsonLinesProductEncoder{
+			w:               w,
+			encoder:         json.NewEncoder(w),
+			includeCategory: options.IncludeCategory,
+		}
+	}
+	return &csvProductEncoder{
+		w:               w,
+		writer:          csv.NewWriter(w),
+		includeCategory: options.IncludeCategory,
+	}
+}
+
+// csvProductEncoder writes a row per product, the columns that the import also
+// reads carry the same name and format, so an export can be imported again
+type csvProductEncoder struct {
+	w               io.Writer
+	writer          *csv.Writer
+	includeCategory bool
+}
+
+func (e *csvProductEncoder) header() error {
+	columns := []string{
+		"id", "sku", "slug", entity.ImportFieldName, entity.ImportFieldDescription, entity.ImportFieldPrice,
+		entity.ImportFieldCurrency, "prices", entity.ImportFieldQuantity, entity.ImportFieldCategoryID,
+	}
+	if e.includeCategory {
+		columns = append(columns, "category_name")
+	}
+	columns = append(columns,
+		entity.ImportFieldStatus, entity.ImportFieldPublishAt, "published_at", entity.ImportFieldImageURL,
+		entity.ImportFieldAttributes, "created_at", "updated_at",
+	)
+	return e.writer.Write(columns)
+}
+
+func (e *csvProductEncoder) encode(product entity.Product, categoryName string) error {
+	prices := make([]string, 0, len(product.Prices))
+	for _, price := range product.Prices {
+		prices = append(prices, price.String())
+	}
+
+	attributes := ""
+	if len(product.Attributes) > 0 {
+		encoded, err := json.Marshal(product.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to encode attributes of product %s: %w", product.ID, err)
+		}
+		attributes = string(encoded)
+	}
+
+	record := []string{
+		product.ID,
+		product.SKU,
+		product.Slug,
+		product.Name,
+		product.Description,
+		product.Price.Decimal(),
+		product.Price.Currency,
+		strings.Join(prices, ";"),
+		strconv.Itoa(product.Quantity),
+		product.CategoryID,
+	}
+	if e.includeCategory {
+		record = append(record, categoryName)
+	}
+	record = append(record,
+		string(product.Status),
+		formatOptionalTime(product.PublishAt),
+		formatOptionalTime(product.PublishedAt),
+		product.ImageURL,
+		attributes,
+		product.CreatedAt.Format(time.RFC3339),
+		product.UpdatedAt.Format(time.RFC3339),
+	)
+	return e.writer.Write(record)
+}
+
+func (e *csvProductEncoder) flush() error {
+	e.writer.Flush()
+	if err := e.writer.Error(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	flushWriter(e.w)
+	return nil
+}
+
+type jsonLinesProductEncoder struct {
+	w               io.Writer
+	encoder         *json.Encoder
+	includeCategory bool
+}
+
+type exportMoney struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type exportProduct struct {
+	ID           string                   `json:"id"`
+	SKU          string                   `json:"sku"`
+	Slug         string                   `json:"slug,omitempty"`
+	Name         string                   `json:"name"`
+	Description  string                   `json:"description"`
+	Price        exportMoney              `json:"price"`
+	Prices       []exportMoney            `json:"prices"`
+	Quantity     int                      `json:"quantity"`
+	CategoryID   string                   `json:"category_id"`
+	CategoryName *string                  `json:"category_name,omitempty"`
+	Status       string                   `json:"status"`
+	PublishAt    *time.Time               `json:"publish_at,omitempty"`
+	PublishedAt  *time.Time               `json:"published_at,omitempty"`
+	ImageURL     string                   `json:"image_url"`
+	Images       []kafkaProductImage      `json:"images"`
+	Attributes   entity.ProductAttributes `json:"attributes"`
+	CreatedAt    time.Time                `json:"created_at"`
+	UpdatedAt    time.Time                `json:"updated_at"`
+}
+
+func (e *jsonLinesProductEncoder) header() error {
+	return nil
+}
+
+func (e *jsonLinesProductEncoder) encode(product entity.Product, categoryName string) error {
+	prices := make([]exportMoney, 0, len(product.Prices))
+	for _, price := range product.Prices {
+		prices = append(prices, exportMoney(price))
+	}
+
+	line := exportProduct{
+		ID:          product.ID,
+		SKU:         product.SKU,
+		Slug:        product.Slug,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       exportMoney(product.Price),
+		Prices:      prices,
+		Quantity:    product.Quantity,
+		CategoryID:  product.CategoryID,
+		Status:      string(product.Status),
+		PublishAt:   product.PublishAt,
+		PublishedAt: product.PublishedAt,
+		ImageURL:    product.ImageURL,
+		Images:      productImagesToKafkaProductImages(product.Images),
+		Attributes:  product.Attributes,
+		CreatedAt:   product.CreatedAt,
+		UpdatedAt:   product.UpdatedAt,
+	}
+	if e.includeCategory {
+		line.CategoryName = &categoryName
+	}
+
+	// Encode ends every object with a newline
+	err := e.encoder.Encode(line)
+	if err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+func (e *jsonLinesProductEncoder) flush() error {
+	flushWriter(e.w)
+	return nil
+}
+
+// flushWriter pushes buffered output out when the writer supports it, like a http response
+func flushWriter(w io.Writer) {
+	if flusher, ok := w.(interface{ Flush() }); ok {
+		flusher.Flush()
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,447 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/pkg/kafka"
+)
+
+const (
+	// _importBatchSize is the number of products written per BatchWriteItem
+	_importBatchSize = 25
+	// _importHeartbeat is how often a running job stores its progress at least, a job
+	// without progress for the stale timeout was interrupted and is failed by the sweep
+	_importHeartbeat = time.Minute
+)
+
+type ImportUseCase struct {
+	productRepoImage   ProductS3Repo
+	productRepoDynamo  ProductDynamoRepo
+	productRepoSearch  ProductSearchRepo
+	variantRepoDynamo  VariantDynamoRepo
+	categoryRepoDynamo CategoryDynamoRepo
+	priceRepoDynamo    PriceDynamoRepo
+	importRepoDynamo   ImportDynamoRepo
+	slugRepoDynamo     SlugDynamoRepo
+	promotions         *PromotionEvaluator
+	producer           *kafka.ProducerServer
+	staleAfter         time.Duration
+}
+
+func NewImportUseCase(
+	productRepoImage ProductS3Repo,
+	productRepoDynamo ProductDynamoRepo,
+	productRepoSearch ProductSearchRepo,
+	variantRepoDynamo VariantDynamoRepo,
+	categoryRepoDynamo CategoryDynamoRepo,
+	priceRepoDynamo PriceDynamoRepo,
+	importRepoDynamo ImportDynamoRepo,
+	slugRepoDynamo SlugDynamoRepo,
+	promotions *PromotionEvaluator,
+	producer *kafka.ProducerServer,
+	staleAfter time.Duration,
+) *ImportUseCase {
+	return &ImportUseCase{
+		productRepoImage:   productRepoImage,
+		productRepoDynamo:  productRepoDynamo,
+		productRepoSearch:  productRepoSearch,
+		variantRepoDynamo:  variantRepoDynamo,
+		categoryRepoDynamo: categoryRepoDynamo,
+		priceRepoDynamo:    priceRepoDynamo,
+		importRepoDynamo:   importRepoDynamo,
+		slugRepoDynamo:     slugRepoDynamo,
+		promotions:         promotions,
+		producer:           producer,
+		staleAfter:         staleAfter,
+	}
+}
+
+// StartImport checks the header of the file against the mapping, stores the file and
+// imports it in the background. The returned job is polled for the progress.
+func (u *ImportUseCase) StartImport(
+	ctx context.Context,
+	file *multipart.FileHeader,
+	mapping entity.ImportMapping,
+	actor string,
+) (*entity.ImportJob, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer src.Close()
+
+	header, err := csv.NewReader(src).Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read the header: %s", entity.ErrInvalidImportFile, err.Error())
+	}
+
+	_, err = mapping.Columns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = src.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind import file: %w", err)
+	}
+
+	job, err := entity.NewImportJob(file.Filename, actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	// the multipart file is gone once the request ends, the job reads it from s3
+	err = u.productRepoImage.SaveImportFile(ctx, job.ID, src, file.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.importRepoDynamo.SaveJob(ctx, &job)
+	if err != nil {
+		return nil, err
+	}
+
+	go u.runImport(context.Background(), job, mapping)
+
+	return &job, nil
+}
+
+func (u *ImportUseCase) GetImportJob(ctx context.Context, id string) (*entity.ImportJob, error) {
+	return u.importRepoDynamo.GetJobByID(ctx, id)
+}
+
+func (u *ImportUseCase) GetImportErrors(ctx context.Context, id string, page entity.Pagination) ([]entity.ImportRowError, string, error) {
+	_, err := u.importRepoDynamo.GetJobByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	return u.importRepoDynamo.GetRowErrors(ctx, id, page)
+}
+
+// FailStaleImports fails the jobs that reported no progress for the stale timeout, their
+// import was interrupted by a restart. It returns how many were failed.
+func (u *ImportUseCase) FailStaleImports(ctx context.Context) (int, error) {
+	now := time.Now()
+	jobs, err := u.importRepoDynamo.GetStaleJobs(ctx, now.Add(-u.staleAfter))
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for i := range jobs {
+		job := &jobs[i]
+		lastSeen := job.UpdatedAt
+		job.Status = entity.ImportFailed
+		job.Error = "the import was interrupted, the rows before the last progress were imported"
+		job.UpdatedAt = now
+		job.FinishedAt = &now
+
+		ok, err := u.importRepoDynamo.FailStaleJob(ctx, job, lastSeen)
+		if err != nil {
+			return failed, err
+		}
+		if !ok {
+			continue
+		}
+		failed++
+
+		_ = u.productRepoImage.DeleteImportFile(ctx, job.ID)
+	}
+
+	return failed, nil
+}
+
+// importRun is the state of one running import
+type importRun struct {
+	job        entity.ImportJob
+	columns    entity.ImportColumns
+	categories map[string]*entity.Category // nil when the category does not exist
+	batch      []entity.ImportRow
+	rowErrors  []entity.ImportRowError
+}
+
+// runImport streams the rows of the file, a row that fails is reported and the
+// import goes on. The job fails only when the file can not be read.
+func (u *ImportUseCase) runImport(ctx context.Context, job entity.ImportJob, mapping entity.ImportMapping) {
+	run := &importRun{
+		job:        job,
+		categories: make(map[string]*entity.Category),
+	}
+
+	err := u.readImport(ctx, run, mapping)
+	if err != nil {
+		run.job.Status = entity.ImportFailed
+		run.job.Error = err.Error()
+		// the rows of the batch are not saved, their slugs can be taken by others
+		for i := range run.batch {
+			_ = u.releaseSlug(ctx, &run.batch[i].Product, err)
+		}
+	} else {
+		run.job.Status = entity.ImportCompleted
+	}
+
+	now := time.Now()
+	run.job.UpdatedAt = now
+	run.job.FinishedAt = &now
+	// the job has nowhere to report to, the status stays running when this fails
+	_ = u.importRepoDynamo.UpdateJob(ctx, &run.job)
+
+	_ = u.productRepoImage.DeleteImportFile(ctx, run.job.ID)
+}
+
+func (u *ImportUseCase) readImport(ctx context.Context, run *importRun, mapping entity.ImportMapping) error {
+	run.job.Status = entity.ImportRunning
+	run.job.UpdatedAt = time.Now()
+	err := u.importRepoDynamo.UpdateJob(ctx, &run.job)
+	if err != nil {
+		return err
+	}
+
+	body, err := u.productRepoImage.OpenImportFile(ctx, run.job.ID)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	reader := csv.NewReader(body)
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read the header: %w", err)
+	}
+	run.columns, err = mapping.Columns(header)
+	if err != nil {
+		return err
+	}
+	// rows with more or fewer fields than the header are reported, not rejected by the reader
+	reader.FieldsPerRecord = -1
+
+	for {
+		// rows that fail do not flush, the job still shows it is alive
+		if time.Since(run.job.UpdatedAt) > _importHeartbeat {
+			run.job.UpdatedAt = time.Now()
+			err = u.importRepoDynamo.UpdateJob(ctx, &run.job)
+			if err != nil {
+				return err
+			}
+		}
+
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			run.job.TotalRows++
+			run.addError(parseErr.StartLine, "", parseErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the file: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		run.job.TotalRows++
+
+		if len(record) != len(header) {
+			run.addError(line, "", fmt.Sprintf("has %d fields, the header has %d", len(record), len(header)))
+			continue
+		}
+
+		row, err := entity.ParseImportRow(line, record, run.columns)
+		if err == nil {
+			err = u.prepareRow(ctx, run, &row)
+		}
+		if err != nil {
+			run.addRowError(line, err)
+			continue
+		}
+
+		run.batch = append(run.batch, row)
+		if len(run.batch) == _importBatchSize {
+			err = u.flushImport(ctx, run)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return u.flushImport(ctx, run)
+}
+
+// prepareRow runs the checks that need the category and completes the product
+func (u *ImportUseCase) prepareRow(ctx context.Context, run *importRun, row *entity.ImportRow) error {
+	product := &row.Product
+
+	category, ok := run.categories[product.CategoryID]
+	if !ok {
+		var err error
+		category, err = u.categoryRepoDynamo.GetByID(ctx, product.CategoryID)
+		if err != nil && !errors.Is(err, entity.ErrCategoryNotFound) {
+			return err
+		}
+		run.categories[product.CategoryID] = category
+	}
+	if category == nil {
+		return &entity.ImportFieldError{Field: entity.ImportFieldCategoryID, Message: "category not found"}
+	}
+
+	attributes, err := entity.ValidateAttributes(category.Attributes, product.Attributes)
+	if err != nil {
+		return &entity.ImportFieldError{Field: entity.ImportFieldAttributes, Message: err.Error()}
+	}
+	product.Attributes = attributes
+
+	now := time.Now()
+	err = product.InitStatus(now)
+	if err != nil {
+		return &entity.ImportFieldError{Field: entity.ImportFieldStatus, Message: err.Error()}
+	}
+
+	err = product.GenerateProductID()
+	if err != nil {
+		return err
+	}
+	product.GenerateSKU()
+
+	// the slug of a row that fails later on is given back by flushImport
+	slug, err := reserveSlug(ctx, u.slugRepoDynamo, entity.SlugProduct, product.Name, product.ID)
+	if err != nil {
+		return err
+	}
+	product.Slug = slug.slug
+	product.CreatedAt = now
+	product.UpdatedAt = now
+	product.UpdatedBy = run.job.CreatedBy
+
+	return nil
+}
+
+// flushImport uploads the images of the batch, writes the products and announces them.
+// The row errors collected so far are stored with the progress of the job.
+func (u *ImportUseCase) flushImport(ctx context.Context, run *importRun) error {
+	products := make([]*entity.Product, 0, len(run.batch))
+	rows := make([]entity.ImportRow, 0, len(run.batch))
+	for i := range run.batch {
+		row := &run.batch[i]
+		if source, ok := row.ImageSource(); ok {
+			image, err := entity.NewProductImage(row.Product.Name)
+			if err != nil {
+				run.addRowError(row.Row, u.releaseSlug(ctx, &row.Product, err))
+				continue
+			}
+
+			err = u.productRepoImage.UploadImage(ctx, row.Product.ID, &image, source)
+			if err != nil {
+				field := entity.ImportFieldImageURL
+				if row.ImageKey != "" {
+					field = entity.ImportFieldImageKey
+				}
+				run.addError(row.Row, field, u.releaseSlug(ctx, &row.Product, err).Error())
+				continue
+			}
+			row.Product.AddImage(image, true)
+		}
+
+		products = append(products, &row.Product)
+		rows = append(rows, *row)
+	}
+	run.batch = run.batch[:0]
+
+	unsaved := make(map[int]bool)
+	if len(products) > 0 {
+		// images of the rows that were not saved are orphans for the image garbage collection
+		failed, err := u.productRepoDynamo.SaveBatch(ctx, products)
+		for _, i := range failed {
+			unsaved[i] = true
+			// the failed request may have written the product anyway, it must not stay unannounced
+			discardErr := u.productRepoDynamo.Discard(ctx, products[i].ID, products[i].CategoryID)
+			if discardErr != nil {
+				run.addError(rows[i].Row, "", fmt.Sprintf("%s, product %s may exist: %s", err.Error(), products[i].ID, discardErr.Error()))
+				continue
+			}
+			run.addRowError(rows[i].Row, u.releaseSlug(ctx, products[i], err))
+		}
+	}
+
+	for i, product := range products {
+		if unsaved[i] {
+			continue
+		}
+		err := u.announceProduct(ctx, product)
+		if err != nil {
+			run.addError(rows[i].Row, "", fmt.Sprintf("product %s was saved, but %s", product.ID, err.Error()))
+			continue
+		}
+		run.job.Succeeded++
+	}
+
+	if len(run.rowErrors) > 0 {
+		err := u.importRepoDynamo.SaveRowErrors(ctx, run.rowErrors)
+		if err != nil {
+			return err
+		}
+		run.rowErrors = run.rowErrors[:0]
+	}
+
+	run.job.UpdatedAt = time.Now()
+	return u.importRepoDynamo.UpdateJob(ctx, &run.job)
+}
+
+// releaseSlug gives back the slug prepareRow reserved for a product that is not saved and
+// returns the error of the row
+func (u *ImportUseCase) releaseSlug(ctx context.Context, product *entity.Product, err error) error {
+	slug := claimedSlug{kind: entity.SlugProduct, slug: product.Slug, targetID: product.ID, fresh: true}
+	return slug.releaseOnError(ctx, u.slugRepoDynamo, err)
+}
+
+// announceProduct does what CreateProduct does once a product is saved
+func (u *ImportUseCase) announceProduct(ctx context.Context, product *entity.Product) error {
+	err := recordPrice(ctx, u.priceRepoDynamo, product, entity.Money{Currency: product.Price.Currency}, entity.PriceSourceImport, "")
+	if err != nil {
+		return fmt.Errorf("failed to record product price: %w", err)
+	}
+
+	err = indexProduct(ctx, u.productRepoSearch, product)
+	if err != nil {
+		return fmt.Errorf("failed to index product: %w", err)
+	}
+
+	// drafts and scheduled products are announced once they get published
+	if !product.IsPublished() {
+		return nil
+	}
+
+	err = produceProductCreated(ctx, u.producer, u.variantRepoDynamo, u.promotions, product)
+	if err != nil {
+		return fmt.Errorf("failed to send product data to kafka: %w", err)
+	}
+	return nil
+}
+
+func (r *importRun) addRowError(row int, err error) {
+	var fieldErr *entity.ImportFieldError
+	if errors.As(err, &fieldErr) {
+		r.addError(row, fieldErr.Field, fieldErr.Message)
+		return
+	}
+	r.addError(row, "", err.Error())
+}
+
+func (r *importRun) addError(row int, field, message string) {
+	r.job.Failed++
+	r.rowErrors = append(r.rowErrors, entity.ImportRowError{
+		JobID:   r.job.ID,
+		Row:     row,
+		Field:   field,
+		Message: message,
+	})
+}
@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+)
+
+type ProductLinkUseCase struct {
+	productLinkRepoDynamo ProductLinkDynamoRepo
+	productRepoDynamo     ProductDynamoRepo
+	productRepoSearch     ProductSearchRepo
+	promotions            *PromotionEvaluator
+}
+
+func NewProductLinkUseCase(
+	productLinkRepoDynamo ProductLinkDynamoRepo,
+	productRepoDynamo ProductDynamoRepo,
+	productRepoSearch ProductSearchRepo,
+	promotions *PromotionEvaluator,
+) *ProductLinkUseCase {
+	return &ProductLinkUseCase{
+		productLinkRepoDynamo: productLinkRepoDynamo,
+		productRepoDynamo:     productRepoDynamo,
+		productRepoSearch:     productRepoSearch,
+		promotions:            promotions,
+	}
+}
+
+// LinkProducts links the product to another one, linking them again with the same type
+// only moves the link to its new position
+func (u *ProductLinkUseCase) LinkProducts(ctx context.Context, link *entity.ProductLink) error {
+	err := link.Validate()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range []string{link.ProductID, link.LinkedProductID} {
+		_, err = u.productRepoDynamo.GetProductByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to link products: %w", err)
+		}
+	}
+
+	link.CreatedAt = time.Now()
+	err = u.productLinkRepoDynamo.Save(ctx, link)
+	if err != nil {
+		return fmt.Errorf("failed to link products: %w", err)
+	}
+
+	return nil
+}
+
+func (u *ProductLinkUseCase) UnlinkProducts(ctx context.Context, productID string, linkType entity.ProductLinkType, linkedProductID string) error {
+	return u.productLinkRepoDynamo.Delete(ctx, productID, linkType, linkedProductID)
+}
+
+// GetRelatedProducts returns the published products linked to the product, or only those
+// of the type when it is not empty. Without related links the products most similar to
+// it are returned instead, and the other products of its category when none is similar.
+func (u *ProductLinkUseCase) GetRelatedProducts(
+	ctx context.Context,
+	productID string,
+	linkType entity.ProductLinkType,
+	limit int,
+) ([]entity.RelatedProduct, error) {
+	if linkType != "" && !linkType.IsValid() {
+		return nil, fmt.Errorf("%w: unknown type %q", entity.ErrInvalidProductLink, linkType)
+	}
+	if limit <= 0 {
+		limit = entity.DefaultRelatedLimit
+	}
+
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get related products: %w", err)
+	}
+
+	related, err := u.linkedProducts(ctx, productID, linkType, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// accessories and upsells are only what a merchandiser picked
+	hasRelatedLinks := false
+	for _, r := range related {
+		hasRelatedLinks = hasRelatedLinks || r.Type == entity.ProductLinkRelated
+	}
+	if !hasRelatedLinks && (linkType == "" || linkType == entity.ProductLinkRelated) {
+		suggested, err := u.suggestedProducts(ctx, product, limit-len(related))
+		if err != nil {
+			return nil, err
+		}
+		related = append(related, suggested...)
+	}
+
+	products := make([]*entity.Product, 0, len(related))
+	for i := range related {
+		products = append(products, &related[i].Product)
+	}
+	err = u.promotions.apply(ctx, products...)
+	if err != nil {
+		return nil, err
+	}
+
+	return related, nil
+}
+
+// linkedProducts returns the published products of the links, by type and then position
+func (u *ProductLinkUseCase) linkedProducts(
+	ctx context.Context,
+	productID string,
+	linkType entity.ProductLinkType,
+	limit int,
+) ([]entity.RelatedProduct, error) {
+	links, err := u.productLinkRepoDynamo.GetByProductID(ctx, productID, linkType)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(links, func(a, b int) bool {
+		if links[a].Type != links[b].Type {
+			return links[a].Type < links[b].Type
+		}
+		return links[a].Position < links[b].Position
+	})
+
+	related := make([]entity.RelatedProduct, 0, len(links))
+	for _, link := range links {
+		if len(related) == limit {
+			break
+		}
+
+		linked, err := u.productRepoDynamo.GetProductByID(ctx, link.LinkedProductID)
+		// a linked product that was deleted stays out until the link is removed
+		if errors.Is(err, entity.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !linked.IsPublished() {
+			continue
+		}
+
+		related = append(related, entity.RelatedProduct{
+			Product: *linked,
+			Type:    link.Type,
+			Source:  entity.RelatedSourceLink,
+		})
+	}
+	return related, nil
+}
+
+// suggestedProducts returns the products similar to the product, or other products of its
+// category while the similarity index has nothing, like for a product that is not published
+func (u *ProductLinkUseCase) suggestedProducts(ctx context.Context, product *entity.Product, limit int) ([]entity.RelatedProduct, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	hits, err := u.productRepoSearch.Similar(ctx, product.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	suggested := make([]entity.RelatedProduct, 0, limit)
+	for _, hit := range hits {
+		suggested = append(suggested, entity.RelatedProduct{
+			Product: hit.Product,
+			Type:    entity.ProductLinkRelated,
+			Source:  entity.RelatedSourceSimilar,
+			Score:   hit.Score,
+		})
+	}
+	if len(suggested) > 0 {
+		return suggested, nil
+	}
+
+	// one more than the limit, the product itself may be on the page
+	products, _, err := u.productRepoDynamo.GetProductsByCategory(
+		ctx,
+		product.CategoryID,
+		entity.ProductPublished,
+		entity.Pagination{Limit: min(int32(limit)+1, entity.MaxPageLimit)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range products {
+		if len(suggested) == limit {
+			break
+		}
+		if p.ID == product.ID {
+			continue
+		}
+		suggested = append(suggested, entity.RelatedProduct{
+			Product: p,
+			Type:    entity.ProductLinkRelated,
+			Source:  entity.RelatedSourceCategory,
+		})
+	}
+	return suggested, nil
+}
@@ -0,0 +1,445 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/pkg/kafka"
+)
+
+type PromotionUseCase struct {
+	promotionRepoDynamo PromotionDynamoRepo
+	productRepoDynamo   ProductDynamoRepo
+	variantRepoDynamo   VariantDynamoRepo
+	categoryRepoDynamo  CategoryDynamoRepo
+	promotions          *PromotionEvaluator
+	producer            *kafka.ProducerServer
+}
+
+func NewPromotionUseCase(
+	promotionRepoDynamo PromotionDynamoRepo,
+	productRepoDynamo ProductDynamoRepo,
+	variantRepoDynamo VariantDynamoRepo,
+	categoryRepoDynamo CategoryDynamoRepo,
+	promotions *PromotionEvaluator,
+	producer *kafka.ProducerServer,
+) *PromotionUseCase {
+	return &PromotionUseCase{
+		promotionRepoDynamo: promotionRepoDynamo,
+		productRepoDynamo:   productRepoDynamo,
+		variantRepoDynamo:   variantRepoDynamo,
+		categoryRepoDynamo:  categoryRepoDynamo,
+		promotions:          promotions,
+		producer:            producer,
+	}
+}
+
+func (u *PromotionUseCase) CreatePromotion(ctx context.Context, promotion *entity.Promotion) (*entity.Promotion, error) {
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := promotion.GeneratePromotionID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create promotion: %w", err)
+	}
+	promotion.CreatedAt = time.Now()
+	promotion.UpdatedAt = promotion.CreatedAt
+	promotion.Announced = promotion.Phase(promotion.CreatedAt)
+
+	err = u.promotionRepoDynamo.Save(ctx, promotion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create promotion: %w", err)
+	}
+	u.promotions.Invalidate()
+
+	if promotion.Announced == entity.PromotionRunning {
+		err = u.announcePrices(ctx, promotion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to produce kafka message: %w", err)
+		}
+	}
+
+	return promotion, nil
+}
+
+func (u *PromotionUseCase) GetPromotions(ctx context.Context) ([]entity.Promotion, error) {
+	return u.promotionRepoDynamo.GetAll(ctx)
+}
+
+func (u *PromotionUseCase) GetPromotionByID(ctx context.Context, id string) (*entity.Promotion, error) {
+	return u.promotionRepoDynamo.GetByID(ctx, id)
+}
+
+func (u *PromotionUseCase) UpdatePromotion(ctx context.Context, promotion *entity.Promotion) error {
+	if err := promotion.Validate(); err != nil {
+		return err
+	}
+
+	current, err := u.promotionRepoDynamo.GetByID(ctx, promotion.ID)
+	if err != nil {
+		return err
+	}
+	promotion.CreatedAt = current.CreatedAt
+	promotion.UpdatedAt = time.Now()
+	promotion.Announced = promotion.Phase(promotion.UpdatedAt)
+
+	err = u.promotionRepoDynamo.Update(ctx, promotion)
+	if err != nil {
+		return err
+	}
+	u.promotions.Invalidate()
+
+	// the products the promotion applied to before the change get their price back
+	var changed []*entity.Promotion
+	if current.Phase(promotion.UpdatedAt) == entity.PromotionRunning {
+		changed = append(changed, current)
+	}
+	if promotion.Announced == entity.PromotionRunning {
+		changed = append(changed, promotion)
+	}
+	err = u.announcePrices(ctx, changed...)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
+}
+
+func (u *PromotionUseCase) DeletePromotion(ctx context.Context, id string) error {
+	promotion, err := u.promotionRepoDynamo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = u.promotionRepoDynamo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	u.promotions.Invalidate()
+
+	if promotion.Phase(time.Now()) == entity.PromotionRunning {
+		err = u.announcePrices(ctx, promotion)
+		if err != nil {
+			return fmt.Errorf("failed to produce kafka message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AnnouncePromotionPhases sends the sale prices of the products of the promotions that
+// started or ended since they were last announced, it returns how many promotions changed
+// phase. The phase is recorded after the prices are sent, so a failed send is tried again.
+func (u *PromotionUseCase) AnnouncePromotionPhases(ctx context.Context) (int, error) {
+	promotions, err := u.promotionRepoDynamo.GetUnannounced(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to announce promotions: %w", err)
+	}
+
+	now := time.Now()
+	announced := 0
+	for i := range promotions {
+		promotion := &promotions[i]
+		phase := promotion.Phase(now)
+		if phase == promotion.Announced {
+			continue
+		}
+
+		// a promotion that ended before it was announced as running never changed a price
+		if promotion.Announced != entity.PromotionUpcoming || phase != entity.PromotionEnded {
+			err = u.announcePrices(ctx, promotion)
+			if err != nil {
+				return announced, fmt.Errorf("failed to announce promotion %s: %w", promotion.ID, err)
+			}
+		}
+
+		err = u.promotionRepoDynamo.UpdateAnnounced(ctx, promotion, phase)
+		if err != nil {
+			return announced, err
+		}
+		announced++
+	}
+
+	return announced, nil
+}
+
+// announcePrices sends product-updated for every product the promotions target, with the
+// sale price of now
+func (u *PromotionUseCase) announcePrices(ctx context.Context, promotions ...*entity.Promotion) error {
+	products, err := u.targetedProducts(ctx, promotions)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		err = produceProductUpdated(ctx, u.producer, u.variantRepoDynamo, u.promotions, &products[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// targetedProducts returns the products the promotions target by id, by the sku of the
+// product or of a variant, or by a category or one of its descendants
+func (u *PromotionUseCase) targetedProducts(ctx context.Context, promotions []*entity.Promotion) ([]entity.Product, error) {
+	var productIDs, skus, categoryIDs []string
+	for _, promotion := range promotions {
+		for _, target := range promotion.Targets {
+			switch target.Type {
+			case entity.PromotionTargetProduct:
+				productIDs = append(productIDs, target.ID)
+			case entity.PromotionTargetSKU:
+				skus = append(skus, target.ID)
+			case entity.PromotionTargetCategory:
+				categoryIDs = append(categoryIDs, target.ID)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	var products []entity.Product
+	add := func(found []entity.Product) {
+		for _, product := range found {
+			if !seen[product.ID] {
+				seen[product.ID] = true
+				products = append(products, product)
+			}
+		}
+	}
+
+	if len(skus) > 0 {
+		found, err := u.productRepoDynamo.GetProductsBySKUs(ctx, skus)
+		if err != nil {
+			return nil, err
+		}
+		add(found)
+
+		variants, err := u.variantRepoDynamo.GetBySKUs(ctx, skus)
+		if err != nil {
+			return nil, err
+		}
+		for _, variant := range variants {
+			productIDs = append(productIDs, variant.ProductID)
+		}
+	}
+
+	for _, productID := range productIDs {
+		if seen[productID] {
+			continue
+		}
+		product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+		if err != nil {
+			// a targeted product can be deleted, it has no price to announce
+			if errors.Is(err, entity.ErrProductNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		add([]entity.Product{*product})
+	}
+
+	if len(categoryIDs) > 0 {
+		descendants, err := u.categorySubtrees(ctx, categoryIDs)
+		if err != nil {
+			return nil, err
+		}
+		found, err := u.productRepoDynamo.GetProductsByCategories(ctx, descendants, "")
+		if err != nil {
+			return nil, err
+		}
+		add(found)
+	}
+
+	return products, nil
+}
+
+// categorySubtrees returns the categories and all their descendants
+func (u *PromotionUseCase) categorySubtrees(ctx context.Context, categoryIDs []string) ([]string, error) {
+	categories, err := u.categoryRepoDynamo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[string][]string{}
+	for _, category := range *categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	seen := map[string]bool{}
+	var subtree []string
+	queue := append([]string{}, categoryIDs...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		subtree = append(subtree, id)
+		queue = append(queue, children[id]...)
+	}
+	return subtree, nil
+}
+
+// PromotionEvaluator computes the sale price of products from the running promotions.
+// Every usecase that returns or publishes products goes through it, so the http
+// responses and the kafka messages show the same price. The promotions, the category
+// tree and the variants with a targeted sku are cached for cacheTTL, writing a
+// promotion invalidates the cache.
+type PromotionEvaluator struct {
+	promotionRepoDynamo PromotionDynamoRepo
+	categoryRepoDynamo  CategoryDynamoRepo
+	variantRepoDynamo   VariantDynamoRepo
+	cacheTTL            time.Duration
+
+	mu       sync.Mutex
+	snapshot *promotionSnapshot
+}
+
+// promotionSnapshot is what applying promotions reads, the promotions are the ones that
+// had not ended when it was loaded
+type promotionSnapshot struct {
+	promotions  []entity.Promotion
+	parents     map[string]string   // category id to the id of its parent
+	variantSKUs map[string][]string // product id to the targeted skus of its variants
+	loadedAt    time.Time
+}
+
+func NewPromotionEvaluator(
+	promotionRepoDynamo PromotionDynamoRepo,
+	categoryRepoDynamo CategoryDynamoRepo,
+	variantRepoDynamo VariantDynamoRepo,
+	cacheTTL time.Duration,
+) *PromotionEvaluator {
+	return &PromotionEvaluator{
+		promotionRepoDynamo: promotionRepoDynamo,
+		categoryRepoDynamo:  categoryRepoDynamo,
+		variantRepoDynamo:   variantRepoDynamo,
+		cacheTTL:            cacheTTL,
+	}
+}
+
+// Invalidate drops the cache, the next product read loads the promotions again
+func (e *PromotionEvaluator) Invalidate() {
+	e.mu.Lock()
+	e.snapshot = nil
+	e.mu.Unlock()
+}
+
+func (e *PromotionEvaluator) apply(ctx context.Context, products ...*entity.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	snapshot, err := e.load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to apply promotions: %w", err)
+	}
+
+	now := time.Now()
+	for _, product := range products {
+		product.ApplyPromotions(
+			snapshot.promotions,
+			categoryChain(snapshot.parents, product.CategoryID),
+			snapshot.variantSKUs[product.ID],
+			now,
+		)
+	}
+
+	return nil
+}
+
+// load returns the cached snapshot, or loads it when it is missing or older than the TTL.
+// The lock is held while loading so concurrent reads wait for one load.
+func (e *PromotionEvaluator) load(ctx context.Context) (*promotionSnapshot, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if e.snapshot != nil && now.Sub(e.snapshot.loadedAt) < e.cacheTTL {
+		return e.snapshot, nil
+	}
+
+	promotions, err := e.promotionRepoDynamo.GetNotEnded(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &promotionSnapshot{promotions: promotions, loadedAt: now}
+
+	// the category tree and the variants are only loaded when a promotion needs them
+	var skus []string
+	needsCategories := false
+	for i := range promotions {
+		needsCategories = needsCategories || promotions[i].TargetsCategory()
+		for _, target := range promotions[i].Targets {
+			if target.Type == entity.PromotionTargetSKU {
+				skus = append(skus, target.ID)
+			}
+		}
+	}
+
+	if needsCategories {
+		snapshot.parents, err = e.categoryParents(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(skus) > 0 {
+		variants, err := e.variantRepoDynamo.GetBySKUs(ctx, skus)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.variantSKUs = make(map[string][]string, len(variants))
+		for _, variant := range variants {
+			snapshot.variantSKUs[variant.ProductID] = append(snapshot.variantSKUs[variant.ProductID], variant.SKU)
+		}
+	}
+
+	e.snapshot = snapshot
+	return snapshot, nil
+}
+
+func (e *PromotionEvaluator) applyAll(ctx context.Context, products []entity.Product) error {
+	pointers := make([]*entity.Product, 0, len(products))
+	for i := range products {
+		pointers = append(pointers, &products[i])
+	}
+	return e.apply(ctx, pointers...)
+}
+
+// categoryParents maps every category id to the id of its parent
+func (e *PromotionEvaluator) categoryParents(ctx context.Context) (map[string]string, error) {
+	categories, err := e.categoryRepoDynamo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[string]string, len(*categories))
+	for _, category := range *categories {
+		if category.ParentID != nil {
+			parents[category.ID] = *category.ParentID
+		} else {
+			parents[category.ID] = ""
+		}
+	}
+	return parents, nil
+}
+
+// categoryChain returns the category followed by its ancestors, the length of the
+// chain is bounded so a cycle in the tree cannot loop forever
+func categoryChain(parents map[string]string, categoryID string) []string {
+	chain := []string{categoryID}
+	for id := parents[categoryID]; id != "" && len(chain) <= len(parents); id = parents[id] {
+		chain = append(chain, id)
+	}
+	return chain
+}
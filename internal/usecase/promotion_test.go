@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCategoryChain(t *testing.T) {
+	parents := map[string]string{
+		"apparel": "",
+		"shoes":   "apparel",
+		"running": "shoes",
+		"trail":   "running",
+		"hats":    "apparel",
+	}
+	tests := []struct {
+		name       string
+		parents    map[string]string
+		categoryID string
+		want       []string
+	}{
+		{"root", parents, "apparel", []string{"apparel"}},
+		{"child", parents, "hats", []string{"hats", "apparel"}},
+		{"descendant", parents, "trail", []string{"trail", "running", "shoes", "apparel"}},
+		{"unknown category", parents, "books", []string{"books"}},
+		// a broken tree is cut after as many ancestors as there are categories
+		{"cycle", map[string]string{"a": "b", "b": "a"}, "a", []string{"a", "b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := categoryChain(tt.parents, tt.categoryID); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("categoryChain(%s) = %v, want %v", tt.categoryID, got, tt.want)
+			}
+		})
+	}
+}
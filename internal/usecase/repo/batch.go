@@ -0,0 +1,222 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+)
+
+const (
+	// BatchWriteItem accepts at most 25 items per request
+	_batchWriteSize = 25
+	// how often the unprocessed items of a batch are sent again
+	_batchWriteRetries = 5
+	_batchWriteBackoff = 100 * time.Millisecond
+	// an IN condition takes at most 100 values
+	_maxInValues = 100
+)
+
+// batchWriteItems puts the items in batches of 25. dynamodb can return part of a batch
+// as unprocessed when the table is throttled, those are sent again with a growing backoff.
+// When it fails the indexes of the items that were not written are returned with the error,
+// the items before them are written. keyNames are the key attributes of the table, they
+// match the unprocessed items to their index.
+func batchWriteItems(
+	ctx context.Context,
+	client *dynamodb.Client,
+	table string,
+	keyNames []string,
+	items []map[string]types.AttributeValue,
+) ([]int, error) {
+	for start := 0; start < len(items); start += _batchWriteSize {
+		end := start + _batchWriteSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		indexes := make(map[string]int, end-start)
+		requests := make([]types.WriteRequest, 0, end-start)
+		for i, item := range items[start:end] {
+			indexes[itemKey(item, keyNames)] = start + i
+			requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+		}
+
+		pending := map[string][]types.WriteRequest{table: requests}
+		err := writeBatch(ctx, client, table, pending)
+		if err != nil {
+			unwritten := make([]int, 0, len(pending[table])+len(items)-end)
+			for _, request := range pending[table] {
+				unwritten = append(unwritten, indexes[itemKey(request.PutRequest.Item, keyNames)])
+			}
+			for i := end; i < len(items); i++ {
+				unwritten = append(unwritten, i)
+			}
+			return unwritten, err
+		}
+	}
+
+	return nil, nil
+}
+
+// writeBatch sends the pending requests until all are processed, pending holds the
+// requests that were not written when it fails
+func writeBatch(ctx context.Context, client *dynamodb.Client, table string, pending map[string][]types.WriteRequest) error {
+	backoff := _batchWriteBackoff
+	for attempt := 0; len(pending[table]) > 0; attempt++ {
+		if attempt > _batchWriteRetries {
+			return fmt.Errorf("failed to write %d items after %d retries", len(pending[table]), _batchWriteRetries)
+		}
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		output, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+		if err != nil {
+			return fmt.Errorf("failed to batch write items: %w", err)
+		}
+		pending[table] = output.UnprocessedItems[table]
+	}
+	return nil
+}
+
+// itemKey joins the key attributes of the item into one string
+func itemKey(item map[string]types.AttributeValue, keyNames []string) string {
+	parts := make([]string, 0, len(keyNames))
+	for _, name := range keyNames {
+		switch value := item[name].(type) {
+		case *types.AttributeValueMemberS:
+			parts = append(parts, value.Value)
+		case *types.AttributeValueMemberN:
+			parts = append(parts, value.Value)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// batchDeleteItems deletes the items with the keys in batches of 25
+func batchDeleteItems(ctx context.Context, client *dynamodb.Client, table string, keys []map[string]types.AttributeValue) error {
+	for start := 0; start < len(keys); start += _batchWriteSize {
+		end := start + _batchWriteSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		requests := make([]types.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+		}
+
+		err := writeBatch(ctx, client, table, map[string][]types.WriteRequest{table: requests})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// collectKeys calls fetch until there is nothing left to read and returns the keys of the items
+func collectKeys(ctx context.Context, fetch pageFetcher, keyNames []string) ([]map[string]types.AttributeValue, error) {
+	var keys []map[string]types.AttributeValue
+	var startKey map[string]types.AttributeValue
+	for {
+		items, lastKey, err := fetch(ctx, startKey, entity.MaxPageLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			key := make(map[string]types.AttributeValue, len(keyNames))
+			for _, name := range keyNames {
+				key[name] = item[name]
+			}
+			keys = append(keys, key)
+		}
+
+		if len(lastKey) == 0 {
+			break
+		}
+		startKey = lastKey
+	}
+
+	return keys, nil
+}
+
+// queryPartition reads every item of the partition
+func queryPartition(client *dynamodb.Client, table, keyName, keyValue string) pageFetcher {
+	return func(
+		ctx context.Context,
+		startKey map[string]types.AttributeValue,
+		limit int32,
+	) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		result, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(table),
+			KeyConditionExpression: aws.String("#key = :key"),
+			ExpressionAttributeNames: map[string]string{
+				"#key": keyName,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":key": &types.AttributeValueMemberS{Value: keyValue},
+			},
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	}
+}
+
+// scanIn returns the items that are not deleted whose attribute is one of the values,
+// the values are matched in chunks of 100
+func scanIn(ctx context.Context, client *dynamodb.Client, table, attribute string, values []string) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	for start := 0; start < len(values); start += _maxInValues {
+		end := start + _maxInValues
+		if end > len(values) {
+			end = len(values)
+		}
+
+		placeholders := make([]string, 0, end-start)
+		attributeValues := make(map[string]types.AttributeValue, end-start)
+		for i, value := range values[start:end] {
+			placeholder := fmt.Sprintf(":v%d", i)
+			placeholders = append(placeholders, placeholder)
+			attributeValues[placeholder] = &types.AttributeValueMemberS{Value: value}
+		}
+
+		var startKey map[string]types.AttributeValue
+		for {
+			result, err := client.Scan(ctx, &dynamodb.ScanInput{
+				TableName:                 aws.String(table),
+				FilterExpression:          aws.String("attribute_not_exists(deleted_at) AND #attr IN (" + strings.Join(placeholders, ", ") + ")"),
+				ExpressionAttributeNames:  map[string]string{"#attr": attribute},
+				ExpressionAttributeValues: attributeValues,
+				ExclusiveStartKey:         startKey,
+			})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, result.Items...)
+
+			if len(result.LastEvaluatedKey) == 0 {
+				break
+			}
+			startKey = result.LastEvaluatedKey
+		}
+	}
+
+	return items, nil
+}
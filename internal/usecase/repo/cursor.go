@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+)
+
+// cursorValue is the json form of a key attribute, only S and N are used as keys
+type cursorValue struct {
+	S *string `json:"s,omitempty"`
+	N *string `json:"n,omitempty"`
+}
+
+// encodeCursor turns the LastEvaluatedKey of a scan/query into an opaque cursor
+func encodeCursor(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	values := make(map[string]cursorValue, len(key))
+	for name, attr := range key {
+		switch v := attr.(type) {
+		case *types.AttributeValueMemberS:
+			values[name] = cursorValue{S: &v.Value}
+		case *types.AttributeValueMemberN:
+			values[name] = cursorValue{N: &v.Value}
+		default:
+			return "", fmt.Errorf("unsupported key attribute type for %s", name)
+		}
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor turns a cursor back into an ExclusiveStartKey
+func decodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, entity.ErrInvalidCursor
+	}
+
+	var values map[string]cursorValue
+	if err := json.Unmarshal(data, &values); err != nil || len(values) == 0 {
+		return nil, entity.ErrInvalidCursor
+	}
+
+	key := make(map[string]types.AttributeValue, len(values))
+	for name, v := range values {
+		switch {
+		case v.S != nil:
+			key[name] = &types.AttributeValueMemberS{Value: *v.S}
+		case v.N != nil:
+			key[name] = &types.AttributeValueMemberN{Value: *v.N}
+		default:
+			return nil, entity.ErrInvalidCursor
+		}
+	}
+
+	return key, nil
+}
+
+type pageFetcher func(
+	ctx context.Context,
+	startKey map[string]types.AttributeValue,
+	limit int32,
+) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error)
+
+// collectPage calls fetch until the page is full or there is nothing left to read.
+// dynamodb applies Limit before the filter expression, so a single call can return
+// fewer items than asked even when more matching items exist.
+func collectPage(ctx context.Context, page entity.Pagination, fetch pageFetcher) ([]map[string]types.AttributeValue, string, error) {
+	startKey, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]map[string]types.AttributeValue, 0, page.Limit)
+	for {
+		result, lastKey, err := fetch(ctx, startKey, page.Limit-int32(len(items)))
+		if err != nil {
+			return nil, "", err
+		}
+
+		items = append(items, result...)
+		startKey = lastKey
+
+		if len(startKey) == 0 || int32(len(items)) >= page.Limit {
+			break
+		}
+	}
+
+	nextCursor, err := encodeCursor(startKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return items, nextCursor, nil
+}
@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+)
+
+// BundleDynamoRepo indexes the bundles by their components, with component_id as
+// partition key and bundle_id as sort key. The bundle itself is stored on the product.
+type BundleDynamoRepo struct {
+	*awsService.DynamoDB
+}
+
+func NewBundleDynamoRepo(d *awsService.DynamoDB) *BundleDynamoRepo {
+	return &BundleDynamoRepo{
+		d,
+	}
+}
+
+func (r *BundleDynamoRepo) SaveComponents(ctx context.Context, bundleID string, components []entity.BundleComponent) error {
+	items := make([]map[string]types.AttributeValue, 0, len(components))
+	for _, component := range components {
+		items = append(items, map[string]types.AttributeValue{
+			"component_id": &types.AttributeValueMemberS{Value: component.ProductID},
+			"bundle_id":    &types.AttributeValueMemberS{Value: bundleID},
+			"quantity":     &types.AttributeValueMemberN{Value: strconv.Itoa(component.Quantity)},
+		})
+	}
+
+	_, err := batchWriteItems(ctx, r.Client, r.BundleComponentTable, []string{"component_id", "bundle_id"}, items)
+	if err != nil {
+		return fmt.Errorf("failed to save bundle components: %w", err)
+	}
+	return nil
+}
+
+func (r *BundleDynamoRepo) DeleteComponents(ctx context.Context, bundleID string, componentIDs []string) error {
+	for _, componentID := range componentIDs {
+		_, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(r.BundleComponentTable),
+			Key: map[string]types.AttributeValue{
+				"component_id": &types.AttributeValueMemberS{Value: componentID},
+				"bundle_id":    &types.AttributeValueMemberS{Value: bundleID},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete bundle component: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetBundleIDs returns the ids of the bundles the product is a component of
+func (r *BundleDynamoRepo) GetBundleIDs(ctx context.Context, componentID string) ([]string, error) {
+	bundleIDs := []string{}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.BundleComponentTable),
+			KeyConditionExpression: aws.String("component_id = :component_id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":component_id": &types.AttributeValueMemberS{Value: componentID},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query bundle components: %w", err)
+		}
+
+		for _, item := range result.Items {
+			bundleIDs = append(bundleIDs, stringAttr(item, "bundle_id"))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return bundleIDs, nil
+}
+
+func bundleToAttributeValue(bundle *entity.Bundle) *types.AttributeValueMemberM {
+	components := make([]types.AttributeValue, 0, len(bundle.Components))
+	for _, component := range bundle.Components {
+		components = append(components, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"product_id": &types.AttributeValueMemberS{Value: component.ProductID},
+			"quantity":   &types.AttributeValueMemberN{Value: strconv.Itoa(component.Quantity)},
+		}})
+	}
+
+	return &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"components":       &types.AttributeValueMemberL{Value: components},
+		"pricing":          &types.AttributeValueMemberS{Value: string(bundle.Pricing)},
+		"discount_percent": &types.AttributeValueMemberN{Value: strconv.FormatFloat(bundle.DiscountPercent, 'f', -1, 64)},
+	}}
+}
+
+func bundleFromItem(item map[string]types.AttributeValue) *entity.Bundle {
+	m, ok := item["bundle"].(*types.AttributeValueMemberM)
+	if !ok {
+		return nil
+	}
+
+	bundle := &entity.Bundle{
+		Pricing: entity.BundlePricing(stringAttr(m.Value, "pricing")),
+	}
+	if discount, err := strconv.ParseFloat(numberAttr(m.Value, "discount_percent"), 64); err == nil {
+		bundle.DiscountPercent = discount
+	}
+
+	if list, ok := m.Value["components"].(*types.AttributeValueMemberL); ok {
+		for _, value := range list.Value {
+			fields, ok := value.(*types.AttributeValueMemberM)
+			if !ok {
+				continue
+			}
+			component := entity.BundleComponent{ProductID: stringAttr(fields.Value, "product_id")}
+			if quantity, err := strconv.Atoi(numberAttr(fields.Value, "quantity")); err == nil {
+				component.Quantity = quantity
+			}
+			bundle.Components = append(bundle.Components, component)
+		}
+	}
+	return bundle
+}
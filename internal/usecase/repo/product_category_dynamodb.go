@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -30,10 +31,19 @@ func (r *CategoryDynamoRepo) Save(ctx context.Context, category *entity.Category
 			"id":         &types.AttributeValueMemberS{Value: category.ID},
 			"name":       &types.AttributeValueMemberS{Value: category.Name},
 			"parent_id":  &types.AttributeValueMemberS{Value: *category.ParentID},
+			"attributes": attributeDefinitionsToAttributeValue(category.Attributes),
+			"version":    &types.AttributeValueMemberN{Value: strconv.FormatInt(max(category.Version, 1), 10)},
 			"created_at": &types.AttributeValueMemberS{Value: category.CreatedAt.String()},
 			"updated_at": &types.AttributeValueMemberS{Value: category.UpdatedAt.String()},
 		},
 	}
+	// the slug is the key of the slug-index GSI, which does not take empty strings
+	if category.Slug != "" {
+		input.Item["slug"] = &types.AttributeValueMemberS{Value: category.Slug}
+	}
+	if len(category.Translations) > 0 {
+		input.Item["translations"] = categoryTranslationsToAttributeValue(category.Translations)
+	}
 
 	_, err := r.Client.PutItem(ctx, input)
 	if err != nil {
@@ -55,22 +65,56 @@ func (r *CategoryDynamoRepo) GetAll(ctx context.Context) (*[]entity.Category, er
 
 	categories := make([]entity.Category, 0, len(result.Items))
 	for _, item := range result.Items {
-		category := entity.Category{}
+		categories = append(categories, categoryFromItem(item))
+	}
 
-		category.ID = item["id"].(*types.AttributeValueMemberS).Value
-		category.Name = item["name"].(*types.AttributeValueMemberS).Value
-		category.ParentID = &item["parent_id"].(*types.AttributeValueMemberS).Value
-		if createdAt, err := time.Parse(time.RFC3339, item["created_at"].(*types.AttributeValueMemberS).Value); err == nil {
-			category.CreatedAt = createdAt
-		}
-		if updatedAt, err := time.Parse(time.RFC3339, item["updated_at"].(*types.AttributeValueMemberS).Value); err == nil {
-			category.UpdatedAt = updatedAt
-		}
+	return &categories, nil
+}
+
+func (r *CategoryDynamoRepo) GetByID(ctx context.Context, id string) (*entity.Category, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(r.CategoryTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	}
 
-		categories = append(categories, category)
+	result, err := r.Client.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
 	}
 
-	return &categories, nil
+	if result.Item == nil || result.Item["deleted_at"] != nil {
+		return nil, fmt.Errorf("%w, id: %s", entity.ErrCategoryNotFound, id)
+	}
+
+	category := categoryFromItem(result.Item)
+	return &category, nil
+}
+
+// GetBySlug finds the category that currently has the slug with the slug-index GSI
+func (r *CategoryDynamoRepo) GetBySlug(ctx context.Context, slug string) (*entity.Category, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.CategoryTable),
+		IndexName:              aws.String("slug-index"),
+		KeyConditionExpression: aws.String("slug = :slug"),
+		FilterExpression:       aws.String("attribute_not_exists(deleted_at)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":slug": &types.AttributeValueMemberS{Value: slug},
+		},
+	}
+
+	result, err := r.Client.Query(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category by slug: %w", err)
+	}
+
+	if len(result.Items) == 0 {
+		return nil, fmt.Errorf("%w, slug: %s", entity.ErrCategoryNotFound, slug)
+	}
+
+	category := categoryFromItem(result.Items[0])
+	return &category, nil
 }
 
 func (r *CategoryDynamoRepo) GetByParentID(ctx context.Context, parentID string) (*[]entity.Category, error) {
@@ -93,52 +137,91 @@ func (r *CategoryDynamoRepo) GetByParentID(ctx context.Context, parentID string)
 
 	categories := make([]entity.Category, 0, len(result.Items))
 	for _, item := range result.Items {
-		category := entity.Category{}
-
-		category.ID = item["id"].(*types.AttributeValueMemberS).Value
-		category.Name = item["name"].(*types.AttributeValueMemberS).Value
-		category.ParentID = &item["parent_id"].(*types.AttributeValueMemberS).Value
-		if createdAt, err := time.Parse(time.RFC3339, item["created_at"].(*types.AttributeValueMemberS).Value); err == nil {
-			category.CreatedAt = createdAt
-		}
-		if updatedAt, err := time.Parse(time.RFC3339, item["updated_at"].(*types.AttributeValueMemberS).Value); err == nil {
-			category.UpdatedAt = updatedAt
-		}
-
-		categories = append(categories, category)
+		categories = append(categories, categoryFromItem(item))
 	}
 
 	return &categories, nil
 }
 
+// Update stores the category, a version set on it has to be the one stored so concurrent
+// edits can not overwrite each other. The category gets the version it has now.
 func (r *CategoryDynamoRepo) Update(ctx context.Context, category *entity.Category) error {
+	updateExpression := "SET #name = :name, updated_at = :updated_at, " + _versionIncrement
+	expressionAttributeValues := map[string]types.AttributeValue{
+		":name":       &types.AttributeValueMemberS{Value: category.Name},
+		":updated_at": &types.AttributeValueMemberS{Value: category.UpdatedAt.Format(time.RFC3339)},
+	}
+	versionValues(expressionAttributeValues, category.Version)
+
+	if category.Slug != "" {
+		updateExpression += ", slug = :slug"
+		expressionAttributeValues[":slug"] = &types.AttributeValueMemberS{Value: category.Slug}
+	}
+
+	// the schema is only replaced when a new one is given
+	if category.Attributes != nil {
+		updateExpression += ", attributes = :attributes"
+		expressionAttributeValues[":attributes"] = attributeDefinitionsToAttributeValue(category.Attributes)
+	}
+
 	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(r.CategoryTable),
 		Key: map[string]types.AttributeValue{
 			"id": &types.AttributeValueMemberS{Value: category.ID},
 		},
-		UpdateExpression: aws.String(
-			"SET #name = :name, " +
-				"updated_at = :updated_at",
-		),
+		UpdateExpression: aws.String(updateExpression),
 		ExpressionAttributeNames: map[string]string{
 			"#name": "name",
 		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":name":       &types.AttributeValueMemberS{Value: category.Name},
-			":updated_at": &types.AttributeValueMemberS{Value: category.UpdatedAt.Format(time.RFC3339)},
-		},
-		ConditionExpression: aws.String("attribute_not_exists(deleted_at)"),
+		ExpressionAttributeValues:           expressionAttributeValues,
+		ConditionExpression:                 aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)" + versionCondition(category.Version)),
+		ReturnValues:                        types.ReturnValueUpdatedNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 	}
 
-	_, err := r.Client.UpdateItem(ctx, input)
+	result, err := r.Client.UpdateItem(ctx, input)
 	if err != nil {
 		var ccf *types.ConditionalCheckFailedException
 		if ok := errors.As(err, &ccf); ok {
-			return fmt.Errorf("category not found or has been deleted, id: %s", category.ID)
+			if ccf.Item == nil || ccf.Item["deleted_at"] != nil {
+				return fmt.Errorf("%w, id: %s", entity.ErrCategoryNotFound, category.ID)
+			}
+			return &entity.VersionMismatchError{Current: versionFromItem(ccf.Item)}
 		}
 		return fmt.Errorf("failed to category product: %w", err)
 	}
+
+	category.Version = versionFromItem(result.Attributes)
+	return nil
+}
+
+// UpdateTranslations replaces the translations of a category
+func (r *CategoryDynamoRepo) UpdateTranslations(ctx context.Context, category *entity.Category) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.CategoryTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: category.ID},
+		},
+		UpdateExpression: aws.String("SET translations = :translations, updated_at = :updated_at, " + _versionIncrement),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":translations": categoryTranslationsToAttributeValue(category.Translations),
+			":updated_at":   &types.AttributeValueMemberS{Value: category.UpdatedAt.Format(time.RFC3339)},
+			":version_one":  &types.AttributeValueMemberN{Value: "1"},
+		},
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
+		ReturnValues:        types.ReturnValueUpdatedNew,
+	}
+
+	result, err := r.Client.UpdateItem(ctx, input)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w, id: %s", entity.ErrCategoryNotFound, category.ID)
+		}
+		return fmt.Errorf("failed to update category translations: %w", err)
+	}
+
+	category.Version = versionFromItem(result.Attributes)
 	return nil
 }
 
@@ -166,3 +249,180 @@ func (r *CategoryDynamoRepo) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// GetDeleted returns every soft-deleted category
+func (r *CategoryDynamoRepo) GetDeleted(ctx context.Context) ([]entity.Category, error) {
+	var categories []entity.Category
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         aws.String(r.CategoryTable),
+			FilterExpression:  aws.String("attribute_exists(deleted_at)"),
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deleted categories: %w", err)
+		}
+
+		for _, item := range result.Items {
+			categories = append(categories, categoryFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return categories, nil
+}
+
+func (r *CategoryDynamoRepo) GetDeletedByID(ctx context.Context, id string) (*entity.Category, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(r.CategoryTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	}
+
+	result, err := r.Client.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted category: %w", err)
+	}
+
+	if result.Item == nil || result.Item["deleted_at"] == nil {
+		return nil, fmt.Errorf("%w in trash, id: %s", entity.ErrCategoryNotFound, id)
+	}
+
+	category := categoryFromItem(result.Item)
+	return &category, nil
+}
+
+// Restore takes the category out of the trash
+func (r *CategoryDynamoRepo) Restore(ctx context.Context, category *entity.Category) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.CategoryTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: category.ID},
+		},
+		UpdateExpression: aws.String("SET updated_at = :updated_at REMOVE deleted_at"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":updated_at": &types.AttributeValueMemberS{Value: category.UpdatedAt.Format(time.RFC3339)},
+		},
+		ConditionExpression: aws.String("attribute_exists(deleted_at)"),
+	}
+
+	_, err := r.Client.UpdateItem(ctx, input)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w in trash, id: %s", entity.ErrCategoryNotFound, category.ID)
+		}
+		return fmt.Errorf("failed to restore category: %w", err)
+	}
+
+	return nil
+}
+
+// Purge removes a soft-deleted category for good, a category restored in the
+// meantime is kept
+func (r *CategoryDynamoRepo) Purge(ctx context.Context, id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.CategoryTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConditionExpression: aws.String("attribute_exists(deleted_at)"),
+	}
+
+	_, err := r.Client.DeleteItem(ctx, input)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w in trash, id: %s", entity.ErrCategoryNotFound, id)
+		}
+		return fmt.Errorf("failed to purge category: %w", err)
+	}
+
+	return nil
+}
+
+func categoryFromItem(item map[string]types.AttributeValue) entity.Category {
+	category := entity.Category{}
+
+	category.ID = stringAttr(item, "id")
+	category.Name = stringAttr(item, "name")
+	category.Slug = stringAttr(item, "slug")
+	if parentID, ok := item["parent_id"].(*types.AttributeValueMemberS); ok {
+		category.ParentID = &parentID.Value
+	}
+	if createdAt, err := time.Parse(time.RFC3339, stringAttr(item, "created_at")); err == nil {
+		category.CreatedAt = createdAt
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, stringAttr(item, "updated_at")); err == nil {
+		category.UpdatedAt = updatedAt
+	}
+	if deletedAt, err := time.Parse(time.RFC3339, stringAttr(item, "deleted_at")); err == nil {
+		category.DeletedAt = &deletedAt
+	}
+
+	category.Version = versionFromItem(item)
+	category.Attributes = attributeDefinitionsFromAttributeValue(item["attributes"])
+	category.Translations = categoryTranslationsFromItem(item)
+
+	return category
+}
+
+func attributeDefinitionsFromAttributeValue(value types.AttributeValue) []entity.AttributeDefinition {
+	list, ok := value.(*types.AttributeValueMemberL)
+	if !ok {
+		return []entity.AttributeDefinition{}
+	}
+
+	definitions := make([]entity.AttributeDefinition, 0, len(list.Value))
+	for _, v := range list.Value {
+		m, ok := v.(*types.AttributeValueMemberM)
+		if !ok {
+			continue
+		}
+
+		definition := entity.AttributeDefinition{
+			Name:          stringAttr(m.Value, "name"),
+			Type:          entity.AttributeType(stringAttr(m.Value, "type")),
+			Unit:          stringAttr(m.Value, "unit"),
+			AllowedValues: []string{},
+		}
+		if required, ok := m.Value["required"].(*types.AttributeValueMemberBOOL); ok {
+			definition.Required = required.Value
+		}
+		if allowed, ok := m.Value["allowed_values"].(*types.AttributeValueMemberL); ok {
+			for _, a := range allowed.Value {
+				if s, ok := a.(*types.AttributeValueMemberS); ok {
+					definition.AllowedValues = append(definition.AllowedValues, s.Value)
+				}
+			}
+		}
+
+		definitions = append(definitions, definition)
+	}
+	return definitions
+}
+
+func attributeDefinitionsToAttributeValue(definitions []entity.AttributeDefinition) *types.AttributeValueMemberL {
+	list := make([]types.AttributeValue, 0, len(definitions))
+	for _, definition := range definitions {
+		allowed := make([]types.AttributeValue, 0, len(definition.AllowedValues))
+		for _, a := range definition.AllowedValues {
+			allowed = append(allowed, &types.AttributeValueMemberS{Value: a})
+		}
+
+		list = append(list, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"name":           &types.AttributeValueMemberS{Value: definition.Name},
+			"type":           &types.AttributeValueMemberS{Value: string(definition.Type)},
+			"required":       &types.AttributeValueMemberBOOL{Value: definition.Required},
+			"allowed_values": &types.AttributeValueMemberL{Value: allowed},
+			"unit":           &types.AttributeValueMemberS{Value: definition.Unit},
+		}})
+	}
+	return &types.AttributeValueMemberL{Value: list}
+}
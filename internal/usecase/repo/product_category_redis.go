@@ -2,7 +2,9 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/idoyudha/eshop-product/internal/entity"
 	rClient "github.com/idoyudha/eshop-product/pkg/redis"
@@ -35,8 +37,9 @@ func (r *CategoryRedisRepo) SaveAll(ctx context.Context, categories *[]entity.Ca
 		// store category data in hash
 		categoryKey := categoryKeyPrefix + category.ID
 
-		categoryData := map[string]interface{}{
-			"name": category.Name,
+		categoryData, err := categoryToHash(category)
+		if err != nil {
+			return err
 		}
 
 		if category.ParentID != nil {
@@ -87,8 +90,12 @@ func (r *CategoryRedisRepo) GetAll(ctx context.Context) (*[]entity.Category, err
 		}
 
 		category := entity.Category{
-			ID:   id,
-			Name: data["name"],
+			ID:           id,
+			Name:         data["name"],
+			Slug:         data["slug"],
+			Attributes:   attributesFromHash(data),
+			Translations: translationsFromHash(data),
+			Version:      versionFromHash(data),
 		}
 
 		if parentID, exists := data["parent_id"]; exists {
@@ -112,8 +119,12 @@ func (r *CategoryRedisRepo) GetByID(ctx context.Context, id string) (*entity.Cat
 	}
 
 	category := &entity.Category{
-		ID:   id,
-		Name: data["name"],
+		ID:           id,
+		Name:         data["name"],
+		Slug:         data["slug"],
+		Attributes:   attributesFromHash(data),
+		Translations: translationsFromHash(data),
+		Version:      versionFromHash(data),
 	}
 
 	if parentID, ok := data["parent_id"]; ok && parentID != "" {
@@ -149,8 +160,12 @@ func (r *CategoryRedisRepo) GetByParentID(ctx context.Context, parentID string)
 		}
 
 		category := entity.Category{
-			ID:   id,
-			Name: data["name"],
+			ID:           id,
+			Name:         data["name"],
+			Slug:         data["slug"],
+			Attributes:   attributesFromHash(data),
+			Translations: translationsFromHash(data),
+			Version:      versionFromHash(data),
 		}
 		category.ParentID = &parentID
 
@@ -164,8 +179,9 @@ func (r *CategoryRedisRepo) Add(ctx context.Context, category *entity.Category)
 	pipe := r.Client.Pipeline()
 
 	categoryKey := categoryKeyPrefix + category.ID
-	categoryData := map[string]interface{}{
-		"name": category.Name,
+	categoryData, err := categoryToHash(*category)
+	if err != nil {
+		return err
 	}
 
 	if category.ParentID != nil {
@@ -176,7 +192,7 @@ func (r *CategoryRedisRepo) Add(ctx context.Context, category *entity.Category)
 	pipe.HSet(ctx, categoryKey, categoryData)
 	pipe.SAdd(ctx, categorySetKey, category.ID)
 
-	_, err := pipe.Exec(ctx)
+	_, err = pipe.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to add category: %w", err)
 	}
@@ -184,15 +200,39 @@ func (r *CategoryRedisRepo) Add(ctx context.Context, category *entity.Category)
 	return nil
 }
 
-func (r *CategoryRedisRepo) Update(ctx context.Context, id string, newName string) error {
-	categoryKey := categoryKeyPrefix + id
+func (r *CategoryRedisRepo) Update(ctx context.Context, category *entity.Category) error {
+	categoryKey := categoryKeyPrefix + category.ID
 
 	pipe := r.Client.Pipeline()
-	pipe.HSet(ctx, categoryKey, "name", newName)
+	pipe.HSet(ctx, categoryKey, "name", category.Name)
+	if category.Version > 0 {
+		pipe.HSet(ctx, categoryKey, "version", category.Version)
+	}
+	if category.Slug != "" {
+		pipe.HSet(ctx, categoryKey, "slug", category.Slug)
+	}
+
+	// like in dynamo the schema is only replaced when a new one is given
+	if category.Attributes != nil {
+		attributes, err := json.Marshal(category.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to encode category attributes: %w", err)
+		}
+		pipe.HSet(ctx, categoryKey, "attributes", string(attributes))
+	}
+
+	// the translations are only replaced when given, like the schema
+	if category.Translations != nil {
+		translations, err := json.Marshal(category.Translations)
+		if err != nil {
+			return fmt.Errorf("failed to encode category translations: %w", err)
+		}
+		pipe.HSet(ctx, categoryKey, "translations", string(translations))
+	}
 
 	_, err := pipe.Exec(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update category name: %w", err)
+		return fmt.Errorf("failed to update category: %w", err)
 	}
 
 	return nil
@@ -224,3 +264,62 @@ func (r *CategoryRedisRepo) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func categoryToHash(category entity.Category) (map[string]interface{}, error) {
+	attributes := category.Attributes
+	if attributes == nil {
+		attributes = []entity.AttributeDefinition{}
+	}
+
+	encoded, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode category attributes: %w", err)
+	}
+
+	translations := category.Translations
+	if translations == nil {
+		translations = map[string]entity.CategoryTranslation{}
+	}
+
+	encodedTranslations, err := json.Marshal(translations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode category translations: %w", err)
+	}
+
+	return map[string]interface{}{
+		"name":         category.Name,
+		"slug":         category.Slug,
+		"version":      max(category.Version, 1),
+		"attributes":   string(encoded),
+		"translations": string(encodedTranslations),
+	}, nil
+}
+
+// attributesFromHash decodes the attribute schema, categories cached before
+// there were schemas have none
+func attributesFromHash(data map[string]string) []entity.AttributeDefinition {
+	attributes := []entity.AttributeDefinition{}
+	if encoded, ok := data["attributes"]; ok {
+		_ = json.Unmarshal([]byte(encoded), &attributes)
+	}
+	return attributes
+}
+
+// versionFromHash reads the version, categories cached before there were versions are version 1
+func versionFromHash(data map[string]string) int64 {
+	version, err := strconv.ParseInt(data["version"], 10, 64)
+	if err != nil {
+		return 1
+	}
+	return version
+}
+
+// translationsFromHash decodes the translations, categories cached before there
+// were translations have none
+func translationsFromHash(data map[string]string) map[string]entity.CategoryTranslation {
+	translations := map[string]entity.CategoryTranslation{}
+	if encoded, ok := data["translations"]; ok {
+		_ = json.Unmarshal([]byte(encoded), &translations)
+	}
+	return translations
+}
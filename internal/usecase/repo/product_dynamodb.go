@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,6 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
 	"github.com/idoyudha/eshop-product/internal/entity"
 	awsService "github.com/idoyudha/eshop-product/pkg/aws"
 )
@@ -29,18 +31,7 @@ func NewProductDynamoDBRepo(d *awsService.DynamoDB) *ProductDynamoRepo {
 func (r *ProductDynamoRepo) Save(ctx context.Context, product *entity.Product) error {
 	input := &dynamodb.PutItemInput{
 		TableName: aws.String(r.ProductTable),
-		Item: map[string]types.AttributeValue{
-			"id":          &types.AttributeValueMemberS{Value: product.ID},
-			"sku":         &types.AttributeValueMemberS{Value: product.SKU},
-			"name":        &types.AttributeValueMemberS{Value: product.Name},
-			"image_url":   &types.AttributeValueMemberS{Value: product.ImageURL},
-			"description": &types.AttributeValueMemberS{Value: product.Description},
-			"price":       &types.AttributeValueMemberN{Value: strconv.FormatFloat(product.Price, 'f', -1, 64)},
-			"quantity":    &types.AttributeValueMemberN{Value: strconv.Itoa(product.Quantity)},
-			"category_id": &types.AttributeValueMemberS{Value: product.CategoryID},
-			"created_at":  &types.AttributeValueMemberS{Value: product.CreatedAt.String()},
-			"updated_at":  &types.AttributeValueMemberS{Value: product.UpdatedAt.String()},
-		},
+		Item:      productToItem(product),
 	}
 
 	_, err := r.Client.PutItem(ctx, input)
@@ -51,49 +42,254 @@ func (r *ProductDynamoRepo) Save(ctx context.Context, product *entity.Product) e
 	return nil
 }
 
-func (r *ProductDynamoRepo) GetProducts(ctx context.Context) (*[]entity.Product, error) {
-	input := &dynamodb.ScanInput{
-		TableName:        aws.String(r.ProductTable),
-		FilterExpression: aws.String("attribute_not_exists(deleted_at)"),
+// SaveBatch writes new products with BatchWriteItem, 25 per request. When it fails the
+// indexes of the products that were not saved are returned with the error, the others
+// are saved. A request that failed may still have written some of its products.
+func (r *ProductDynamoRepo) SaveBatch(ctx context.Context, products []*entity.Product) ([]int, error) {
+	items := make([]map[string]types.AttributeValue, 0, len(products))
+	for _, product := range products {
+		items = append(items, productToItem(product))
 	}
 
-	result, err := r.Client.Scan(ctx, input)
+	unsaved, err := batchWriteItems(ctx, r.Client, r.ProductTable, []string{"id", "category_id"}, items)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan products: %w", err)
+		return unsaved, fmt.Errorf("failed to save products: %w", err)
 	}
+	return nil, nil
+}
 
-	products := make([]entity.Product, 0, len(result.Items))
-	for _, item := range result.Items {
-		product := entity.Product{}
+// Discard deletes a product that was never announced, like one a failed batch may have written
+func (r *ProductDynamoRepo) Discard(ctx context.Context, productID string, categoryID string) error {
+	_, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.ProductTable),
+		Key:       productKey(productID, categoryID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to discard product: %w", err)
+	}
+	return nil
+}
 
-		product.ID = item["id"].(*types.AttributeValueMemberS).Value
-		product.SKU = item["sku"].(*types.AttributeValueMemberS).Value
-		product.Name = item["name"].(*types.AttributeValueMemberS).Value
+// _catalogPartition is the value of the catalog attribute every product has, it is the partition
+// key of the catalog-<field>-index GSIs that sort the listing across all categories
+const _catalogPartition = "product"
+
+func productToItem(product *entity.Product) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"id":          &types.AttributeValueMemberS{Value: product.ID},
+		"sku":         &types.AttributeValueMemberS{Value: product.SKU},
+		"name":        &types.AttributeValueMemberS{Value: product.Name},
+		"image_url":   &types.AttributeValueMemberS{Value: product.ImageURL},
+		"description": &types.AttributeValueMemberS{Value: product.Description},
+		"price":       moneyToAttributeValue(product.Price),
+		"currency":    &types.AttributeValueMemberS{Value: product.Price.Currency},
+		"prices":      priceListToAttributeValue(product.Prices),
+		"quantity":    &types.AttributeValueMemberN{Value: strconv.Itoa(product.Quantity)},
+		"version":     &types.AttributeValueMemberN{Value: strconv.FormatInt(max(product.Version, 1), 10)},
+		"category_id": &types.AttributeValueMemberS{Value: product.CategoryID},
+		"created_at":  &types.AttributeValueMemberS{Value: product.CreatedAt.Format(time.RFC3339)},
+		"updated_at":  &types.AttributeValueMemberS{Value: product.UpdatedAt.Format(time.RFC3339)},
+		"updated_by":  &types.AttributeValueMemberS{Value: product.UpdatedBy},
+		"images":      imagesToAttributeValue(product.Images),
+		"attributes":  productAttributesToAttributeValue(product.Attributes),
+		"status":      &types.AttributeValueMemberS{Value: string(product.Status)},
+		"catalog":     &types.AttributeValueMemberS{Value: _catalogPartition},
+	}
+	if len(product.Translations) > 0 {
+		item["translations"] = productTranslationsToAttributeValue(product.Translations)
+	}
+	if product.Type != "" {
+		item["type"] = &types.AttributeValueMemberS{Value: string(product.Type)}
+	}
+	if product.Bundle != nil {
+		item["bundle"] = bundleToAttributeValue(product.Bundle)
+	}
+	// the slug is the key of the slug-index GSI, which does not take empty strings
+	if product.Slug != "" {
+		item["slug"] = &types.AttributeValueMemberS{Value: product.Slug}
+	}
+	if product.PublishAt != nil {
+		item["publish_at"] = &types.AttributeValueMemberS{Value: sortableTime(*product.PublishAt)}
+	}
+	if product.PublishedAt != nil {
+		item["published_at"] = &types.AttributeValueMemberS{Value: product.PublishedAt.Format(time.RFC3339)}
+	}
+	if product.ReorderThreshold != nil {
+		item["reorder_threshold"] = &types.AttributeValueMemberN{Value: strconv.Itoa(*product.ReorderThreshold)}
+	}
+	return item
+}
 
-		if imgURL, ok := item["image_url"]; ok {
-			product.ImageURL = imgURL.(*types.AttributeValueMemberS).Value
+// GetProducts scans the table, or queries a category GSI when the filter has a category.
+// Sorting uses the category_id-<field>-index GSI, where the field is the sort key, and the
+// catalog-<field>-index GSI when the listing is not narrowed to a category.
+func (r *ProductDynamoRepo) GetProducts(
+	ctx context.Context,
+	filter entity.ProductFilter,
+	sort entity.ProductSort,
+	page entity.Pagination,
+) (*[]entity.Product, string, error) {
+	expr := buildProductFilterExpression(filter, sort)
+
+	var indexName string
+	switch {
+	case filter.CategoryID != "" && sort.IsSet():
+		indexName = fmt.Sprintf("category_id-%s-index", sort.Field)
+	case filter.CategoryID != "":
+		indexName = "category_id-index"
+	case sort.IsSet():
+		indexName = fmt.Sprintf("catalog-%s-index", sort.Field)
+	}
+
+	var fetch pageFetcher
+	if indexName == "" {
+		fetch = func(
+			ctx context.Context,
+			startKey map[string]types.AttributeValue,
+			limit int32,
+		) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+			result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+				TableName:                 aws.String(r.ProductTable),
+				FilterExpression:          aws.String(expr.filter),
+				ExpressionAttributeNames:  expr.names,
+				ExpressionAttributeValues: expr.values,
+				ExclusiveStartKey:         startKey,
+				Limit:                     aws.Int32(limit),
+			})
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to scan products: %w", err)
+			}
+			return result.Items, result.LastEvaluatedKey, nil
+		}
+	} else {
+		fetch = func(
+			ctx context.Context,
+			startKey map[string]types.AttributeValue,
+			limit int32,
+		) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+			result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+				TableName:                 aws.String(r.ProductTable),
+				IndexName:                 aws.String(indexName),
+				KeyConditionExpression:    aws.String(expr.keyCondition),
+				FilterExpression:          aws.String(expr.filter),
+				ExpressionAttributeNames:  expr.names,
+				ExpressionAttributeValues: expr.values,
+				ScanIndexForward:          aws.Bool(!sort.Desc),
+				ExclusiveStartKey:         startKey,
+				Limit:                     aws.Int32(limit),
+			})
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to query products: %w", err)
+			}
+			return result.Items, result.LastEvaluatedKey, nil
 		}
+	}
 
-		product.Description = item["description"].(*types.AttributeValueMemberS).Value
-		product.CategoryID = item["category_id"].(*types.AttributeValueMemberS).Value
+	items, nextCursor, err := collectPage(ctx, page, fetch)
+	if err != nil {
+		return nil, "", err
+	}
+
+	products := make([]entity.Product, 0, len(items))
+	for _, item := range items {
+		products = append(products, productFromItem(item))
+	}
+	return &products, nextCursor, nil
+}
+
+type productExpression struct {
+	keyCondition string
+	filter       string
+	names        map[string]string
+	values       map[string]types.AttributeValue
+}
+
+// buildProductFilterExpression splits the filter into the key condition and the filter
+// expression, the sort key of a GSI is not allowed in the filter expression.
+func buildProductFilterExpression(filter entity.ProductFilter, sort entity.ProductSort) productExpression {
+	expr := productExpression{
+		names:  map[string]string{},
+		values: map[string]types.AttributeValue{},
+	}
+
+	var keyParts []string
+	filterParts := []string{"attribute_not_exists(deleted_at)"}
+
+	if filter.CategoryID != "" {
+		keyParts = append(keyParts, "category_id = :category_id")
+		expr.values[":category_id"] = &types.AttributeValueMemberS{Value: filter.CategoryID}
+	} else if sort.IsSet() {
+		keyParts = append(keyParts, "catalog = :catalog")
+		expr.values[":catalog"] = &types.AttributeValueMemberS{Value: _catalogPartition}
+	}
+
+	if filter.Name != "" {
+		expr.names["#name"] = "name"
+		expr.values[":name"] = &types.AttributeValueMemberS{Value: filter.Name}
+		filterParts = append(filterParts, "contains(#name, :name)")
+	}
 
-		if price, err := strconv.ParseFloat(item["price"].(*types.AttributeValueMemberN).Value, 64); err == nil {
-			product.Price = price
+	if filter.MinPrice != nil || filter.MaxPrice != nil {
+		expr.names["#price"] = "price"
+
+		var priceCondition string
+		switch {
+		case filter.MinPrice != nil && filter.MaxPrice != nil:
+			priceCondition = "#price BETWEEN :min_price AND :max_price"
+		case filter.MinPrice != nil:
+			priceCondition = "#price >= :min_price"
+		default:
+			priceCondition = "#price <= :max_price"
 		}
-		if quantity, err := strconv.Atoi(item["quantity"].(*types.AttributeValueMemberN).Value); err == nil {
-			product.Quantity = quantity
+		if filter.MinPrice != nil {
+			expr.values[":min_price"] = moneyToAttributeValue(*filter.MinPrice)
+		}
+		if filter.MaxPrice != nil {
+			expr.values[":max_price"] = moneyToAttributeValue(*filter.MaxPrice)
 		}
 
-		if createdAt, err := time.Parse(time.RFC3339, item["created_at"].(*types.AttributeValueMemberS).Value); err == nil {
-			product.CreatedAt = createdAt
+		// prices in different currencies are not comparable
+		expr.names["#currency"] = "currency"
+		expr.values[":currency"] = &types.AttributeValueMemberS{Value: filter.PriceCurrency()}
+		if filter.PriceCurrency() == entity.DefaultCurrency {
+			filterParts = append(filterParts, "(#currency = :currency OR attribute_not_exists(#currency))")
+		} else {
+			filterParts = append(filterParts, "#currency = :currency")
 		}
-		if updatedAt, err := time.Parse(time.RFC3339, item["updated_at"].(*types.AttributeValueMemberS).Value); err == nil {
-			product.UpdatedAt = updatedAt
+
+		if sort.Field == entity.ProductSortPrice {
+			keyParts = append(keyParts, priceCondition)
+		} else {
+			filterParts = append(filterParts, priceCondition)
 		}
+	}
 
-		products = append(products, product)
+	if filter.Status != "" {
+		filterParts = append(filterParts, statusCondition(expr.names, expr.values, filter.Status))
 	}
-	return &products, nil
+
+	if filter.InStock != nil {
+		expr.names["#quantity"] = "quantity"
+		expr.values[":zero"] = &types.AttributeValueMemberN{Value: "0"}
+		if *filter.InStock {
+			filterParts = append(filterParts, "#quantity > :zero")
+		} else {
+			filterParts = append(filterParts, "#quantity <= :zero")
+		}
+	}
+
+	expr.keyCondition = strings.Join(keyParts, " AND ")
+	expr.filter = strings.Join(filterParts, " AND ")
+
+	// dynamodb rejects empty maps in the request
+	if len(expr.names) == 0 {
+		expr.names = nil
+	}
+	if len(expr.values) == 0 {
+		expr.values = nil
+	}
+
+	return expr
 }
 
 func (r *ProductDynamoRepo) GetProductByID(ctx context.Context, id string) (*entity.Product, error) {
@@ -112,95 +308,117 @@ func (r *ProductDynamoRepo) GetProductByID(ctx context.Context, id string) (*ent
 	}
 
 	if len(result.Items) == 0 {
-		return nil, fmt.Errorf("product not found with id: %s", id)
-	}
-
-	item := result.Items[0]
-
-	product := &entity.Product{}
-	product.ID = id
-	product.SKU = item["sku"].(*types.AttributeValueMemberS).Value
-	product.Name = item["name"].(*types.AttributeValueMemberS).Value
-	product.Description = item["description"].(*types.AttributeValueMemberS).Value
-
-	if imgURL, ok := item["image_url"]; ok {
-		product.ImageURL = imgURL.(*types.AttributeValueMemberS).Value
-	}
-
-	product.CategoryID = item["category_id"].(*types.AttributeValueMemberS).Value
-
-	if price, err := strconv.ParseFloat(item["price"].(*types.AttributeValueMemberN).Value, 64); err == nil {
-		product.Price = price
-	}
-	if quantity, err := strconv.Atoi(item["quantity"].(*types.AttributeValueMemberN).Value); err == nil {
-		product.Quantity = quantity
+		return nil, fmt.Errorf("%w, id: %s", entity.ErrProductNotFound, id)
 	}
 
-	if createdAt, err := time.Parse(time.RFC3339, item["created_at"].(*types.AttributeValueMemberS).Value); err == nil {
-		product.CreatedAt = createdAt
-	}
-	if updatedAt, err := time.Parse(time.RFC3339, item["updated_at"].(*types.AttributeValueMemberS).Value); err == nil {
-		product.UpdatedAt = updatedAt
-	}
-
-	return product, nil
+	product := productFromItem(result.Items[0])
+	return &product, nil
 }
 
-func (r *ProductDynamoRepo) GetProductsByCategory(ctx context.Context, categoryID string) ([]entity.Product, error) {
+// GetProductBySlug finds the product that currently has the slug with the slug-index GSI
+func (r *ProductDynamoRepo) GetProductBySlug(ctx context.Context, slug string) (*entity.Product, error) {
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String(r.ProductTable),
-		IndexName:              aws.String("category_id-index"),
-		KeyConditionExpression: aws.String("category_id = :category_id"),
+		IndexName:              aws.String("slug-index"),
+		KeyConditionExpression: aws.String("slug = :slug"),
 		FilterExpression:       aws.String("attribute_not_exists(deleted_at)"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":category_id": &types.AttributeValueMemberS{Value: categoryID},
+			":slug": &types.AttributeValueMemberS{Value: slug},
 		},
 	}
 
 	result, err := r.Client.Query(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query products by category: %w", err)
+		return nil, fmt.Errorf("failed to get product by slug: %w", err)
 	}
 
 	if len(result.Items) == 0 {
-		return []entity.Product{}, nil
+		return nil, fmt.Errorf("%w, slug: %s", entity.ErrProductNotFound, slug)
 	}
 
-	products := make([]entity.Product, 0, len(result.Items))
-	for _, item := range result.Items {
-		product := entity.Product{}
+	product := productFromItem(result.Items[0])
+	return &product, nil
+}
 
-		product.ID = item["id"].(*types.AttributeValueMemberS).Value
-		product.CategoryID = categoryID
-		product.SKU = item["sku"].(*types.AttributeValueMemberS).Value
-		product.Name = item["name"].(*types.AttributeValueMemberS).Value
-		product.Description = item["description"].(*types.AttributeValueMemberS).Value
+// GetProductsByCategory returns a page of the products of the category, an empty status means any status
+func (r *ProductDynamoRepo) GetProductsByCategory(
+	ctx context.Context,
+	categoryID string,
+	status entity.ProductStatus,
+	page entity.Pagination,
+) ([]entity.Product, string, error) {
+	items, nextCursor, err := collectPage(ctx, page, r.queryByCategory(categoryID, status))
+	if err != nil {
+		return nil, "", err
+	}
 
-		if price, err := strconv.ParseFloat(item["price"].(*types.AttributeValueMemberN).Value, 64); err == nil {
-			product.Price = price
-		}
-		if quantity, err := strconv.Atoi(item["quantity"].(*types.AttributeValueMemberN).Value); err == nil {
-			product.Quantity = quantity
-		}
+	products := make([]entity.Product, 0, len(items))
+	for _, item := range items {
+		products = append(products, productFromItem(item))
+	}
+	return products, nextCursor, nil
+}
 
-		if createdAt, err := time.Parse(time.RFC3339, item["created_at"].(*types.AttributeValueMemberS).Value); err == nil {
-			product.CreatedAt = createdAt
-		}
-		if updatedAt, err := time.Parse(time.RFC3339, item["updated_at"].(*types.AttributeValueMemberS).Value); err == nil {
-			product.UpdatedAt = updatedAt
+// getAllProductsByCategory follows the continuation keys until the whole category is read
+func (r *ProductDynamoRepo) getAllProductsByCategory(ctx context.Context, categoryID string, status entity.ProductStatus) ([]entity.Product, error) {
+	fetch := r.queryByCategory(categoryID, status)
+
+	var products []entity.Product
+	var startKey map[string]types.AttributeValue
+	for {
+		items, lastKey, err := fetch(ctx, startKey, entity.MaxPageLimit)
+		if err != nil {
+			return nil, err
 		}
 
-		if imgURL, ok := item["image_url"]; ok {
-			product.ImageURL = imgURL.(*types.AttributeValueMemberS).Value
+		for _, item := range items {
+			products = append(products, productFromItem(item))
 		}
 
-		products = append(products, product)
+		if len(lastKey) == 0 {
+			break
+		}
+		startKey = lastKey
 	}
 
 	return products, nil
 }
 
-func (r *ProductDynamoRepo) GetProductsByCategories(ctx context.Context, categoryIDs []string) ([]entity.Product, error) {
+func (r *ProductDynamoRepo) queryByCategory(categoryID string, status entity.ProductStatus) pageFetcher {
+	filter := "attribute_not_exists(deleted_at)"
+	var names map[string]string
+	values := map[string]types.AttributeValue{
+		":category_id": &types.AttributeValueMemberS{Value: categoryID},
+	}
+	if status != "" {
+		names = map[string]string{}
+		filter += " AND " + statusCondition(names, values, status)
+	}
+
+	return func(
+		ctx context.Context,
+		startKey map[string]types.AttributeValue,
+		limit int32,
+	) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(r.ProductTable),
+			IndexName:                 aws.String("category_id-index"),
+			KeyConditionExpression:    aws.String("category_id = :category_id"),
+			FilterExpression:          aws.String(filter),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			ExclusiveStartKey:         startKey,
+			Limit:                     aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query products by category: %w", err)
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	}
+}
+
+// GetProductsByCategories returns every product of the categories, an empty status means any status
+func (r *ProductDynamoRepo) GetProductsByCategories(ctx context.Context, categoryIDs []string, status entity.ProductStatus) ([]entity.Product, error) {
 	var wg sync.WaitGroup
 	resultsChan := make(chan []entity.Product, len(categoryIDs))
 	errorsChan := make(chan error, len(categoryIDs))
@@ -210,7 +428,7 @@ func (r *ProductDynamoRepo) GetProductsByCategories(ctx context.Context, categor
 		go func(catID string) {
 			defer wg.Done()
 
-			products, err := r.GetProductsByCategory(ctx, catID)
+			products, err := r.getAllProductsByCategory(ctx, catID, status)
 			if err != nil {
 				errorsChan <- fmt.Errorf("error fetching products for category %s: %w", catID, err)
 				return
@@ -244,6 +462,48 @@ func (r *ProductDynamoRepo) GetProductsByCategories(ctx context.Context, categor
 	return allProducts, nil
 }
 
+// GetProductsBySKUs returns the products with one of the skus
+func (r *ProductDynamoRepo) GetProductsBySKUs(ctx context.Context, skus []string) ([]entity.Product, error) {
+	items, err := scanIn(ctx, r.Client, r.ProductTable, "sku", skus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan products by sku: %w", err)
+	}
+
+	products := make([]entity.Product, 0, len(items))
+	for _, item := range items {
+		products = append(products, productFromItem(item))
+	}
+	return products, nil
+}
+
+// GetAllProductImages reads the images of every product, soft-deleted ones included.
+// Only the keys, the images and deleted_at are read.
+func (r *ProductDynamoRepo) GetAllProductImages(ctx context.Context) ([]entity.Product, error) {
+	var products []entity.Product
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:            aws.String(r.ProductTable),
+			ProjectionExpression: aws.String("id, category_id, image_url, images, deleted_at"),
+			ExclusiveStartKey:    startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product images: %w", err)
+		}
+
+		for _, item := range result.Items {
+			products = append(products, productFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return products, nil
+}
+
 func (r *ProductDynamoRepo) Update(ctx context.Context, product *entity.Product) error {
 	var updateParts []string
 	expAttrNames := map[string]string{
@@ -261,6 +521,11 @@ func (r *ProductDynamoRepo) Update(ctx context.Context, product *entity.Product)
 		updateParts = append(updateParts, "#name = :name")
 		expAttrValues[":name"] = &types.AttributeValueMemberS{Value: product.Name}
 	}
+	if product.Slug != "" {
+		updateParts = append(updateParts, "#slug = :slug")
+		expAttrNames["#slug"] = "slug"
+		expAttrValues[":slug"] = &types.AttributeValueMemberS{Value: product.Slug}
+	}
 	if product.ImageURL != "" {
 		updateParts = append(updateParts, "#image_url = :image_url")
 		expAttrValues[":image_url"] = &types.AttributeValueMemberS{Value: product.ImageURL}
@@ -269,13 +534,47 @@ func (r *ProductDynamoRepo) Update(ctx context.Context, product *entity.Product)
 		updateParts = append(updateParts, "#description = :description")
 		expAttrValues[":description"] = &types.AttributeValueMemberS{Value: product.Description}
 	}
-	if product.Price > 0 {
-		updateParts = append(updateParts, "#price = :price")
-		expAttrValues[":price"] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(product.Price, 'f', 2, 64)}
+	if product.Price.Amount > 0 {
+		updateParts = append(updateParts, "#price = :price", "#currency = :currency")
+		expAttrNames["#currency"] = "currency"
+		expAttrValues[":price"] = moneyToAttributeValue(product.Price)
+		expAttrValues[":currency"] = &types.AttributeValueMemberS{Value: product.Price.Currency}
+	}
+	if product.Prices != nil {
+		updateParts = append(updateParts, "#prices = :prices")
+		expAttrNames["#prices"] = "prices"
+		expAttrValues[":prices"] = priceListToAttributeValue(product.Prices)
+	}
+	if product.Images != nil {
+		updateParts = append(updateParts, "#images = :images")
+		expAttrNames["#images"] = "images"
+		expAttrValues[":images"] = imagesToAttributeValue(product.Images)
+	}
+	if product.Attributes != nil {
+		updateParts = append(updateParts, "#attributes = :attributes")
+		expAttrNames["#attributes"] = "attributes"
+		expAttrValues[":attributes"] = productAttributesToAttributeValue(product.Attributes)
+	}
+	if product.ReorderThreshold != nil {
+		updateParts = append(updateParts, "#reorder_threshold = :reorder_threshold")
+		expAttrNames["#reorder_threshold"] = "reorder_threshold"
+		expAttrValues[":reorder_threshold"] = &types.AttributeValueMemberN{Value: strconv.Itoa(*product.ReorderThreshold)}
 	}
 
-	updateParts = append(updateParts, "#updated_at = :updated_at")
+	if product.UpdatedBy != "" {
+		updateParts = append(updateParts, "#updated_by = :updated_by")
+		expAttrNames["#updated_by"] = "updated_by"
+		expAttrValues[":updated_by"] = &types.AttributeValueMemberS{Value: product.UpdatedBy}
+	}
+
+	// products saved before the catalog attribute get it with their next update
+	updateParts = append(updateParts, "#catalog = :catalog")
+	expAttrNames["#catalog"] = "catalog"
+	expAttrValues[":catalog"] = &types.AttributeValueMemberS{Value: _catalogPartition}
+
+	updateParts = append(updateParts, "#updated_at = :updated_at", _versionIncrement)
 	expAttrValues[":updated_at"] = &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)}
+	versionValues(expAttrValues, product.Version)
 
 	updateExpression := "SET " + strings.Join(updateParts, ", ")
 
@@ -288,32 +587,115 @@ func (r *ProductDynamoRepo) Update(ctx context.Context, product *entity.Product)
 		UpdateExpression:          aws.String(updateExpression),
 		ExpressionAttributeNames:  expAttrNames,
 		ExpressionAttributeValues: expAttrValues,
-		ConditionExpression:       aws.String("attribute_exists(#id) AND attribute_exists(#category_id)"),
+		// a version set on the product has to be the one stored, so concurrent edits can not overwrite each other
+		ConditionExpression:                 aws.String("attribute_exists(#id) AND attribute_exists(#category_id) AND attribute_not_exists(deleted_at)" + versionCondition(product.Version)),
+		ReturnValues:                        types.ReturnValueUpdatedNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 	}
 
-	// First, let's verify if the item exists
-	getInput := &dynamodb.GetItemInput{
+	result, err := r.Client.UpdateItem(ctx, input)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			if ccf.Item == nil || ccf.Item["deleted_at"] != nil {
+				return fmt.Errorf("%w, id: %s", entity.ErrProductNotFound, product.ID)
+			}
+			return &entity.VersionMismatchError{Current: versionFromItem(ccf.Item)}
+		}
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+
+	product.Version = versionFromItem(result.Attributes)
+	return nil
+}
+
+// UpdateImages replaces the gallery and the primary image url of a product
+func (r *ProductDynamoRepo) UpdateImages(ctx context.Context, product *entity.Product) error {
+	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(r.ProductTable),
 		Key: map[string]types.AttributeValue{
 			"id":          &types.AttributeValueMemberS{Value: product.ID},
 			"category_id": &types.AttributeValueMemberS{Value: product.CategoryID},
 		},
+		UpdateExpression: aws.String("SET images = :images, image_url = :image_url, updated_at = :updated_at, " + _versionIncrement),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":images":      imagesToAttributeValue(product.Images),
+			":image_url":   &types.AttributeValueMemberS{Value: product.ImageURL},
+			":updated_at":  &types.AttributeValueMemberS{Value: product.UpdatedAt.Format(time.RFC3339)},
+			":version_one": &types.AttributeValueMemberN{Value: "1"},
+		},
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
 	}
 
-	// Check if item exists before updating
-	result, err := r.Client.GetItem(ctx, getInput)
+	_, err := r.Client.UpdateItem(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to check if product exists: %w", err)
+		var ccf *types.ConditionalCheckFailedException
+		if ok := errors.As(err, &ccf); ok {
+			return fmt.Errorf("product not found or has been deleted, id: %s", product.ID)
+		}
+		return fmt.Errorf("failed to update product images: %w", err)
 	}
 
-	if result.Item == nil {
-		return fmt.Errorf("product with ID %s and category ID %s not found", product.ID, product.CategoryID)
+	return nil
+}
+
+// UpdateTranslations replaces the translations of a product
+func (r *ProductDynamoRepo) UpdateTranslations(ctx context.Context, product *entity.Product) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.ProductTable),
+		Key: map[string]types.AttributeValue{
+			"id":          &types.AttributeValueMemberS{Value: product.ID},
+			"category_id": &types.AttributeValueMemberS{Value: product.CategoryID},
+		},
+		UpdateExpression: aws.String("SET translations = :translations, updated_at = :updated_at, updated_by = :updated_by, " + _versionIncrement),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":translations": productTranslationsToAttributeValue(product.Translations),
+			":updated_at":   &types.AttributeValueMemberS{Value: product.UpdatedAt.Format(time.RFC3339)},
+			":updated_by":   &types.AttributeValueMemberS{Value: product.UpdatedBy},
+			":version_one":  &types.AttributeValueMemberN{Value: "1"},
+		},
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
 	}
 
-	// If item exists, proceed with update
-	_, err = r.Client.UpdateItem(ctx, input)
+	_, err := r.Client.UpdateItem(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to update product: %w", err)
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w, id: %s", entity.ErrProductNotFound, product.ID)
+		}
+		return fmt.Errorf("failed to update product translations: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateBundle stores the components of a bundle with the price and stock derived from them
+func (r *ProductDynamoRepo) UpdateBundle(ctx context.Context, product *entity.Product) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.ProductTable),
+		Key: map[string]types.AttributeValue{
+			"id":          &types.AttributeValueMemberS{Value: product.ID},
+			"category_id": &types.AttributeValueMemberS{Value: product.CategoryID},
+		},
+		UpdateExpression: aws.String("SET bundle = :bundle, price = :price, quantity = :quantity, updated_at = :updated_at, updated_by = :updated_by, " + _versionIncrement),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":bundle":      bundleToAttributeValue(product.Bundle),
+			":price":       moneyToAttributeValue(product.Price),
+			":quantity":    &types.AttributeValueMemberN{Value: strconv.Itoa(product.Quantity)},
+			":updated_at":  &types.AttributeValueMemberS{Value: product.UpdatedAt.Format(time.RFC3339)},
+			":updated_by":  &types.AttributeValueMemberS{Value: product.UpdatedBy},
+			":version_one": &types.AttributeValueMemberN{Value: "1"},
+		},
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
+	}
+
+	_, err := r.Client.UpdateItem(ctx, input)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w, id: %s", entity.ErrProductNotFound, product.ID)
+		}
+		return fmt.Errorf("failed to update product bundle: %w", err)
 	}
 
 	return nil
@@ -343,28 +725,214 @@ func (r *ProductDynamoRepo) GetCategoryByProductId(ctx context.Context, productI
 	return &categoryID, nil
 }
 
-func (r *ProductDynamoRepo) UpdateProductQty(ctx context.Context, productID, categoryID string, quantity int) error {
+// UpdateProductQty stores the stock on hand of a product, the quantity left available is
+// what is not reserved for open orders. It returns the available quantity.
+func (r *ProductDynamoRepo) UpdateProductQty(ctx context.Context, productID, categoryID string, quantity int) (int, error) {
 	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(r.ProductTable),
 		Key: map[string]types.AttributeValue{
 			"id":          &types.AttributeValueMemberS{Value: productID},
 			"category_id": &types.AttributeValueMemberS{Value: categoryID},
 		},
-		UpdateExpression: aws.String("SET quantity = :quantity"),
+		UpdateExpression: aws.String("SET quantity = :quantity - if_not_exists(reserved, :zero)"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":quantity": &types.AttributeValueMemberN{Value: strconv.Itoa(quantity)},
+			":zero":     &types.AttributeValueMemberN{Value: "0"},
 		},
 		ConditionExpression: aws.String("attribute_exists(id)"),
+		ReturnValues:        types.ReturnValueUpdatedNew,
+	}
+
+	result, err := r.Client.UpdateItem(ctx, input)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update product quantity: %w", err)
+	}
+
+	available, err := strconv.Atoi(numberAttr(result.Attributes, "quantity"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read product quantity: %w", err)
+	}
+	return available, nil
+}
+
+// UpdateStockAlert stores the alert state of the product if it still has the state
+// previous, so a change of stock is alerted once even when it is evaluated twice.
+// ErrStockConflict is returned when the state changed meanwhile. An empty state removes
+// the attribute, so it reads back as never alerted.
+func (r *ProductDynamoRepo) UpdateStockAlert(ctx context.Context, product *entity.Product, previous entity.StockAlert) error {
+	values := map[string]types.AttributeValue{}
+	update := "REMOVE stock_alert"
+	if product.StockAlert != (entity.StockAlert{}) {
+		update = "SET stock_alert = :stock_alert"
+		values[":stock_alert"] = stockAlertToAttributeValue(product.StockAlert)
+	}
+	condition := "attribute_exists(id) AND attribute_not_exists(stock_alert)"
+	if previous != (entity.StockAlert{}) {
+		condition = "attribute_exists(id) AND stock_alert = :previous"
+		values[":previous"] = stockAlertToAttributeValue(previous)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName:           aws.String(r.ProductTable),
+		Key:                 productKey(product.ID, product.CategoryID),
+		UpdateExpression:    aws.String(update),
+		ConditionExpression: aws.String(condition),
+	}
+	// dynamodb rejects an empty map of values
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
 	}
 
 	_, err := r.Client.UpdateItem(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to update product quantity: %w", err)
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w, product: %s", entity.ErrStockConflict, product.ID)
+		}
+		return fmt.Errorf("failed to update product stock alert: %w", err)
+	}
+
+	return nil
+}
+
+// GetLowStock returns a page of the products whose available quantity is at or below their
+// reorder threshold, the default threshold for products without one
+func (r *ProductDynamoRepo) GetLowStock(ctx context.Context, defaultThreshold int, page entity.Pagination) ([]entity.Product, string, error) {
+	fetch := func(
+		ctx context.Context,
+		startKey map[string]types.AttributeValue,
+		limit int32,
+	) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+			TableName: aws.String(r.ProductTable),
+			FilterExpression: aws.String("attribute_not_exists(deleted_at) AND " +
+				"(quantity <= reorder_threshold OR (attribute_not_exists(reorder_threshold) AND quantity <= :threshold))"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":threshold": &types.AttributeValueMemberN{Value: strconv.Itoa(defaultThreshold)},
+			},
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan low stock products: %w", err)
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	}
+
+	items, nextCursor, err := collectPage(ctx, page, fetch)
+	if err != nil {
+		return nil, "", err
+	}
+
+	products := make([]entity.Product, 0, len(items))
+	for _, item := range items {
+		products = append(products, productFromItem(item))
+	}
+	return products, nextCursor, nil
+}
+
+// UpdateStatus stores the status of the product if it still has the status from,
+// so two concurrent transitions can not both succeed
+func (r *ProductDynamoRepo) UpdateStatus(ctx context.Context, product *entity.Product, from entity.ProductStatus) error {
+	names := map[string]string{}
+	values := map[string]types.AttributeValue{
+		":to":         &types.AttributeValueMemberS{Value: string(product.Status)},
+		":updated_at": &types.AttributeValueMemberS{Value: product.UpdatedAt.Format(time.RFC3339)},
+		":updated_by": &types.AttributeValueMemberS{Value: product.UpdatedBy},
+	}
+
+	setParts := []string{"#status = :to", "updated_at = :updated_at", "updated_by = :updated_by", _versionIncrement}
+	versionValues(values, 0)
+	var removeParts []string
+	if product.PublishAt != nil {
+		setParts = append(setParts, "publish_at = :publish_at")
+		values[":publish_at"] = &types.AttributeValueMemberS{Value: sortableTime(*product.PublishAt)}
+	} else {
+		removeParts = append(removeParts, "publish_at")
+	}
+	if product.PublishedAt != nil {
+		setParts = append(setParts, "published_at = :published_at")
+		values[":published_at"] = &types.AttributeValueMemberS{Value: product.PublishedAt.Format(time.RFC3339)}
+	}
+
+	updateExpression := "SET " + strings.Join(setParts, ", ")
+	if len(removeParts) > 0 {
+		updateExpression += " REMOVE " + strings.Join(removeParts, ", ")
+	}
+
+	condition := "attribute_exists(id) AND attribute_not_exists(deleted_at) AND " + statusCondition(names, values, from)
+
+	_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.ProductTable),
+		Key: map[string]types.AttributeValue{
+			"id":          &types.AttributeValueMemberS{Value: product.ID},
+			"category_id": &types.AttributeValueMemberS{Value: product.CategoryID},
+		},
+		UpdateExpression:          aws.String(updateExpression),
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w, id: %s", entity.ErrProductStatusChanged, product.ID)
+		}
+		return fmt.Errorf("failed to update product status: %w", err)
 	}
 
 	return nil
 }
 
+// GetDueScheduledProducts returns the scheduled products whose publish_at is before the given time.
+// It queries the sparse status-publish_at-index GSI, only scheduled products have publish_at.
+func (r *ProductDynamoRepo) GetDueScheduledProducts(ctx context.Context, before time.Time) ([]entity.Product, error) {
+	products := []entity.Product{}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.ProductTable),
+			IndexName:              aws.String("status-publish_at-index"),
+			KeyConditionExpression: aws.String("#status = :status AND publish_at <= :before"),
+			FilterExpression:       aws.String("attribute_not_exists(deleted_at)"),
+			ExpressionAttributeNames: map[string]string{
+				"#status": "status",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":status": &types.AttributeValueMemberS{Value: string(entity.ProductScheduled)},
+				":before": &types.AttributeValueMemberS{Value: sortableTime(before)},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query due scheduled products: %w", err)
+		}
+
+		for _, item := range result.Items {
+			products = append(products, productFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return products, nil
+}
+
+// statusCondition matches the status, products stored before the lifecycle have
+// no status and count as published
+func statusCondition(names map[string]string, values map[string]types.AttributeValue, status entity.ProductStatus) string {
+	names["#status"] = "status"
+	values[":status"] = &types.AttributeValueMemberS{Value: string(status)}
+	if status == entity.ProductPublished {
+		return "(#status = :status OR attribute_not_exists(#status))"
+	}
+	return "#status = :status"
+}
+
 func (r *ProductDynamoRepo) Delete(ctx context.Context, productID string, categoryID string) error {
 	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(r.ProductTable),
@@ -376,17 +944,514 @@ func (r *ProductDynamoRepo) Delete(ctx context.Context, productID string, catego
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":deleted_at": &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
 		},
-		ConditionExpression: aws.String("attribute_not_exists(deleted_at)"),
+		// without attribute_exists an unknown id or category would be upserted as a deleted product
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
 	}
 
 	_, err := r.Client.UpdateItem(ctx, input)
 	if err != nil {
 		var ccf *types.ConditionalCheckFailedException
 		if ok := errors.As(err, &ccf); ok {
-			return fmt.Errorf("product not found or already deleted, id: %s", productID)
+			return fmt.Errorf("%w, id: %s", entity.ErrProductNotFound, productID)
 		}
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
 
 	return nil
 }
+
+// BackfillCatalog sets the catalog attribute on the products saved before it existed, so
+// they show up in the sorted listing across all categories. It returns how many it set.
+func (r *ProductDynamoRepo) BackfillCatalog(ctx context.Context) (int, error) {
+	backfilled := 0
+
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:            aws.String(r.ProductTable),
+			ProjectionExpression: aws.String("id, category_id"),
+			FilterExpression:     aws.String("attribute_not_exists(catalog)"),
+			ExclusiveStartKey:    startKey,
+		})
+		if err != nil {
+			return backfilled, fmt.Errorf("failed to scan products without catalog: %w", err)
+		}
+
+		for _, item := range result.Items {
+			_, err = r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:           aws.String(r.ProductTable),
+				Key:                 productKey(stringAttr(item, "id"), stringAttr(item, "category_id")),
+				UpdateExpression:    aws.String("SET catalog = :catalog"),
+				ConditionExpression: aws.String("attribute_exists(id)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":catalog": &types.AttributeValueMemberS{Value: _catalogPartition},
+				},
+			})
+			if err != nil {
+				var ccf *types.ConditionalCheckFailedException
+				// discarded since the scan
+				if errors.As(err, &ccf) {
+					continue
+				}
+				return backfilled, fmt.Errorf("failed to backfill product catalog: %w", err)
+			}
+			backfilled++
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return backfilled, nil
+}
+
+// HasProducts tells whether the category has any product, a soft-deleted one counts too
+func (r *ProductDynamoRepo) HasProducts(ctx context.Context, categoryID string) (bool, error) {
+	result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.ProductTable),
+		IndexName:              aws.String("category_id-index"),
+		KeyConditionExpression: aws.String("category_id = :category_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":category_id": &types.AttributeValueMemberS{Value: categoryID},
+		},
+		Limit: aws.Int32(1),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to query products by category: %w", err)
+	}
+	return len(result.Items) > 0, nil
+}
+
+// GetDeleted returns a page of the soft-deleted products
+func (r *ProductDynamoRepo) GetDeleted(ctx context.Context, page entity.Pagination) ([]entity.Product, string, error) {
+	items, nextCursor, err := collectPage(ctx, page, r.scanDeleted())
+	if err != nil {
+		return nil, "", err
+	}
+
+	products := make([]entity.Product, 0, len(items))
+	for _, item := range items {
+		products = append(products, productFromItem(item))
+	}
+	return products, nextCursor, nil
+}
+
+// GetDeletedBefore returns every product soft-deleted before the time. deleted_at
+// is not stored in a sortable form, so it is compared after the scan.
+func (r *ProductDynamoRepo) GetDeletedBefore(ctx context.Context, before time.Time) ([]entity.Product, error) {
+	fetch := r.scanDeleted()
+
+	var products []entity.Product
+	var startKey map[string]types.AttributeValue
+	for {
+		items, lastKey, err := fetch(ctx, startKey, entity.MaxPageLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			product := productFromItem(item)
+			if product.DeletedAt != nil && product.DeletedAt.Before(before) {
+				products = append(products, product)
+			}
+		}
+
+		if len(lastKey) == 0 {
+			break
+		}
+		startKey = lastKey
+	}
+
+	return products, nil
+}
+
+func (r *ProductDynamoRepo) scanDeleted() pageFetcher {
+	return func(
+		ctx context.Context,
+		startKey map[string]types.AttributeValue,
+		limit int32,
+	) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         aws.String(r.ProductTable),
+			FilterExpression:  aws.String("attribute_exists(deleted_at)"),
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan deleted products: %w", err)
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	}
+}
+
+func (r *ProductDynamoRepo) GetDeletedProductByID(ctx context.Context, id string) (*entity.Product, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.ProductTable),
+		KeyConditionExpression: aws.String("id = :id"),
+		FilterExpression:       aws.String("attribute_exists(deleted_at)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: id},
+		},
+	}
+
+	result, err := r.Client.Query(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted product: %w", err)
+	}
+
+	if len(result.Items) == 0 {
+		return nil, fmt.Errorf("%w in trash, id: %s", entity.ErrProductNotFound, id)
+	}
+
+	product := productFromItem(result.Items[0])
+	return &product, nil
+}
+
+// Restore takes the product out of the trash
+func (r *ProductDynamoRepo) Restore(ctx context.Context, product *entity.Product) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.ProductTable),
+		Key: map[string]types.AttributeValue{
+			"id":          &types.AttributeValueMemberS{Value: product.ID},
+			"category_id": &types.AttributeValueMemberS{Value: product.CategoryID},
+		},
+		UpdateExpression: aws.String("SET updated_at = :updated_at REMOVE deleted_at"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":updated_at": &types.AttributeValueMemberS{Value: product.UpdatedAt.Format(time.RFC3339)},
+		},
+		ConditionExpression: aws.String("attribute_exists(deleted_at)"),
+	}
+
+	_, err := r.Client.UpdateItem(ctx, input)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w in trash, id: %s", entity.ErrProductNotFound, product.ID)
+		}
+		return fmt.Errorf("failed to restore product: %w", err)
+	}
+
+	return nil
+}
+
+// Purge removes a soft-deleted product for good, a product restored in the
+// meantime is kept
+func (r *ProductDynamoRepo) Purge(ctx context.Context, productID string, categoryID string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.ProductTable),
+		Key: map[string]types.AttributeValue{
+			"id":          &types.AttributeValueMemberS{Value: productID},
+			"category_id": &types.AttributeValueMemberS{Value: categoryID},
+		},
+		ConditionExpression: aws.String("attribute_exists(deleted_at)"),
+	}
+
+	_, err := r.Client.DeleteItem(ctx, input)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w in trash, id: %s", entity.ErrProductNotFound, productID)
+		}
+		return fmt.Errorf("failed to purge product: %w", err)
+	}
+
+	return nil
+}
+
+func productFromItem(item map[string]types.AttributeValue) entity.Product {
+	product := entity.Product{}
+
+	product.ID = stringAttr(item, "id")
+	product.SKU = stringAttr(item, "sku")
+	product.Name = stringAttr(item, "name")
+	product.Slug = stringAttr(item, "slug")
+	product.ImageURL = stringAttr(item, "image_url")
+	product.Description = stringAttr(item, "description")
+	product.CategoryID = stringAttr(item, "category_id")
+	product.UpdatedBy = stringAttr(item, "updated_by")
+
+	product.Price = moneyFromItem(item, "price", "currency")
+	product.Prices = priceListFromItem(item)
+	if quantity, err := strconv.Atoi(numberAttr(item, "quantity")); err == nil {
+		product.Quantity = quantity
+	}
+	product.Version = versionFromItem(item)
+	if reserved, err := strconv.Atoi(numberAttr(item, "reserved")); err == nil {
+		product.Reserved = reserved
+	}
+	if threshold, err := strconv.Atoi(numberAttr(item, "reorder_threshold")); err == nil {
+		product.ReorderThreshold = &threshold
+	}
+	product.StockAlert = stockAlertFromItem(item)
+
+	if createdAt, err := time.Parse(time.RFC3339, stringAttr(item, "created_at")); err == nil {
+		product.CreatedAt = createdAt
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, stringAttr(item, "updated_at")); err == nil {
+		product.UpdatedAt = updatedAt
+	}
+	if deletedAt, err := time.Parse(time.RFC3339, stringAttr(item, "deleted_at")); err == nil {
+		product.DeletedAt = &deletedAt
+	}
+
+	product.Status = entity.ProductStatus(stringAttr(item, "status"))
+	if product.Status == "" {
+		// products created before the lifecycle were live right away
+		product.Status = entity.ProductPublished
+		product.PublishedAt = &product.CreatedAt
+	}
+	if publishAt, err := time.Parse(_sortableTimeLayout, stringAttr(item, "publish_at")); err == nil {
+		product.PublishAt = &publishAt
+	}
+	if publishedAt, err := time.Parse(time.RFC3339, stringAttr(item, "published_at")); err == nil {
+		product.PublishedAt = &publishedAt
+	}
+
+	product.Attributes = productAttributesFromItem(item)
+	product.Translations = productTranslationsFromItem(item)
+	product.Type = entity.ProductType(stringAttr(item, "type"))
+	if product.Type == "" {
+		product.Type = entity.ProductSimple
+	}
+	product.Bundle = bundleFromItem(item)
+	product.Images = imagesFromItem(item)
+	if len(product.Images) == 0 && product.ImageURL != "" {
+		// products created before the gallery only have image_url,
+		// the id is derived from the url so it stays the same on every read
+		product.Images = []entity.ProductImage{{
+			ID:        uuid.NewSHA1(uuid.NameSpaceURL, []byte(product.ImageURL)).String(),
+			URL:       product.ImageURL,
+			IsPrimary: true,
+		}}
+	}
+
+	return product
+}
+
+func stockAlertToAttributeValue(alert entity.StockAlert) *types.AttributeValueMemberM {
+	fields := map[string]types.AttributeValue{
+		"level":      &types.AttributeValueMemberS{Value: string(alert.Level)},
+		"sent_level": &types.AttributeValueMemberS{Value: string(alert.SentLevel)},
+	}
+	if alert.SentAt != nil {
+		fields["sent_at"] = &types.AttributeValueMemberS{Value: alert.SentAt.Format(time.RFC3339)}
+	}
+	return &types.AttributeValueMemberM{Value: fields}
+}
+
+func stockAlertFromItem(item map[string]types.AttributeValue) entity.StockAlert {
+	fields, ok := item["stock_alert"].(*types.AttributeValueMemberM)
+	if !ok {
+		return entity.StockAlert{}
+	}
+
+	alert := entity.StockAlert{
+		Level:     entity.StockLevel(stringAttr(fields.Value, "level")),
+		SentLevel: entity.StockLevel(stringAttr(fields.Value, "sent_level")),
+	}
+	if sentAt, err := time.Parse(time.RFC3339, stringAttr(fields.Value, "sent_at")); err == nil {
+		alert.SentAt = &sentAt
+	}
+	return alert
+}
+
+func imagesFromItem(item map[string]types.AttributeValue) []entity.ProductImage {
+	list, ok := item["images"].(*types.AttributeValueMemberL)
+	if !ok {
+		return nil
+	}
+
+	images := make([]entity.ProductImage, 0, len(list.Value))
+	for _, value := range list.Value {
+		m, ok := value.(*types.AttributeValueMemberM)
+		if !ok {
+			continue
+		}
+
+		image := entity.ProductImage{
+			ID:      stringAttr(m.Value, "id"),
+			URL:     stringAttr(m.Value, "url"),
+			AltText: stringAttr(m.Value, "alt_text"),
+		}
+		if position, err := strconv.Atoi(numberAttr(m.Value, "position")); err == nil {
+			image.Position = position
+		}
+		if primary, ok := m.Value["is_primary"].(*types.AttributeValueMemberBOOL); ok {
+			image.IsPrimary = primary.Value
+		}
+		image.Renditions = renditionsFromAttributeValue(m.Value["renditions"])
+		images = append(images, image)
+	}
+	return images
+}
+
+func imagesToAttributeValue(images []entity.ProductImage) *types.AttributeValueMemberL {
+	list := make([]types.AttributeValue, 0, len(images))
+	for _, image := range images {
+		list = append(list, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"id":         &types.AttributeValueMemberS{Value: image.ID},
+			"url":        &types.AttributeValueMemberS{Value: image.URL},
+			"alt_text":   &types.AttributeValueMemberS{Value: image.AltText},
+			"position":   &types.AttributeValueMemberN{Value: strconv.Itoa(image.Position)},
+			"is_primary": &types.AttributeValueMemberBOOL{Value: image.IsPrimary},
+			"renditions": renditionsToAttributeValue(image.Renditions),
+		}})
+	}
+	return &types.AttributeValueMemberL{Value: list}
+}
+
+func renditionsFromAttributeValue(value types.AttributeValue) []entity.ImageRendition {
+	list, ok := value.(*types.AttributeValueMemberL)
+	if !ok {
+		return nil
+	}
+
+	renditions := make([]entity.ImageRendition, 0, len(list.Value))
+	for _, v := range list.Value {
+		m, ok := v.(*types.AttributeValueMemberM)
+		if !ok {
+			continue
+		}
+
+		rendition := entity.ImageRendition{
+			Name:   stringAttr(m.Value, "name"),
+			Format: stringAttr(m.Value, "format"),
+			URL:    stringAttr(m.Value, "url"),
+		}
+		if width, err := strconv.Atoi(numberAttr(m.Value, "width")); err == nil {
+			rendition.Width = width
+		}
+		if height, err := strconv.Atoi(numberAttr(m.Value, "height")); err == nil {
+			rendition.Height = height
+		}
+		renditions = append(renditions, rendition)
+	}
+	return renditions
+}
+
+func renditionsToAttributeValue(renditions []entity.ImageRendition) *types.AttributeValueMemberL {
+	list := make([]types.AttributeValue, 0, len(renditions))
+	for _, rendition := range renditions {
+		list = append(list, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"name":   &types.AttributeValueMemberS{Value: rendition.Name},
+			"format": &types.AttributeValueMemberS{Value: rendition.Format},
+			"width":  &types.AttributeValueMemberN{Value: strconv.Itoa(rendition.Width)},
+			"height": &types.AttributeValueMemberN{Value: strconv.Itoa(rendition.Height)},
+			"url":    &types.AttributeValueMemberS{Value: rendition.URL},
+		}})
+	}
+	return &types.AttributeValueMemberL{Value: list}
+}
+
+func productAttributesFromItem(item map[string]types.AttributeValue) entity.ProductAttributes {
+	attributes := entity.ProductAttributes{}
+
+	m, ok := item["attributes"].(*types.AttributeValueMemberM)
+	if !ok {
+		return attributes
+	}
+
+	for name, value := range m.Value {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			attributes[name] = v.Value
+		case *types.AttributeValueMemberBOOL:
+			attributes[name] = v.Value
+		case *types.AttributeValueMemberN:
+			// integers are stored without a fraction, decimals keep theirs
+			if i, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+				attributes[name] = i
+			} else if f, err := strconv.ParseFloat(v.Value, 64); err == nil {
+				attributes[name] = f
+			}
+		}
+	}
+	return attributes
+}
+
+func productAttributesToAttributeValue(attributes entity.ProductAttributes) *types.AttributeValueMemberM {
+	m := make(map[string]types.AttributeValue, len(attributes))
+	for name, value := range attributes {
+		switch v := value.(type) {
+		case string:
+			m[name] = &types.AttributeValueMemberS{Value: v}
+		case bool:
+			m[name] = &types.AttributeValueMemberBOOL{Value: v}
+		case int64:
+			m[name] = &types.AttributeValueMemberN{Value: strconv.FormatInt(v, 10)}
+		case float64:
+			m[name] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(v, 'f', -1, 64)}
+		}
+	}
+	return &types.AttributeValueMemberM{Value: m}
+}
+
+func stringAttr(item map[string]types.AttributeValue, name string) string {
+	if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
+
+func numberAttr(item map[string]types.AttributeValue, name string) string {
+	if v, ok := item[name].(*types.AttributeValueMemberN); ok {
+		return v.Value
+	}
+	return ""
+}
+
+// moneyToAttributeValue stores the amount as an exact decimal in the major unit,
+// so the price GSIs keep sorting the same as before prices had a currency
+func moneyToAttributeValue(m entity.Money) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: m.Decimal()}
+}
+
+// moneyFromItem reads an amount stored by moneyToAttributeValue, items without
+// a currency are from before prices had one and are in the default currency
+func moneyFromItem(item map[string]types.AttributeValue, amountName, currencyName string) entity.Money {
+	currency := stringAttr(item, currencyName)
+	if currency == "" {
+		currency = entity.DefaultCurrency
+	}
+
+	money, err := entity.ParseStoredMoney(numberAttr(item, amountName), currency)
+	if err != nil {
+		return entity.Money{Currency: currency}
+	}
+	return money
+}
+
+// priceListToAttributeValue stores the price list as a map from currency to amount
+func priceListToAttributeValue(prices []entity.Money) types.AttributeValue {
+	m := make(map[string]types.AttributeValue, len(prices))
+	for _, price := range prices {
+		m[price.Currency] = moneyToAttributeValue(price)
+	}
+	return &types.AttributeValueMemberM{Value: m}
+}
+
+func priceListFromItem(item map[string]types.AttributeValue) []entity.Money {
+	prices := []entity.Money{}
+
+	m, ok := item["prices"].(*types.AttributeValueMemberM)
+	if !ok {
+		return prices
+	}
+
+	for currency, v := range m.Value {
+		n, ok := v.(*types.AttributeValueMemberN)
+		if !ok {
+			continue
+		}
+		if price, err := entity.ParseStoredMoney(n.Value, currency); err == nil {
+			prices = append(prices, price)
+		}
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].Currency < prices[j].Currency
+	})
+	return prices
+}
@@ -0,0 +1,271 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+)
+
+// ImportDynamoRepo stores the import jobs with id as key, and the row errors of
+// a job with job_id as partition key and row as sort key
+type ImportDynamoRepo struct {
+	*awsService.DynamoDB
+}
+
+func NewImportDynamoRepo(d *awsService.DynamoDB) *ImportDynamoRepo {
+	return &ImportDynamoRepo{
+		d,
+	}
+}
+
+func (r *ImportDynamoRepo) SaveJob(ctx context.Context, job *entity.ImportJob) error {
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.ImportJobTable),
+		Item: map[string]types.AttributeValue{
+			"id":         &types.AttributeValueMemberS{Value: job.ID},
+			"file_name":  &types.AttributeValueMemberS{Value: job.FileName},
+			"status":     &types.AttributeValueMemberS{Value: string(job.Status)},
+			"total_rows": &types.AttributeValueMemberN{Value: strconv.Itoa(job.TotalRows)},
+			"succeeded":  &types.AttributeValueMemberN{Value: strconv.Itoa(job.Succeeded)},
+			"failed":     &types.AttributeValueMemberN{Value: strconv.Itoa(job.Failed)},
+			"created_by": &types.AttributeValueMemberS{Value: job.CreatedBy},
+			"created_at": &types.AttributeValueMemberS{Value: job.CreatedAt.Format(time.RFC3339)},
+			"updated_at": &types.AttributeValueMemberS{Value: job.UpdatedAt.Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save import job: %w", err)
+	}
+	return nil
+}
+
+func (r *ImportDynamoRepo) GetJobByID(ctx context.Context, id string) (*entity.ImportJob, error) {
+	result, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.ImportJobTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, fmt.Errorf("%w, id: %s", entity.ErrImportJobNotFound, id)
+	}
+
+	job := importJobFromItem(result.Item)
+	return &job, nil
+}
+
+// UpdateJob stores the status and the counts of a running job
+func (r *ImportDynamoRepo) UpdateJob(ctx context.Context, job *entity.ImportJob) error {
+	updateExpression := "SET #status = :status, total_rows = :total_rows, succeeded = :succeeded, failed = :failed, updated_at = :updated_at"
+	values := map[string]types.AttributeValue{
+		":status":     &types.AttributeValueMemberS{Value: string(job.Status)},
+		":total_rows": &types.AttributeValueMemberN{Value: strconv.Itoa(job.TotalRows)},
+		":succeeded":  &types.AttributeValueMemberN{Value: strconv.Itoa(job.Succeeded)},
+		":failed":     &types.AttributeValueMemberN{Value: strconv.Itoa(job.Failed)},
+		":updated_at": &types.AttributeValueMemberS{Value: job.UpdatedAt.Format(time.RFC3339)},
+	}
+	if job.Error != "" {
+		updateExpression += ", #error = :error"
+		values[":error"] = &types.AttributeValueMemberS{Value: job.Error}
+	}
+	if job.FinishedAt != nil {
+		updateExpression += ", finished_at = :finished_at"
+		values[":finished_at"] = &types.AttributeValueMemberS{Value: job.FinishedAt.Format(time.RFC3339)}
+	}
+
+	names := map[string]string{"#status": "status"}
+	if job.Error != "" {
+		names["#error"] = "error"
+	}
+
+	_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.ImportJobTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: job.ID},
+		},
+		UpdateExpression:          aws.String(updateExpression),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ConditionExpression:       aws.String("attribute_exists(id)"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update import job: %w", err)
+	}
+	return nil
+}
+
+// GetStaleJobs returns the pending and running jobs that reported no progress since the
+// time. updated_at is not stored in a sortable form, so it is compared after the scan.
+func (r *ImportDynamoRepo) GetStaleJobs(ctx context.Context, before time.Time) ([]entity.ImportJob, error) {
+	var jobs []entity.ImportJob
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:        aws.String(r.ImportJobTable),
+			FilterExpression: aws.String("#status IN (:pending, :running)"),
+			ExpressionAttributeNames: map[string]string{
+				"#status": "status",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pending": &types.AttributeValueMemberS{Value: string(entity.ImportPending)},
+				":running": &types.AttributeValueMemberS{Value: string(entity.ImportRunning)},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import jobs: %w", err)
+		}
+
+		for _, item := range result.Items {
+			job := importJobFromItem(item)
+			if job.UpdatedAt.Before(before) {
+				jobs = append(jobs, job)
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return jobs, nil
+}
+
+// FailStaleJob stores the job as failed if it reported no progress since it was read,
+// it returns false when the job went on meanwhile
+func (r *ImportDynamoRepo) FailStaleJob(ctx context.Context, job *entity.ImportJob, lastSeen time.Time) (bool, error) {
+	_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.ImportJobTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: job.ID},
+		},
+		UpdateExpression:    aws.String("SET #status = :status, #error = :error, updated_at = :updated_at, finished_at = :finished_at"),
+		ConditionExpression: aws.String("updated_at = :last_seen AND #status IN (:pending, :running)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+			"#error":  "error",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status":      &types.AttributeValueMemberS{Value: string(job.Status)},
+			":error":       &types.AttributeValueMemberS{Value: job.Error},
+			":updated_at":  &types.AttributeValueMemberS{Value: job.UpdatedAt.Format(time.RFC3339)},
+			":finished_at": &types.AttributeValueMemberS{Value: job.FinishedAt.Format(time.RFC3339)},
+			":last_seen":   &types.AttributeValueMemberS{Value: lastSeen.Format(time.RFC3339)},
+			":pending":     &types.AttributeValueMemberS{Value: string(entity.ImportPending)},
+			":running":     &types.AttributeValueMemberS{Value: string(entity.ImportRunning)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to fail import job: %w", err)
+	}
+	return true, nil
+}
+
+func (r *ImportDynamoRepo) SaveRowErrors(ctx context.Context, rowErrors []entity.ImportRowError) error {
+	items := make([]map[string]types.AttributeValue, 0, len(rowErrors))
+	for _, rowError := range rowErrors {
+		items = append(items, map[string]types.AttributeValue{
+			"job_id":  &types.AttributeValueMemberS{Value: rowError.JobID},
+			"row":     &types.AttributeValueMemberN{Value: strconv.Itoa(rowError.Row)},
+			"field":   &types.AttributeValueMemberS{Value: rowError.Field},
+			"message": &types.AttributeValueMemberS{Value: rowError.Message},
+		})
+	}
+
+	_, err := batchWriteItems(ctx, r.Client, r.ImportErrorTable, []string{"job_id", "row"}, items)
+	if err != nil {
+		return fmt.Errorf("failed to save import row errors: %w", err)
+	}
+	return nil
+}
+
+// GetRowErrors returns the row errors of a job in file order
+func (r *ImportDynamoRepo) GetRowErrors(ctx context.Context, jobID string, page entity.Pagination) ([]entity.ImportRowError, string, error) {
+	fetch := func(
+		ctx context.Context,
+		startKey map[string]types.AttributeValue,
+		limit int32,
+	) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.ImportErrorTable),
+			KeyConditionExpression: aws.String("job_id = :job_id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":job_id": &types.AttributeValueMemberS{Value: jobID},
+			},
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query import row errors: %w", err)
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	}
+
+	items, nextCursor, err := collectPage(ctx, page, fetch)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rowErrors := make([]entity.ImportRowError, 0, len(items))
+	for _, item := range items {
+		rowError := entity.ImportRowError{
+			JobID:   stringAttr(item, "job_id"),
+			Field:   stringAttr(item, "field"),
+			Message: stringAttr(item, "message"),
+		}
+		if row, err := strconv.Atoi(numberAttr(item, "row")); err == nil {
+			rowError.Row = row
+		}
+		rowErrors = append(rowErrors, rowError)
+	}
+	return rowErrors, nextCursor, nil
+}
+
+func importJobFromItem(item map[string]types.AttributeValue) entity.ImportJob {
+	job := entity.ImportJob{
+		ID:        stringAttr(item, "id"),
+		FileName:  stringAttr(item, "file_name"),
+		Status:    entity.ImportJobStatus(stringAttr(item, "status")),
+		Error:     stringAttr(item, "error"),
+		CreatedBy: stringAttr(item, "created_by"),
+	}
+
+	if totalRows, err := strconv.Atoi(numberAttr(item, "total_rows")); err == nil {
+		job.TotalRows = totalRows
+	}
+	if succeeded, err := strconv.Atoi(numberAttr(item, "succeeded")); err == nil {
+		job.Succeeded = succeeded
+	}
+	if failed, err := strconv.Atoi(numberAttr(item, "failed")); err == nil {
+		job.Failed = failed
+	}
+
+	if createdAt, err := time.Parse(time.RFC3339, stringAttr(item, "created_at")); err == nil {
+		job.CreatedAt = createdAt
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, stringAttr(item, "updated_at")); err == nil {
+		job.UpdatedAt = updatedAt
+	}
+	if finishedAt, err := time.Parse(time.RFC3339, stringAttr(item, "finished_at")); err == nil {
+		job.FinishedAt = &finishedAt
+	}
+
+	return job
+}
@@ -0,0 +1,165 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+)
+
+// ProductLinkDynamoRepo stores the links with product_id as partition key and
+// type_linked_id (<type>#<linked product id>) as sort key, so a product has one link
+// of a type to another product
+type ProductLinkDynamoRepo struct {
+	*awsService.DynamoDB
+}
+
+func NewProductLinkDynamoRepo(d *awsService.DynamoDB) *ProductLinkDynamoRepo {
+	return &ProductLinkDynamoRepo{
+		d,
+	}
+}
+
+// Save creates the link or moves it to its new position
+func (r *ProductLinkDynamoRepo) Save(ctx context.Context, link *entity.ProductLink) error {
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.ProductLinkTable),
+		Item: map[string]types.AttributeValue{
+			"product_id":        &types.AttributeValueMemberS{Value: link.ProductID},
+			"type_linked_id":    &types.AttributeValueMemberS{Value: productLinkSortKey(link.Type, link.LinkedProductID)},
+			"linked_product_id": &types.AttributeValueMemberS{Value: link.LinkedProductID},
+			"type":              &types.AttributeValueMemberS{Value: string(link.Type)},
+			"position":          &types.AttributeValueMemberN{Value: strconv.Itoa(link.Position)},
+			"created_at":        &types.AttributeValueMemberS{Value: link.CreatedAt.Format(time.RFC3339)},
+			"created_by":        &types.AttributeValueMemberS{Value: link.CreatedBy},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save product link: %w", err)
+	}
+	return nil
+}
+
+func (r *ProductLinkDynamoRepo) Delete(ctx context.Context, productID string, linkType entity.ProductLinkType, linkedProductID string) error {
+	_, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.ProductLinkTable),
+		Key: map[string]types.AttributeValue{
+			"product_id":     &types.AttributeValueMemberS{Value: productID},
+			"type_linked_id": &types.AttributeValueMemberS{Value: productLinkSortKey(linkType, linkedProductID)},
+		},
+		ConditionExpression: aws.String("attribute_exists(product_id)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w, product: %s, %s: %s", entity.ErrProductLinkNotFound, productID, linkType, linkedProductID)
+		}
+		return fmt.Errorf("failed to delete product link: %w", err)
+	}
+	return nil
+}
+
+// GetByProductID returns the links of the product, or only those of the type when it is not empty
+func (r *ProductLinkDynamoRepo) GetByProductID(ctx context.Context, productID string, linkType entity.ProductLinkType) ([]entity.ProductLink, error) {
+	keyCondition := "product_id = :product_id"
+	values := map[string]types.AttributeValue{
+		":product_id": &types.AttributeValueMemberS{Value: productID},
+	}
+	if linkType != "" {
+		keyCondition += " AND begins_with(type_linked_id, :type)"
+		values[":type"] = &types.AttributeValueMemberS{Value: string(linkType) + "#"}
+	}
+
+	links := []entity.ProductLink{}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(r.ProductLinkTable),
+			KeyConditionExpression:    aws.String(keyCondition),
+			ExpressionAttributeValues: values,
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query product links: %w", err)
+		}
+
+		for _, item := range result.Items {
+			links = append(links, productLinkFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return links, nil
+}
+
+// PurgeByProductID deletes the links of the product and the links of other products to it.
+// The links to it are found with a scan, this only runs when a product is purged.
+func (r *ProductLinkDynamoRepo) PurgeByProductID(ctx context.Context, productID string) error {
+	keyNames := []string{"product_id", "type_linked_id"}
+	keys, err := collectKeys(ctx, queryPartition(r.Client, r.ProductLinkTable, "product_id", productID), keyNames)
+	if err != nil {
+		return fmt.Errorf("failed to query product links: %w", err)
+	}
+
+	scanLinked := func(
+		ctx context.Context,
+		startKey map[string]types.AttributeValue,
+		limit int32,
+	) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:        aws.String(r.ProductLinkTable),
+			FilterExpression: aws.String("linked_product_id = :product_id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":product_id": &types.AttributeValueMemberS{Value: productID},
+			},
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan product links: %w", err)
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	}
+	linked, err := collectKeys(ctx, scanLinked, keyNames)
+	if err != nil {
+		return err
+	}
+
+	err = batchDeleteItems(ctx, r.Client, r.ProductLinkTable, append(keys, linked...))
+	if err != nil {
+		return fmt.Errorf("failed to purge product links: %w", err)
+	}
+	return nil
+}
+
+func productLinkSortKey(linkType entity.ProductLinkType, linkedProductID string) string {
+	return string(linkType) + "#" + linkedProductID
+}
+
+func productLinkFromItem(item map[string]types.AttributeValue) entity.ProductLink {
+	link := entity.ProductLink{
+		ProductID:       stringAttr(item, "product_id"),
+		LinkedProductID: stringAttr(item, "linked_product_id"),
+		Type:            entity.ProductLinkType(stringAttr(item, "type")),
+		CreatedBy:       stringAttr(item, "created_by"),
+	}
+	if position, err := strconv.Atoi(numberAttr(item, "position")); err == nil {
+		link.Position = position
+	}
+	if createdAt, err := time.Parse(time.RFC3339, stringAttr(item, "created_at")); err == nil {
+		link.CreatedAt = createdAt
+	}
+	return link
+}
@@ -0,0 +1,374 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+)
+
+// _sortableTimeLayout has a fixed width so timestamps stored as strings sort in time order,
+// RFC3339Nano drops trailing zeros and would not
+const _sortableTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// PriceDynamoRepo stores the price history with product_id as partition key and
+// effective_from_id (<effective from>#<id>) as sort key, and the scheduled changes with
+// product_id and id as key. The status-effective_from-index GSI finds the due changes.
+type PriceDynamoRepo struct {
+	*awsService.DynamoDB
+}
+
+func NewPriceDynamoRepo(d *awsService.DynamoDB) *PriceDynamoRepo {
+	return &PriceDynamoRepo{
+		d,
+	}
+}
+
+func (r *PriceDynamoRepo) AppendHistory(ctx context.Context, entry *entity.PriceHistoryEntry) error {
+	// the history is append only, an entry is never overwritten
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.PriceHistoryTable),
+		Item:                priceHistoryToItem(entry),
+		ConditionExpression: aws.String("attribute_not_exists(product_id)"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append price history: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyPrice stores the price of the product and appends its history entry in one
+// transaction, so a price never changes without its entry. The product has to exist and
+// not be deleted.
+func (r *PriceDynamoRepo) ApplyPrice(ctx context.Context, product *entity.Product, entry *entity.PriceHistoryEntry) error {
+	_, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Update: &types.Update{
+					TableName:           aws.String(r.ProductTable),
+					Key:                 productKey(product.ID, product.CategoryID),
+					UpdateExpression:    aws.String("SET price = :price, currency = :currency, updated_at = :updated_at, updated_by = :updated_by, " + _versionIncrement),
+					ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":price":       moneyToAttributeValue(product.Price),
+						":currency":    &types.AttributeValueMemberS{Value: product.Price.Currency},
+						":updated_at":  &types.AttributeValueMemberS{Value: product.UpdatedAt.Format(time.RFC3339)},
+						":updated_by":  &types.AttributeValueMemberS{Value: product.UpdatedBy},
+						":version_one": &types.AttributeValueMemberN{Value: "1"},
+					},
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName:           aws.String(r.PriceHistoryTable),
+					Item:                priceHistoryToItem(entry),
+					ConditionExpression: aws.String("attribute_not_exists(product_id)"),
+				},
+			},
+		},
+	})
+	if err != nil {
+		failed, ok := failedTransactItems(err)
+		if ok && len(failed) > 0 && failed[0] == 0 {
+			return fmt.Errorf("%w, id: %s", entity.ErrProductNotFound, product.ID)
+		}
+		return fmt.Errorf("failed to apply price: %w", err)
+	}
+
+	return nil
+}
+
+func priceHistoryToItem(entry *entity.PriceHistoryEntry) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"product_id":        &types.AttributeValueMemberS{Value: entry.ProductID},
+		"effective_from_id": &types.AttributeValueMemberS{Value: sortableTime(entry.EffectiveFrom) + "#" + entry.ID},
+		"id":                &types.AttributeValueMemberS{Value: entry.ID},
+		"price":             moneyToAttributeValue(entry.Price),
+		"currency":          &types.AttributeValueMemberS{Value: entry.Price.Currency},
+		"previous_price":    moneyToAttributeValue(entry.PreviousPrice),
+		"previous_currency": &types.AttributeValueMemberS{Value: entry.PreviousPrice.Currency},
+		"effective_from":    &types.AttributeValueMemberS{Value: sortableTime(entry.EffectiveFrom)},
+		"actor":             &types.AttributeValueMemberS{Value: entry.Actor},
+		"source":            &types.AttributeValueMemberS{Value: string(entry.Source)},
+	}
+	if entry.ScheduledChangeID != "" {
+		item["scheduled_change_id"] = &types.AttributeValueMemberS{Value: entry.ScheduledChangeID}
+	}
+	return item
+}
+
+// GetHistory returns the history of a product, newest first
+func (r *PriceDynamoRepo) GetHistory(ctx context.Context, productID string, page entity.Pagination) ([]entity.PriceHistoryEntry, string, error) {
+	fetch := func(
+		ctx context.Context,
+		startKey map[string]types.AttributeValue,
+		limit int32,
+	) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.PriceHistoryTable),
+			KeyConditionExpression: aws.String("product_id = :product_id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":product_id": &types.AttributeValueMemberS{Value: productID},
+			},
+			ScanIndexForward:  aws.Bool(false),
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query price history: %w", err)
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	}
+
+	items, nextCursor, err := collectPage(ctx, page, fetch)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries := make([]entity.PriceHistoryEntry, 0, len(items))
+	for _, item := range items {
+		entries = append(entries, priceHistoryEntryFromItem(item))
+	}
+	return entries, nextCursor, nil
+}
+
+// GetPriceAt returns the entry that was in effect at the given time
+func (r *PriceDynamoRepo) GetPriceAt(ctx context.Context, productID string, at time.Time) (*entity.PriceHistoryEntry, error) {
+	// '$' sorts after '#', so every entry effective at exactly that time is included
+	result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.PriceHistoryTable),
+		KeyConditionExpression: aws.String("product_id = :product_id AND effective_from_id <= :at"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":product_id": &types.AttributeValueMemberS{Value: productID},
+			":at":         &types.AttributeValueMemberS{Value: sortableTime(at) + "$"},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+
+	if len(result.Items) == 0 {
+		return nil, fmt.Errorf("%w, product id: %s", entity.ErrPriceHistoryNotFound, productID)
+	}
+
+	entry := priceHistoryEntryFromItem(result.Items[0])
+	return &entry, nil
+}
+
+func (r *PriceDynamoRepo) SaveScheduledChange(ctx context.Context, change *entity.ScheduledPriceChange) error {
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.PriceScheduleTable),
+		Item: map[string]types.AttributeValue{
+			"product_id":     &types.AttributeValueMemberS{Value: change.ProductID},
+			"id":             &types.AttributeValueMemberS{Value: change.ID},
+			"price":          moneyToAttributeValue(change.Price),
+			"currency":       &types.AttributeValueMemberS{Value: change.Price.Currency},
+			"effective_from": &types.AttributeValueMemberS{Value: sortableTime(change.EffectiveFrom)},
+			"actor":          &types.AttributeValueMemberS{Value: change.Actor},
+			"status":         &types.AttributeValueMemberS{Value: string(change.Status)},
+			"created_at":     &types.AttributeValueMemberS{Value: change.CreatedAt.Format(time.RFC3339)},
+			"updated_at":     &types.AttributeValueMemberS{Value: change.UpdatedAt.Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save scheduled price change: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PriceDynamoRepo) GetScheduledChanges(ctx context.Context, productID string) ([]entity.ScheduledPriceChange, error) {
+	changes := []entity.ScheduledPriceChange{}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.PriceScheduleTable),
+			KeyConditionExpression: aws.String("product_id = :product_id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":product_id": &types.AttributeValueMemberS{Value: productID},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query scheduled price changes: %w", err)
+		}
+
+		for _, item := range result.Items {
+			changes = append(changes, scheduledPriceChangeFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return changes, nil
+}
+
+func (r *PriceDynamoRepo) GetScheduledChange(ctx context.Context, productID, changeID string) (*entity.ScheduledPriceChange, error) {
+	result, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.PriceScheduleTable),
+		Key: map[string]types.AttributeValue{
+			"product_id": &types.AttributeValueMemberS{Value: productID},
+			"id":         &types.AttributeValueMemberS{Value: changeID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled price change: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, fmt.Errorf("%w, id: %s", entity.ErrPriceChangeNotFound, changeID)
+	}
+
+	change := scheduledPriceChangeFromItem(result.Item)
+	return &change, nil
+}
+
+// GetDueScheduledChanges returns the pending changes that take effect at or before the given time
+func (r *PriceDynamoRepo) GetDueScheduledChanges(ctx context.Context, before time.Time) ([]entity.ScheduledPriceChange, error) {
+	changes := []entity.ScheduledPriceChange{}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.PriceScheduleTable),
+			IndexName:              aws.String("status-effective_from-index"),
+			KeyConditionExpression: aws.String("#status = :status AND effective_from <= :before"),
+			ExpressionAttributeNames: map[string]string{
+				"#status": "status",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":status": &types.AttributeValueMemberS{Value: string(entity.ScheduledPricePending)},
+				":before": &types.AttributeValueMemberS{Value: sortableTime(before)},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query due price changes: %w", err)
+		}
+
+		for _, item := range result.Items {
+			changes = append(changes, scheduledPriceChangeFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return changes, nil
+}
+
+// UpdateScheduledChangeStatus moves a change from one status to another. The condition on
+// the current status makes it a claim, only one instance applies or cancels a change.
+func (r *PriceDynamoRepo) UpdateScheduledChangeStatus(
+	ctx context.Context,
+	change *entity.ScheduledPriceChange,
+	from entity.ScheduledPriceStatus,
+) error {
+	_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.PriceScheduleTable),
+		Key: map[string]types.AttributeValue{
+			"product_id": &types.AttributeValueMemberS{Value: change.ProductID},
+			"id":         &types.AttributeValueMemberS{Value: change.ID},
+		},
+		UpdateExpression: aws.String("SET #status = :status, updated_at = :updated_at"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status":     &types.AttributeValueMemberS{Value: string(change.Status)},
+			":from":       &types.AttributeValueMemberS{Value: string(from)},
+			":updated_at": &types.AttributeValueMemberS{Value: change.UpdatedAt.Format(time.RFC3339)},
+		},
+		ConditionExpression: aws.String("#status = :from"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w, id: %s", entity.ErrPriceChangeNotPending, change.ID)
+		}
+		return fmt.Errorf("failed to update scheduled price change: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeByProductID hard-deletes the price history and the scheduled changes of the product
+func (r *PriceDynamoRepo) PurgeByProductID(ctx context.Context, productID string) error {
+	keys, err := collectKeys(ctx, queryPartition(r.Client, r.PriceHistoryTable, "product_id", productID), []string{"product_id", "effective_from_id"})
+	if err != nil {
+		return fmt.Errorf("failed to query price history: %w", err)
+	}
+	err = batchDeleteItems(ctx, r.Client, r.PriceHistoryTable, keys)
+	if err != nil {
+		return fmt.Errorf("failed to purge price history: %w", err)
+	}
+
+	keys, err = collectKeys(ctx, queryPartition(r.Client, r.PriceScheduleTable, "product_id", productID), []string{"product_id", "id"})
+	if err != nil {
+		return fmt.Errorf("failed to query scheduled price changes: %w", err)
+	}
+	err = batchDeleteItems(ctx, r.Client, r.PriceScheduleTable, keys)
+	if err != nil {
+		return fmt.Errorf("failed to purge scheduled price changes: %w", err)
+	}
+	return nil
+}
+
+func priceHistoryEntryFromItem(item map[string]types.AttributeValue) entity.PriceHistoryEntry {
+	entry := entity.PriceHistoryEntry{
+		ID:                stringAttr(item, "id"),
+		ProductID:         stringAttr(item, "product_id"),
+		Actor:             stringAttr(item, "actor"),
+		Source:            entity.PriceSource(stringAttr(item, "source")),
+		ScheduledChangeID: stringAttr(item, "scheduled_change_id"),
+	}
+
+	entry.Price = moneyFromItem(item, "price", "currency")
+	entry.PreviousPrice = moneyFromItem(item, "previous_price", "previous_currency")
+	if effectiveFrom, err := time.Parse(_sortableTimeLayout, stringAttr(item, "effective_from")); err == nil {
+		entry.EffectiveFrom = effectiveFrom
+	}
+
+	return entry
+}
+
+func scheduledPriceChangeFromItem(item map[string]types.AttributeValue) entity.ScheduledPriceChange {
+	change := entity.ScheduledPriceChange{
+		ID:        stringAttr(item, "id"),
+		ProductID: stringAttr(item, "product_id"),
+		Actor:     stringAttr(item, "actor"),
+		Status:    entity.ScheduledPriceStatus(stringAttr(item, "status")),
+	}
+
+	change.Price = moneyFromItem(item, "price", "currency")
+	if effectiveFrom, err := time.Parse(_sortableTimeLayout, stringAttr(item, "effective_from")); err == nil {
+		change.EffectiveFrom = effectiveFrom
+	}
+	if createdAt, err := time.Parse(time.RFC3339, stringAttr(item, "created_at")); err == nil {
+		change.CreatedAt = createdAt
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, stringAttr(item, "updated_at")); err == nil {
+		change.UpdatedAt = updatedAt
+	}
+
+	return change
+}
+
+func sortableTime(t time.Time) string {
+	return t.UTC().Format(_sortableTimeLayout)
+}
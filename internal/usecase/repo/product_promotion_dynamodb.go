@@ -0,0 +1,265 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+)
+
+// PromotionDynamoRepo stores promotions keyed by id, starts_at and ends_at are
+// stored sortable so the active ones can be filtered by comparing strings
+type PromotionDynamoRepo struct {
+	*awsService.DynamoDB
+}
+
+func NewPromotionDynamoRepo(d *awsService.DynamoDB) *PromotionDynamoRepo {
+	return &PromotionDynamoRepo{
+		d,
+	}
+}
+
+func (r *PromotionDynamoRepo) Save(ctx context.Context, promotion *entity.Promotion) error {
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.PromotionTable),
+		Item:      promotionToItem(promotion),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save promotion: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PromotionDynamoRepo) GetAll(ctx context.Context) ([]entity.Promotion, error) {
+	return r.scan(ctx, "attribute_not_exists(deleted_at)", nil)
+}
+
+// GetNotEnded returns the promotions running at the given time and the ones still to start
+func (r *PromotionDynamoRepo) GetNotEnded(ctx context.Context, at time.Time) ([]entity.Promotion, error) {
+	return r.scan(
+		ctx,
+		"attribute_not_exists(deleted_at) AND ends_at > :at",
+		map[string]types.AttributeValue{
+			":at": &types.AttributeValueMemberS{Value: sortableTime(at)},
+		},
+	)
+}
+
+// GetUnannounced returns the promotions whose end was not announced yet
+func (r *PromotionDynamoRepo) GetUnannounced(ctx context.Context) ([]entity.Promotion, error) {
+	return r.scan(
+		ctx,
+		"attribute_not_exists(deleted_at) AND (attribute_not_exists(announced) OR announced <> :ended)",
+		map[string]types.AttributeValue{
+			":ended": &types.AttributeValueMemberS{Value: string(entity.PromotionEnded)},
+		},
+	)
+}
+
+func (r *PromotionDynamoRepo) scan(
+	ctx context.Context,
+	filter string,
+	values map[string]types.AttributeValue,
+) ([]entity.Promotion, error) {
+	promotions := []entity.Promotion{}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:                 aws.String(r.PromotionTable),
+			FilterExpression:          aws.String(filter),
+			ExpressionAttributeValues: values,
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotions: %w", err)
+		}
+
+		for _, item := range result.Items {
+			promotions = append(promotions, promotionFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return promotions, nil
+}
+
+func (r *PromotionDynamoRepo) GetByID(ctx context.Context, id string) (*entity.Promotion, error) {
+	result, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.PromotionTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promotion: %w", err)
+	}
+
+	if result.Item == nil || result.Item["deleted_at"] != nil {
+		return nil, fmt.Errorf("%w, id: %s", entity.ErrPromotionNotFound, id)
+	}
+
+	promotion := promotionFromItem(result.Item)
+	return &promotion, nil
+}
+
+// Update replaces the promotion, it has to exist and not be deleted
+func (r *PromotionDynamoRepo) Update(ctx context.Context, promotion *entity.Promotion) error {
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.PromotionTable),
+		Item:                promotionToItem(promotion),
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w, id: %s", entity.ErrPromotionNotFound, promotion.ID)
+		}
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAnnounced records the announced phase. Nothing is written when the promotion was
+// changed or deleted since it was read, the change announced the prices itself.
+func (r *PromotionDynamoRepo) UpdateAnnounced(ctx context.Context, promotion *entity.Promotion, phase entity.PromotionPhase) error {
+	_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.PromotionTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: promotion.ID},
+		},
+		UpdateExpression: aws.String("SET announced = :announced"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":announced":  &types.AttributeValueMemberS{Value: string(phase)},
+			":updated_at": &types.AttributeValueMemberS{Value: promotion.UpdatedAt.Format(time.RFC3339)},
+		},
+		ConditionExpression: aws.String("updated_at = :updated_at AND attribute_not_exists(deleted_at)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil
+		}
+		return fmt.Errorf("failed to update announced promotion phase: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PromotionDynamoRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.PromotionTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		UpdateExpression: aws.String("SET deleted_at = :deleted_at"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deleted_at": &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		},
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("%w, id: %s", entity.ErrPromotionNotFound, id)
+		}
+		return fmt.Errorf("failed to delete promotion: %w", err)
+	}
+
+	return nil
+}
+
+func promotionToItem(promotion *entity.Promotion) map[string]types.AttributeValue {
+	targets := make([]types.AttributeValue, 0, len(promotion.Targets))
+	for _, target := range promotion.Targets {
+		targets = append(targets, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"type": &types.AttributeValueMemberS{Value: string(target.Type)},
+			"id":   &types.AttributeValueMemberS{Value: target.ID},
+		}})
+	}
+
+	item := map[string]types.AttributeValue{
+		"id":            &types.AttributeValueMemberS{Value: promotion.ID},
+		"name":          &types.AttributeValueMemberS{Value: promotion.Name},
+		"discount_type": &types.AttributeValueMemberS{Value: string(promotion.DiscountType)},
+		"value":         &types.AttributeValueMemberN{Value: strconv.FormatFloat(promotion.Value, 'f', -1, 64)},
+		"targets":       &types.AttributeValueMemberL{Value: targets},
+		"priority":      &types.AttributeValueMemberN{Value: strconv.Itoa(promotion.Priority)},
+		"stackable":     &types.AttributeValueMemberBOOL{Value: promotion.Stackable},
+		"starts_at":     &types.AttributeValueMemberS{Value: sortableTime(promotion.StartsAt)},
+		"ends_at":       &types.AttributeValueMemberS{Value: sortableTime(promotion.EndsAt)},
+		"created_at":    &types.AttributeValueMemberS{Value: promotion.CreatedAt.Format(time.RFC3339)},
+		"updated_at":    &types.AttributeValueMemberS{Value: promotion.UpdatedAt.Format(time.RFC3339)},
+	}
+	if promotion.DiscountType == entity.DiscountFixed {
+		item["amount"] = moneyToAttributeValue(promotion.Amount)
+		item["currency"] = &types.AttributeValueMemberS{Value: promotion.Amount.Currency}
+	}
+	if promotion.Announced != "" {
+		item["announced"] = &types.AttributeValueMemberS{Value: string(promotion.Announced)}
+	}
+
+	return item
+}
+
+func promotionFromItem(item map[string]types.AttributeValue) entity.Promotion {
+	promotion := entity.Promotion{
+		ID:           stringAttr(item, "id"),
+		Name:         stringAttr(item, "name"),
+		DiscountType: entity.DiscountType(stringAttr(item, "discount_type")),
+		Targets:      []entity.PromotionTarget{},
+		Announced:    entity.PromotionPhase(stringAttr(item, "announced")),
+	}
+
+	if value, err := strconv.ParseFloat(numberAttr(item, "value"), 64); err == nil {
+		promotion.Value = value
+	}
+	if promotion.DiscountType == entity.DiscountFixed {
+		promotion.Amount = moneyFromItem(item, "amount", "currency")
+	}
+	if priority, err := strconv.Atoi(numberAttr(item, "priority")); err == nil {
+		promotion.Priority = priority
+	}
+	if stackable, ok := item["stackable"].(*types.AttributeValueMemberBOOL); ok {
+		promotion.Stackable = stackable.Value
+	}
+
+	if targets, ok := item["targets"].(*types.AttributeValueMemberL); ok {
+		for _, v := range targets.Value {
+			if m, ok := v.(*types.AttributeValueMemberM); ok {
+				promotion.Targets = append(promotion.Targets, entity.PromotionTarget{
+					Type: entity.PromotionTargetType(stringAttr(m.Value, "type")),
+					ID:   stringAttr(m.Value, "id"),
+				})
+			}
+		}
+	}
+
+	if startsAt, err := time.Parse(_sortableTimeLayout, stringAttr(item, "starts_at")); err == nil {
+		promotion.StartsAt = startsAt
+	}
+	if endsAt, err := time.Parse(_sortableTimeLayout, stringAttr(item, "ends_at")); err == nil {
+		promotion.EndsAt = endsAt
+	}
+	if createdAt, err := time.Parse(time.RFC3339, stringAttr(item, "created_at")); err == nil {
+		promotion.CreatedAt = createdAt
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, stringAttr(item, "updated_at")); err == nil {
+		promotion.UpdatedAt = updatedAt
+	}
+
+	return promotion
+}
@@ -1,52 +1,459 @@
 package repo
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"mime/multipart"
-	"path/filepath"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-product/internal/entity"
 	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+	"github.com/idoyudha/eshop-product/pkg/imaging"
+)
+
+const (
+	_imagePrefix  = "product/"
+	_uploadPrefix = entity.ImageUploadPrefix
+	_importPrefix = "import/"
+	// how long downloading an image referenced by url in an import may take
+	_imageFetchTimeout = 30 * time.Second
+	// DeleteObjects accepts at most 1000 keys per request
+	_deleteBatchSize = 1000
 )
 
 type ProductS3Repo struct {
 	*awsService.S3Service
+	validator  *imaging.Validator
+	uploadTTL  time.Duration
+	httpClient *http.Client
 }
 
-func NewProductS3Repo(s *awsService.S3Service) *ProductS3Repo {
+func NewProductS3Repo(s *awsService.S3Service, validator *imaging.Validator, uploadTTL time.Duration) *ProductS3Repo {
 	return &ProductS3Repo{
-		s,
+		S3Service:  s,
+		validator:  validator,
+		uploadTTL:  uploadTTL,
+		httpClient: newImageFetchClient(),
+	}
+}
+
+// PresignUpload returns a url the client puts an image to directly, the signature
+// pins the content type and length so the client cannot send something else
+func (r *ProductS3Repo) PresignUpload(ctx context.Context, contentType string, size int64) (*entity.ImageUpload, error) {
+	err := r.validator.CheckDeclared(contentType, size)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate upload token: %w", err)
+	}
+
+	request, err := r.S3Service.Presigner.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(r.ProductBucket),
+		Key:           aws.String(_uploadPrefix + token.String()),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+	}, s3.WithPresignExpires(r.uploadTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	return &entity.ImageUpload{
+		Token: token.String(),
+		URL:   request.URL,
+		Headers: map[string]string{
+			"Content-Type": contentType,
+		},
+		ExpiresAt: time.Now().Add(r.uploadTTL),
+	}, nil
+}
+
+// UploadImage processes the image from the source into its renditions and stores them under
+// product/<product id>/<image id>/, the url and renditions of the image are set on success.
+// An uploaded object is validated like a multipart file and deleted once it is processed.
+// An image given by url or by the key of an upload is validated the same way, the upload
+// behind a key is left in place since more rows of an import can share it.
+func (r *ProductS3Repo) UploadImage(ctx context.Context, productID string, image *entity.ProductImage, source entity.ImageSource) error {
+	if err := source.Validate(); err != nil {
+		return err
+	}
+
+	switch {
+	case source.URL != "":
+		data, err := r.fetchImage(ctx, source.URL)
+		if err != nil {
+			return err
+		}
+		return r.storeImage(ctx, productID, image, data)
+	case source.Key != "":
+		data, err := r.readUpload(ctx, source.Key)
+		if err != nil {
+			return err
+		}
+		return r.storeImage(ctx, productID, image, data)
+	}
+
+	if source.File != nil {
+		src, err := source.File.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer src.Close()
+
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		return r.storeImage(ctx, productID, image, data)
+	}
+
+	if _, err := uuid.Parse(source.UploadToken); err != nil {
+		return entity.ErrImageUploadNotFound
+	}
+	key := _uploadPrefix + source.UploadToken
+
+	data, err := r.readUpload(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	err = r.storeImage(ctx, productID, image, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.S3Service.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(r.ProductBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+
+	return nil
+}
+
+// readUpload loads and validates an object the client uploaded with a presigned url
+func (r *ProductS3Repo) readUpload(ctx context.Context, key string) ([]byte, error) {
+	object, err := r.S3Service.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.ProductBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *s3Types.NoSuchKey
+		if errors.As(err, &notFound) {
+			return nil, entity.ErrImageUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	defer object.Body.Close()
+
+	// the validator stops reading past the size limit
+	var buf bytes.Buffer
+	_, err = r.validator.Validate(io.TeeReader(object.Body, &buf))
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// _nonPublicPrefixes are the ranges an image url of an import may not reach besides the
+// loopback, private, link-local and multicast ones
+var _nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade nat
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // nat64 can map to any ipv4 address
+}
+
+// newImageFetchClient returns the client image urls are downloaded with. The urls come from
+// import files, so it only connects to public addresses: the address is checked after the
+// name is resolved, on every connection including the ones of redirects. No proxy is used,
+// it would connect on behalf of the client.
+func newImageFetchClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: _imageFetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("invalid image address %s: %w", address, err)
+			}
+			if !isPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("image address %s is not public", addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: _imageFetchTimeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range _nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchImage downloads and validates an image from a http(s) url, only public addresses
+// are reached
+func (r *ProductS3Repo) fetchImage(ctx context.Context, imageURL string) ([]byte, error) {
+	parsed, err := url.Parse(imageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid image url: %s", imageURL)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image request: %w", err)
+	}
+
+	response, err := r.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image: %s returned %s", imageURL, response.Status)
+	}
+
+	// the validator stops reading past the size limit
+	var buf bytes.Buffer
+	_, err = r.validator.Validate(io.TeeReader(response.Body, &buf))
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (r *ProductS3Repo) storeImage(ctx context.Context, productID string, image *entity.ProductImage, data []byte) error {
+	outputs, err := imaging.Process(data, imaging.DefaultRenditions)
+	if err != nil {
+		return fmt.Errorf("failed to process image: %w", err)
+	}
+
+	renditions := make([]entity.ImageRendition, len(outputs))
+	errs := make([]error, len(outputs))
+
+	var wg sync.WaitGroup
+	for i, output := range outputs {
+		wg.Add(1)
+		go func(i int, output imaging.Output) {
+			defer wg.Done()
+
+			key := fmt.Sprintf("%s%s/%s/%s%s", _imagePrefix, productID, image.ID, output.Name, output.Extension())
+			_, err := r.S3Service.Client.PutObject(ctx, &s3.PutObjectInput{
+				Bucket:      aws.String(r.ProductBucket),
+				Key:         aws.String(key),
+				Body:        bytes.NewReader(output.Data),
+				ContentType: aws.String(output.ContentType()),
+			})
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to upload file: %w", err)
+				return
+			}
+
+			renditions[i] = entity.ImageRendition{
+				Name:   output.Name,
+				Format: string(output.Format),
+				Width:  output.Width,
+				Height: output.Height,
+				URL:    fmt.Sprintf("%s/%s", r.CDNDomain, key),
+			}
+		}(i, output)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	image.Renditions = renditions
+	for _, rendition := range renditions {
+		if rendition.Name == imaging.OriginalRendition && rendition.Format != string(imaging.FormatWebP) {
+			image.URL = rendition.URL
+		}
 	}
+
+	return nil
 }
 
-func (r *ProductS3Repo) UploadImage(ctx context.Context, file *multipart.FileHeader) (string, error) {
-	if file == nil {
-		return "", fmt.Errorf("file is required")
+// DeleteImage removes the objects behind the url and the renditions of an image
+func (r *ProductS3Repo) DeleteImage(ctx context.Context, image entity.ProductImage) error {
+	urls := []string{image.URL}
+	for _, rendition := range image.Renditions {
+		if rendition.URL != image.URL {
+			urls = append(urls, rendition.URL)
+		}
+	}
+
+	keys := make([]string, 0, len(urls))
+	for _, url := range urls {
+		key := strings.TrimPrefix(url, r.CDNDomain+"/")
+		if key == url {
+			return fmt.Errorf("image url is not served by the cdn: %s", url)
+		}
+		keys = append(keys, key)
 	}
 
-	src, err := file.Open()
+	return r.deleteKeys(ctx, keys)
+}
+
+// ListImages lists every object under product/, the url is the one a product would reference
+func (r *ProductS3Repo) ListImages(ctx context.Context) ([]entity.StoredImage, error) {
+	images, err := r.listObjects(ctx, _imagePrefix)
 	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to list images: %w", err)
 	}
-	defer src.Close()
+	return images, nil
+}
 
-	filename := fmt.Sprintf("product/%s%s", uuid.New().String(), filepath.Ext(file.Filename))
+// ListUploads lists every object under upload/, confirmed uploads are deleted right away
+// so these are the ones still waiting for a confirmation or never confirmed
+func (r *ProductS3Repo) ListUploads(ctx context.Context) ([]entity.StoredImage, error) {
+	uploads, err := r.listObjects(ctx, _uploadPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+	return uploads, nil
+}
 
-	_, err = r.S3Service.Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(r.ProductBucket),
-		Key:         aws.String(filename),
-		Body:        src,
-		ContentType: aws.String(file.Header.Get("Content-Type")),
+func (r *ProductS3Repo) listObjects(ctx context.Context, prefix string) ([]entity.StoredImage, error) {
+	paginator := s3.NewListObjectsV2Paginator(r.S3Service.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.ProductBucket),
+		Prefix: aws.String(prefix),
 	})
 
+	var images []entity.StoredImage
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			images = append(images, entity.StoredImage{
+				Key:          key,
+				URL:          fmt.Sprintf("%s/%s", r.CDNDomain, key),
+				Size:         aws.ToInt64(object.Size),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+
+	return images, nil
+}
+
+// DeleteImages removes the objects in batches, it stops at the first failed batch
+func (r *ProductS3Repo) DeleteImages(ctx context.Context, images []entity.StoredImage) error {
+	keys := make([]string, 0, len(images))
+	for _, image := range images {
+		keys = append(keys, image.Key)
+	}
+
+	for start := 0; start < len(keys); start += _deleteBatchSize {
+		end := start + _deleteBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		err := r.deleteKeys(ctx, keys[start:end])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ProductS3Repo) deleteKeys(ctx context.Context, keys []string) error {
+	objects := make([]s3Types.ObjectIdentifier, 0, len(keys))
+	for _, key := range keys {
+		objects = append(objects, s3Types.ObjectIdentifier{Key: aws.String(key)})
+	}
+
+	output, err := r.S3Service.Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(r.ProductBucket),
+		Delete: &s3Types.Delete{
+			Objects: objects,
+			Quiet:   aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete files: %w", err)
+	}
+	if len(output.Errors) > 0 {
+		return fmt.Errorf("failed to delete file %s: %s", aws.ToString(output.Errors[0].Key), aws.ToString(output.Errors[0].Message))
+	}
+
+	return nil
+}
+
+// SaveImportFile stores the file of an import job until the job has read it
+func (r *ProductS3Repo) SaveImportFile(ctx context.Context, jobID string, file io.Reader, size int64) error {
+	_, err := r.S3Service.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(r.ProductBucket),
+		Key:           aws.String(_importPrefix + jobID + ".csv"),
+		Body:          file,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String("text/csv"),
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file: %w", err)
+		return fmt.Errorf("failed to save import file: %w", err)
 	}
+	return nil
+}
 
-	imageURL := fmt.Sprintf("%s/%s", r.CDNDomain, filename)
+// OpenImportFile streams the file of an import job, the caller closes it
+func (r *ProductS3Repo) OpenImportFile(ctx context.Context, jobID string) (io.ReadCloser, error) {
+	object, err := r.S3Service.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.ProductBucket),
+		Key:    aws.String(_importPrefix + jobID + ".csv"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open import file: %w", err)
+	}
+	return object.Body, nil
+}
 
-	return imageURL, nil
+func (r *ProductS3Repo) DeleteImportFile(ctx context.Context, jobID string) error {
+	return r.deleteKeys(ctx, []string{_importPrefix + jobID + ".csv"})
 }
@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// _exportPartSize is the size of the parts an export is uploaded in, s3 wants at
+// least 5 MiB for every part but the last
+const _exportPartSize = 8 << 20
+
+// UploadExport streams what write produces to the key in the product bucket. Only one
+// part is held in memory, an export smaller than a part is put in a single request.
+// The upload is aborted when write fails, so no partial object is left behind.
+func (r *ProductS3Repo) UploadExport(ctx context.Context, key, contentType string, write func(io.Writer) error) error {
+	w := &exportUploadWriter{
+		ctx:         ctx,
+		repo:        r,
+		key:         key,
+		contentType: contentType,
+	}
+
+	err := write(w)
+	if err == nil {
+		err = w.complete()
+	}
+	if err != nil {
+		w.abort()
+		return fmt.Errorf("failed to upload export: %w", err)
+	}
+	return nil
+}
+
+// exportUploadWriter turns writes into the parts of a multipart upload, the upload
+// is only created once more than one part is needed
+type exportUploadWriter struct {
+	ctx         context.Context
+	repo        *ProductS3Repo
+	key         string
+	contentType string
+	buf         bytes.Buffer
+	uploadID    *string
+	parts       []s3Types.CompletedPart
+}
+
+func (w *exportUploadWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if room := _exportPartSize - w.buf.Len(); n > room {
+			n = room
+		}
+		w.buf.Write(p[:n])
+		p = p[n:]
+		written += n
+
+		if w.buf.Len() == _exportPartSize {
+			if err := w.uploadPart(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (w *exportUploadWriter) uploadPart() error {
+	client := w.repo.S3Service.Client
+
+	if w.uploadID == nil {
+		output, err := client.CreateMultipartUpload(w.ctx, &s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(w.repo.ProductBucket),
+			Key:         aws.String(w.key),
+			ContentType: aws.String(w.contentType),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create multipart upload: %w", err)
+		}
+		w.uploadID = output.UploadId
+	}
+
+	number := int32(len(w.parts) + 1)
+	output, err := client.UploadPart(w.ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(w.repo.ProductBucket),
+		Key:           aws.String(w.key),
+		UploadId:      w.uploadID,
+		PartNumber:    aws.Int32(number),
+		Body:          bytes.NewReader(w.buf.Bytes()),
+		ContentLength: aws.Int64(int64(w.buf.Len())),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload part %d: %w", number, err)
+	}
+
+	w.parts = append(w.parts, s3Types.CompletedPart{ETag: output.ETag, PartNumber: aws.Int32(number)})
+	w.buf.Reset()
+	return nil
+}
+
+func (w *exportUploadWriter) complete() error {
+	if w.uploadID == nil {
+		_, err := w.repo.S3Service.Client.PutObject(w.ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(w.repo.ProductBucket),
+			Key:           aws.String(w.key),
+			Body:          bytes.NewReader(w.buf.Bytes()),
+			ContentLength: aws.Int64(int64(w.buf.Len())),
+			ContentType:   aws.String(w.contentType),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to put export: %w", err)
+		}
+		return nil
+	}
+
+	if w.buf.Len() > 0 {
+		if err := w.uploadPart(); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.repo.S3Service.Client.CompleteMultipartUpload(w.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(w.repo.ProductBucket),
+		Key:             aws.String(w.key),
+		UploadId:        w.uploadID,
+		MultipartUpload: &s3Types.CompletedMultipartUpload{Parts: w.parts},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	w.uploadID = nil
+	return nil
+}
+
+// abort drops the parts uploaded so far, they are billed until then
+func (w *exportUploadWriter) abort() {
+	if w.uploadID == nil {
+		return
+	}
+
+	// the context may be what failed the export. When the abort fails too, the
+	// lifecycle rule of the bucket for incomplete uploads cleans up.
+	_, _ = w.repo.S3Service.Client.AbortMultipartUpload(context.WithoutCancel(w.ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(w.repo.ProductBucket),
+		Key:      aws.String(w.key),
+		UploadId: w.uploadID,
+	})
+}
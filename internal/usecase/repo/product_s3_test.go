@@ -0,0 +1,290 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+	"github.com/idoyudha/eshop-product/pkg/imaging"
+)
+
+// fakeS3 keeps the objects in memory, putErr fails every put
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	deleted []string
+	putErr  error
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string]fakeObject{}}
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if f.putErr != nil {
+		return nil, f.putErr
+	}
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[aws.ToString(in.Key)] = fakeObject{data: data, contentType: aws.ToString(in.ContentType)}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.objects[aws.ToString(in.Key)]
+	if !ok {
+		return nil, &s3Types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(object.data))}, nil
+}
+
+func (f *fakeS3) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.objects, aws.ToString(in.Key))
+	f.deleted = append(f.deleted, aws.ToString(in.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) DeleteObjects(context.Context, *s3.DeleteObjectsInput, ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeS3) ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeS3) CreateMultipartUpload(context.Context, *s3.CreateMultipartUploadInput, ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeS3) UploadPart(context.Context, *s3.UploadPartInput, ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeS3) CompleteMultipartUpload(context.Context, *s3.CompleteMultipartUploadInput, ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeS3) AbortMultipartUpload(context.Context, *s3.AbortMultipartUploadInput, ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+const (
+	_testCDN       = "https://cdn.example.com"
+	_testProductID = "0190a000-0000-7000-8000-000000000001"
+	_testUploadKey = _uploadPrefix + "0190a000-0000-7000-8000-0000000000cc"
+)
+
+func newTestS3Repo(client *fakeS3) *ProductS3Repo {
+	validator := imaging.NewValidator(imaging.Limits{
+		MaxBytes: 1 << 20,
+		Formats:  []imaging.Format{imaging.FormatJPEG, imaging.FormatPNG, imaging.FormatWebP},
+	})
+	return NewProductS3Repo(&awsService.S3Service{
+		Client:        client,
+		ProductBucket: "products",
+		CDNDomain:     _testCDN,
+	}, validator, 0)
+}
+
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadImageStoresRenditions(t *testing.T) {
+	client := newFakeS3()
+	client.objects[_testUploadKey] = fakeObject{data: testJPEG(t, 1600, 1000)}
+	r := newTestS3Repo(client)
+
+	image, err := entity.NewProductImage("shoe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.UploadImage(context.Background(), _testProductID, &image, entity.ImageSource{Key: _testUploadKey})
+	if err != nil {
+		t.Fatalf("UploadImage: %v", err)
+	}
+
+	if len(image.Renditions) != 2*len(imaging.DefaultRenditions) {
+		t.Fatalf("got %d renditions, want %d", len(image.Renditions), 2*len(imaging.DefaultRenditions))
+	}
+
+	prefix := _imagePrefix + _testProductID + "/" + image.ID + "/"
+	for _, rendition := range image.Renditions {
+		key := strings.TrimPrefix(rendition.URL, _testCDN+"/")
+		if !strings.HasPrefix(key, prefix) {
+			t.Errorf("rendition %s is stored at %s, want it under %s", rendition.Name, key, prefix)
+		}
+		object, ok := client.objects[key]
+		if !ok {
+			t.Errorf("rendition %s %s was not uploaded", rendition.Name, rendition.Format)
+			continue
+		}
+		if object.contentType != "image/"+rendition.Format {
+			t.Errorf("%s has content type %s, want image/%s", key, object.contentType, rendition.Format)
+		}
+		if rendition.Name == "thumbnail" && (rendition.Width != 150 || rendition.Height != 93) {
+			t.Errorf("thumbnail is %dx%d, want 150x93", rendition.Width, rendition.Height)
+		}
+	}
+
+	if image.URL != _testCDN+"/"+prefix+"original.jpg" {
+		t.Errorf("image url = %s, want the jpeg original", image.URL)
+	}
+	// an upload given by key can be shared by more import rows, it is left in place
+	if _, ok := client.objects[_testUploadKey]; !ok || len(client.deleted) > 0 {
+		t.Errorf("the source object was deleted: %v", client.deleted)
+	}
+}
+
+func TestUploadImageDeletesProcessedUpload(t *testing.T) {
+	const token = "0190a000-0000-7000-8000-0000000000aa"
+	client := newFakeS3()
+	client.objects[_uploadPrefix+token] = fakeObject{data: testJPEG(t, 300, 200)}
+	r := newTestS3Repo(client)
+
+	image, err := entity.NewProductImage("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.UploadImage(context.Background(), _testProductID, &image, entity.ImageSource{UploadToken: token})
+	if err != nil {
+		t.Fatalf("UploadImage: %v", err)
+	}
+
+	if len(client.deleted) != 1 || client.deleted[0] != _uploadPrefix+token {
+		t.Errorf("deleted %v, want the upload only", client.deleted)
+	}
+}
+
+func TestUploadImageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(*fakeS3)
+		source  entity.ImageSource
+		wantErr error
+	}{
+		{
+			name:    "no source",
+			source:  entity.ImageSource{},
+			wantErr: entity.ErrImageSourceRequired,
+		},
+		{
+			name:    "missing upload",
+			source:  entity.ImageSource{UploadToken: "0190a000-0000-7000-8000-0000000000bb"},
+			wantErr: entity.ErrImageUploadNotFound,
+		},
+		{
+			name:    "invalid upload token",
+			source:  entity.ImageSource{UploadToken: "../product/x"},
+			wantErr: entity.ErrImageUploadNotFound,
+		},
+		{
+			name: "key outside the uploads",
+			setup: func(f *fakeS3) {
+				f.objects["product/other/image/original.jpg"] = fakeObject{data: testJPEG(t, 16, 16)}
+			},
+			source:  entity.ImageSource{Key: "product/other/image/original.jpg"},
+			wantErr: entity.ErrInvalidImageKey,
+		},
+		{
+			name: "key of an import file",
+			setup: func(f *fakeS3) {
+				f.objects["import/job.csv"] = fakeObject{data: testJPEG(t, 16, 16)}
+			},
+			source:  entity.ImageSource{Key: "import/job.csv"},
+			wantErr: entity.ErrInvalidImageKey,
+		},
+		{
+			name:    "upload key without a token",
+			source:  entity.ImageSource{Key: _uploadPrefix + "../product/x"},
+			wantErr: entity.ErrInvalidImageKey,
+		},
+		{
+			name: "not an image",
+			setup: func(f *fakeS3) {
+				f.objects[_testUploadKey] = fakeObject{data: []byte("just some text, no image")}
+			},
+			source:  entity.ImageSource{Key: _testUploadKey},
+			wantErr: imaging.ErrUnsupportedFormat,
+		},
+		{
+			name: "too large",
+			setup: func(f *fakeS3) {
+				f.objects[_testUploadKey] = fakeObject{data: append(testJPEG(t, 16, 16), make([]byte, 1<<20)...)}
+			},
+			source:  entity.ImageSource{Key: _testUploadKey},
+			wantErr: imaging.ErrImageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeS3()
+			if tt.setup != nil {
+				tt.setup(client)
+			}
+			r := newTestS3Repo(client)
+
+			image := entity.ProductImage{ID: "image"}
+			err := r.UploadImage(context.Background(), _testProductID, &image, tt.source)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UploadImage error = %v, want %v", err, tt.wantErr)
+			}
+			if image.URL != "" || len(image.Renditions) > 0 {
+				t.Errorf("the image was changed: %+v", image)
+			}
+		})
+	}
+}
+
+func TestUploadImageFailedPut(t *testing.T) {
+	client := newFakeS3()
+	client.objects[_testUploadKey] = fakeObject{data: testJPEG(t, 64, 64)}
+	client.putErr = errors.New("bucket unavailable")
+	r := newTestS3Repo(client)
+
+	image := entity.ProductImage{ID: "image"}
+	err := r.UploadImage(context.Background(), _testProductID, &image, entity.ImageSource{Key: _testUploadKey})
+	if err == nil || !strings.Contains(err.Error(), "bucket unavailable") {
+		t.Fatalf("UploadImage error = %v, want the put error", err)
+	}
+	if image.URL != "" || len(image.Renditions) > 0 {
+		t.Errorf("the image was changed: %+v", image)
+	}
+}
@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/pkg/search"
+)
+
+const (
+	searchFieldName        = "name"
+	searchFieldDescription = "description"
+	searchFieldSKU         = "sku"
+	searchFieldCategory    = "category"
+)
+
+// ProductSearchRepo is an in-process full-text index of the products, next to it a TF-IDF
+// model of the same products finds similar ones. It keeps a copy of every indexed product
+// so hits can be returned without a database round trip.
+type ProductSearchRepo struct {
+	index      *search.Index
+	similarity *search.Similarity
+	mu         sync.RWMutex
+	products   map[string]entity.Product
+}
+
+func NewProductSearchRepo() *ProductSearchRepo {
+	return &ProductSearchRepo{
+		index: search.NewIndex(
+			search.Field{Name: searchFieldName, Weight: 3},
+			search.Field{Name: searchFieldSKU, Weight: 2},
+			search.Field{Name: searchFieldDescription, Weight: 1},
+		),
+		similarity: search.NewSimilarity(
+			search.Field{Name: searchFieldName, Weight: 3},
+			search.Field{Name: searchFieldCategory, Weight: 2},
+			search.Field{Name: searchFieldDescription, Weight: 1},
+		),
+		products: map[string]entity.Product{},
+	}
+}
+
+func (r *ProductSearchRepo) Index(ctx context.Context, product *entity.Product) error {
+	r.index.Add(product.ID, map[string]string{
+		searchFieldName:        product.Name,
+		searchFieldDescription: product.Description,
+		searchFieldSKU:         product.SKU,
+	})
+	r.similarity.Add(product.ID, map[string]string{
+		searchFieldName:        product.Name,
+		searchFieldDescription: product.Description,
+		// the id is one term, products of the same category share it
+		searchFieldCategory: strings.ReplaceAll(product.CategoryID, "-", ""),
+	})
+
+	r.mu.Lock()
+	r.products[product.ID] = *product
+	r.mu.Unlock()
+
+	return nil
+}
+
+// UpdateQuantity refreshes the stored copy, quantity is not a searchable field
+func (r *ProductSearchRepo) UpdateQuantity(ctx context.Context, productID string, quantity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if product, ok := r.products[productID]; ok {
+		product.Quantity = quantity
+		r.products[productID] = product
+	}
+	return nil
+}
+
+func (r *ProductSearchRepo) Remove(ctx context.Context, productID string) error {
+	r.index.Remove(productID)
+	r.similarity.Remove(productID)
+
+	r.mu.Lock()
+	delete(r.products, productID)
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *ProductSearchRepo) Search(ctx context.Context, query string, limit int) ([]entity.ProductSearchHit, error) {
+	return r.hits(r.index.Search(query, limit)), nil
+}
+
+// Similar returns the indexed products most alike to the product by name, description and
+// category, the score is their cosine similarity
+func (r *ProductSearchRepo) Similar(ctx context.Context, productID string, limit int) ([]entity.ProductSearchHit, error) {
+	return r.hits(r.similarity.Similar(productID, limit)), nil
+}
+
+func (r *ProductSearchRepo) hits(results []search.Result) []entity.ProductSearchHit {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hits := make([]entity.ProductSearchHit, 0, len(results))
+	for _, result := range results {
+		product, ok := r.products[result.ID]
+		if !ok {
+			continue
+		}
+		hits = append(hits, entity.ProductSearchHit{
+			Product: product,
+			Score:   result.Score,
+		})
+	}
+	return hits
+}
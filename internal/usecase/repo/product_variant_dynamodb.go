@@ -0,0 +1,243 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+)
+
+// VariantDynamoRepo stores variants with product_id as partition key and id as sort key
+type VariantDynamoRepo struct {
+	*awsService.DynamoDB
+}
+
+func NewVariantDynamoRepo(d *awsService.DynamoDB) *VariantDynamoRepo {
+	return &VariantDynamoRepo{
+		d,
+	}
+}
+
+func (r *VariantDynamoRepo) Save(ctx context.Context, variant *entity.Variant) error {
+	item := map[string]types.AttributeValue{
+		"product_id": &types.AttributeValueMemberS{Value: variant.ProductID},
+		"id":         &types.AttributeValueMemberS{Value: variant.ID},
+		"sku":        &types.AttributeValueMemberS{Value: variant.SKU},
+		"options":    optionsToAttributeValue(variant.Options),
+		"quantity":   &types.AttributeValueMemberN{Value: strconv.Itoa(variant.Quantity)},
+		"created_at": &types.AttributeValueMemberS{Value: variant.CreatedAt.Format(time.RFC3339)},
+		"updated_at": &types.AttributeValueMemberS{Value: variant.UpdatedAt.Format(time.RFC3339)},
+	}
+	if variant.Price != nil {
+		item["price"] = moneyToAttributeValue(*variant.Price)
+		item["currency"] = &types.AttributeValueMemberS{Value: variant.Price.Currency}
+	}
+
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.VariantTable),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save variant: %w", err)
+	}
+
+	return nil
+}
+
+func (r *VariantDynamoRepo) GetByProductID(ctx context.Context, productID string) ([]entity.Variant, error) {
+	variants := []entity.Variant{}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.VariantTable),
+			KeyConditionExpression: aws.String("product_id = :product_id"),
+			FilterExpression:       aws.String("attribute_not_exists(deleted_at)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":product_id": &types.AttributeValueMemberS{Value: productID},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query variants: %w", err)
+		}
+
+		for _, item := range result.Items {
+			variants = append(variants, variantFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return variants, nil
+}
+
+func (r *VariantDynamoRepo) GetByID(ctx context.Context, productID, variantID string) (*entity.Variant, error) {
+	result, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.VariantTable),
+		Key: map[string]types.AttributeValue{
+			"product_id": &types.AttributeValueMemberS{Value: productID},
+			"id":         &types.AttributeValueMemberS{Value: variantID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variant: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, fmt.Errorf("%w, id: %s", entity.ErrVariantNotFound, variantID)
+	}
+	if _, deleted := result.Item["deleted_at"]; deleted {
+		return nil, fmt.Errorf("%w, id: %s", entity.ErrVariantNotFound, variantID)
+	}
+
+	variant := variantFromItem(result.Item)
+	return &variant, nil
+}
+
+func (r *VariantDynamoRepo) Update(ctx context.Context, variant *entity.Variant) error {
+	updateExpression := "SET #options = :options, updated_at = :updated_at"
+	expAttrValues := map[string]types.AttributeValue{
+		":options":    optionsToAttributeValue(variant.Options),
+		":updated_at": &types.AttributeValueMemberS{Value: variant.UpdatedAt.Format(time.RFC3339)},
+	}
+	if variant.Price != nil {
+		updateExpression += ", price = :price, currency = :currency"
+		expAttrValues[":price"] = moneyToAttributeValue(*variant.Price)
+		expAttrValues[":currency"] = &types.AttributeValueMemberS{Value: variant.Price.Currency}
+	} else {
+		updateExpression += " REMOVE price, currency"
+	}
+
+	output, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.VariantTable),
+		Key: map[string]types.AttributeValue{
+			"product_id": &types.AttributeValueMemberS{Value: variant.ProductID},
+			"id":         &types.AttributeValueMemberS{Value: variant.ID},
+		},
+		UpdateExpression:          aws.String(updateExpression),
+		ExpressionAttributeNames:  map[string]string{"#options": "options"},
+		ExpressionAttributeValues: expAttrValues,
+		ConditionExpression:       aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
+		ReturnValues:              types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if ok := errors.As(err, &ccf); ok {
+			return fmt.Errorf("variant not found or has been deleted, id: %s", variant.ID)
+		}
+		return fmt.Errorf("failed to update variant: %w", err)
+	}
+
+	// the sku and the stock are not part of the update, the stored item holds them
+	*variant = variantFromItem(output.Attributes)
+
+	return nil
+}
+
+func (r *VariantDynamoRepo) Delete(ctx context.Context, productID, variantID string) error {
+	_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.VariantTable),
+		Key: map[string]types.AttributeValue{
+			"product_id": &types.AttributeValueMemberS{Value: productID},
+			"id":         &types.AttributeValueMemberS{Value: variantID},
+		},
+		UpdateExpression: aws.String("SET deleted_at = :deleted_at"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deleted_at": &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		},
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(deleted_at)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if ok := errors.As(err, &ccf); ok {
+			return fmt.Errorf("variant not found or already deleted, id: %s", variantID)
+		}
+		return fmt.Errorf("failed to delete variant: %w", err)
+	}
+
+	return nil
+}
+
+// GetBySKUs returns the variants with one of the skus
+func (r *VariantDynamoRepo) GetBySKUs(ctx context.Context, skus []string) ([]entity.Variant, error) {
+	items, err := scanIn(ctx, r.Client, r.VariantTable, "sku", skus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan variants by sku: %w", err)
+	}
+
+	variants := make([]entity.Variant, 0, len(items))
+	for _, item := range items {
+		variants = append(variants, variantFromItem(item))
+	}
+	return variants, nil
+}
+
+// PurgeByProductID hard-deletes every variant of the product, the soft-deleted ones too
+func (r *VariantDynamoRepo) PurgeByProductID(ctx context.Context, productID string) error {
+	keys, err := collectKeys(ctx, queryPartition(r.Client, r.VariantTable, "product_id", productID), []string{"product_id", "id"})
+	if err != nil {
+		return fmt.Errorf("failed to query variants: %w", err)
+	}
+
+	err = batchDeleteItems(ctx, r.Client, r.VariantTable, keys)
+	if err != nil {
+		return fmt.Errorf("failed to purge variants: %w", err)
+	}
+	return nil
+}
+
+func variantFromItem(item map[string]types.AttributeValue) entity.Variant {
+	variant := entity.Variant{
+		ID:        stringAttr(item, "id"),
+		ProductID: stringAttr(item, "product_id"),
+		SKU:       stringAttr(item, "sku"),
+		Options:   map[string]string{},
+	}
+
+	if options, ok := item["options"].(*types.AttributeValueMemberM); ok {
+		for name, value := range options.Value {
+			if v, ok := value.(*types.AttributeValueMemberS); ok {
+				variant.Options[name] = v.Value
+			}
+		}
+	}
+
+	if numberAttr(item, "price") != "" {
+		price := moneyFromItem(item, "price", "currency")
+		variant.Price = &price
+	}
+	if quantity, err := strconv.Atoi(numberAttr(item, "quantity")); err == nil {
+		variant.Quantity = quantity
+	}
+	if reserved, err := strconv.Atoi(numberAttr(item, "reserved")); err == nil {
+		variant.Reserved = reserved
+	}
+
+	if createdAt, err := time.Parse(time.RFC3339, stringAttr(item, "created_at")); err == nil {
+		variant.CreatedAt = createdAt
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, stringAttr(item, "updated_at")); err == nil {
+		variant.UpdatedAt = updatedAt
+	}
+
+	return variant
+}
+
+func optionsToAttributeValue(options map[string]string) *types.AttributeValueMemberM {
+	value := make(map[string]types.AttributeValue, len(options))
+	for name, option := range options {
+		value[name] = &types.AttributeValueMemberS{Value: option}
+	}
+	return &types.AttributeValueMemberM{Value: value}
+}
@@ -0,0 +1,428 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+)
+
+// ReservationDynamoRepo stores the reservations with order_id as partition key. The
+// status-expires_at-index GSI finds the open reservations that expired. Every change of a
+// reservation and of the product and variant stock it holds is written in one transaction.
+type ReservationDynamoRepo struct {
+	*awsService.DynamoDB
+}
+
+func NewReservationDynamoRepo(d *awsService.DynamoDB) *ReservationDynamoRepo {
+	return &ReservationDynamoRepo{
+		d,
+	}
+}
+
+// Reserve stores the reservation, takes its quantities out of the products and variants
+// and records the movements in the ledger. The movements hold the quantity of every line
+// after the reservation, a product or variant that changed since it was read fails with
+// ErrStockConflict. A line with less stock than it asks for fails the whole reservation,
+// so a quantity never goes below zero.
+func (r *ReservationDynamoRepo) Reserve(ctx context.Context, reservation *entity.Reservation, movements []entity.StockMovement) error {
+	byLine := movementsByLine(movements)
+	items := make([]types.TransactWriteItem, 0, 2*len(reservation.Lines)+1)
+	items = append(items, types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(r.ReservationTable),
+			Item:                reservationToItem(reservation),
+			ConditionExpression: aws.String("attribute_not_exists(order_id)"),
+		},
+	})
+	for _, line := range reservation.Lines {
+		items = append(items, r.lineStockUpdate(line, byLine[lineKey(line.ProductID, line.VariantID)], line.Quantity,
+			"attribute_exists(id) AND attribute_not_exists(deleted_at) AND quantity = :previous"))
+	}
+	for i := range movements {
+		items = append(items, r.movementPut(&movements[i]))
+	}
+
+	_, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		reasons, ok := transactCancellationReasons(err)
+		if !ok {
+			return fmt.Errorf("failed to reserve stock: %w", err)
+		}
+		if conditionFailed(reasons, 0) {
+			return fmt.Errorf("%w, order: %s", entity.ErrReservationExists, reservation.OrderID)
+		}
+
+		shortage := &entity.StockShortageError{}
+		conflict := false
+		for i, line := range reservation.Lines {
+			if !conditionFailed(reasons, i+1) {
+				continue
+			}
+			old := reasons[i+1].Item
+			quantity, _ := strconv.Atoi(numberAttr(old, "quantity"))
+			if old == nil || old["deleted_at"] != nil || quantity < line.Quantity {
+				shortage.Add(line)
+			} else {
+				conflict = true
+			}
+		}
+		if !shortage.Empty() {
+			return shortage
+		}
+		if conflict {
+			return fmt.Errorf("%w, order: %s", entity.ErrStockConflict, reservation.OrderID)
+		}
+		return fmt.Errorf("%w, order: %s", entity.ErrDuplicateStockEvent, reservation.OrderID)
+	}
+
+	return nil
+}
+
+// Release closes the open reservation with the status, puts its quantities back on the
+// products and variants and records the movements in the ledger. The movements hold the
+// quantity of every line after the release, a product or variant that changed since it was
+// read fails with ErrStockConflict. A line without a movement belongs to a purged product
+// or a deleted variant, it is left out.
+func (r *ReservationDynamoRepo) Release(ctx context.Context, reservation *entity.Reservation, movements []entity.StockMovement) error {
+	return r.close(ctx, reservation, movementsByLine(movements))
+}
+
+// Commit closes the open reservation as committed, the quantities it held are sold
+// and only leave the reserved count of the products and variants
+func (r *ReservationDynamoRepo) Commit(ctx context.Context, reservation *entity.Reservation) error {
+	return r.close(ctx, reservation, nil)
+}
+
+// close stores the status of the reservation if it is still open and gives the reserved
+// count of every line back. For a release the quantities are put back as the movements
+// say and the movements are recorded. A product or variant purged in the meantime has
+// nothing left to give back, its line is dropped and the rest is written again.
+func (r *ReservationDynamoRepo) close(ctx context.Context, reservation *entity.Reservation, movements map[string]*entity.StockMovement) error {
+	released := reservation.Status != entity.ReservationCommitted
+	lines := make([]entity.ReservationLine, 0, len(reservation.Lines))
+	for _, line := range reservation.Lines {
+		if !released || movements[lineKey(line.ProductID, line.VariantID)] != nil {
+			lines = append(lines, line)
+		}
+	}
+
+	for {
+		items := make([]types.TransactWriteItem, 0, 2*len(lines)+1)
+		items = append(items, types.TransactWriteItem{
+			Update: &types.Update{
+				TableName:           aws.String(r.ReservationTable),
+				Key:                 map[string]types.AttributeValue{"order_id": &types.AttributeValueMemberS{Value: reservation.OrderID}},
+				UpdateExpression:    aws.String("SET #status = :status, updated_at = :updated_at"),
+				ConditionExpression: aws.String("#status = :open"),
+				ExpressionAttributeNames: map[string]string{
+					"#status": "status",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":status":     &types.AttributeValueMemberS{Value: string(reservation.Status)},
+					":open":       &types.AttributeValueMemberS{Value: string(entity.ReservationReserved)},
+					":updated_at": &types.AttributeValueMemberS{Value: reservation.UpdatedAt.Format(time.RFC3339)},
+				},
+			},
+		})
+		for _, line := range lines {
+			if !released {
+				table, key := r.lineItem(line)
+				items = append(items, types.TransactWriteItem{
+					Update: &types.Update{
+						TableName:           aws.String(table),
+						Key:                 key,
+						UpdateExpression:    aws.String("ADD reserved :reserved"),
+						ConditionExpression: aws.String("attribute_exists(id)"),
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":reserved": &types.AttributeValueMemberN{Value: strconv.Itoa(-line.Quantity)},
+						},
+						ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+					},
+				})
+				continue
+			}
+			items = append(items, r.lineStockUpdate(line, movements[lineKey(line.ProductID, line.VariantID)], -line.Quantity,
+				"attribute_exists(id) AND quantity = :previous"))
+		}
+		if released {
+			for _, line := range lines {
+				items = append(items, r.movementPut(movements[lineKey(line.ProductID, line.VariantID)]))
+			}
+		}
+
+		_, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if err == nil {
+			return nil
+		}
+
+		reasons, ok := transactCancellationReasons(err)
+		if !ok {
+			return fmt.Errorf("failed to close reservation: %w", err)
+		}
+		if conditionFailed(reasons, 0) {
+			return fmt.Errorf("%w, order: %s", entity.ErrReservationClosed, reservation.OrderID)
+		}
+
+		kept := make([]entity.ReservationLine, 0, len(lines))
+		for i, line := range lines {
+			if !conditionFailed(reasons, i+1) {
+				kept = append(kept, line)
+				continue
+			}
+			// the product or variant is still there, its quantity changed since it was read
+			if reasons[i+1].Item != nil {
+				return fmt.Errorf("%w, order: %s", entity.ErrStockConflict, reservation.OrderID)
+			}
+		}
+		if len(kept) == len(lines) {
+			return fmt.Errorf("%w, order: %s", entity.ErrDuplicateStockEvent, reservation.OrderID)
+		}
+		lines = kept
+	}
+}
+
+// lineStockUpdate sets the quantity of the line product or variant to the one of its
+// movement and adds to its reserved count. It has to still hold the quantity it was read with.
+func (r *ReservationDynamoRepo) lineStockUpdate(
+	line entity.ReservationLine,
+	movement *entity.StockMovement,
+	reserved int,
+	condition string,
+) types.TransactWriteItem {
+	table, key := r.lineItem(line)
+	return types.TransactWriteItem{
+		Update: &types.Update{
+			TableName:           aws.String(table),
+			Key:                 key,
+			UpdateExpression:    aws.String("SET quantity = :quantity ADD reserved :reserved"),
+			ConditionExpression: aws.String(condition),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":quantity": &types.AttributeValueMemberN{Value: strconv.Itoa(movement.Quantity)},
+				":previous": &types.AttributeValueMemberN{Value: strconv.Itoa(movement.Quantity - movement.Delta)},
+				":reserved": &types.AttributeValueMemberN{Value: strconv.Itoa(reserved)},
+			},
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		},
+	}
+}
+
+func (r *ReservationDynamoRepo) movementPut(movement *entity.StockMovement) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(r.StockMovementTable),
+			Item:                stockMovementToItem(movement),
+			ConditionExpression: aws.String("attribute_not_exists(event_id)"),
+		},
+	}
+}
+
+// lineItem returns the table and the key of the item holding the stock of the line,
+// the variant when the line has one
+func (r *ReservationDynamoRepo) lineItem(line entity.ReservationLine) (string, map[string]types.AttributeValue) {
+	if line.VariantID != "" {
+		return r.VariantTable, map[string]types.AttributeValue{
+			"product_id": &types.AttributeValueMemberS{Value: line.ProductID},
+			"id":         &types.AttributeValueMemberS{Value: line.VariantID},
+		}
+	}
+	return r.ProductTable, productKey(line.ProductID, line.CategoryID)
+}
+
+// lineKey tells the lines of a reservation apart, a product can have a line of its own
+// and one for every variant
+func lineKey(productID, variantID string) string {
+	return productID + "/" + variantID
+}
+
+func movementsByLine(movements []entity.StockMovement) map[string]*entity.StockMovement {
+	byLine := make(map[string]*entity.StockMovement, len(movements))
+	for i := range movements {
+		byLine[lineKey(movements[i].ProductID, movements[i].VariantID)] = &movements[i]
+	}
+	return byLine
+}
+
+func (r *ReservationDynamoRepo) GetByOrderID(ctx context.Context, orderID string) (*entity.Reservation, error) {
+	result, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.ReservationTable),
+		Key:       map[string]types.AttributeValue{"order_id": &types.AttributeValueMemberS{Value: orderID}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reservation: %w", err)
+	}
+	if result.Item == nil {
+		return nil, fmt.Errorf("%w, order: %s", entity.ErrReservationNotFound, orderID)
+	}
+
+	return reservationFromItem(result.Item), nil
+}
+
+// GetExpired returns the open reservations that expired before the time
+func (r *ReservationDynamoRepo) GetExpired(ctx context.Context, before time.Time) ([]entity.Reservation, error) {
+	reservations := []entity.Reservation{}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.ReservationTable),
+			IndexName:              aws.String("status-expires_at-index"),
+			KeyConditionExpression: aws.String("#status = :status AND expires_at <= :before"),
+			ExpressionAttributeNames: map[string]string{
+				"#status": "status",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":status": &types.AttributeValueMemberS{Value: string(entity.ReservationReserved)},
+				":before": &types.AttributeValueMemberS{Value: sortableTime(before)},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query expired reservations: %w", err)
+		}
+
+		for _, item := range result.Items {
+			reservations = append(reservations, *reservationFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return reservations, nil
+}
+
+// failedTransactItems returns the indexes of the items whose condition failed
+// when err is a cancelled transaction
+func failedTransactItems(err error) ([]int, bool) {
+	var tce *types.TransactionCanceledException
+	if !errors.As(err, &tce) {
+		return nil, false
+	}
+
+	var failed []int
+	for i, reason := range tce.CancellationReasons {
+		if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+			failed = append(failed, i)
+		}
+	}
+	return failed, true
+}
+
+// transactCancellationReasons returns the reason of every item of a cancelled transaction,
+// in the order of the items
+func transactCancellationReasons(err error) ([]types.CancellationReason, bool) {
+	var tce *types.TransactionCanceledException
+	if !errors.As(err, &tce) {
+		return nil, false
+	}
+	return tce.CancellationReasons, true
+}
+
+func conditionFailed(reasons []types.CancellationReason, i int) bool {
+	return i < len(reasons) && aws.ToString(reasons[i].Code) == "ConditionalCheckFailed"
+}
+
+func productKey(productID, categoryID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":          &types.AttributeValueMemberS{Value: productID},
+		"category_id": &types.AttributeValueMemberS{Value: categoryID},
+	}
+}
+
+func reservationToItem(reservation *entity.Reservation) map[string]types.AttributeValue {
+	orderItems := make([]types.AttributeValue, 0, len(reservation.Items))
+	for _, item := range reservation.Items {
+		fields := map[string]types.AttributeValue{
+			"product_id": &types.AttributeValueMemberS{Value: item.ProductID},
+			"quantity":   &types.AttributeValueMemberN{Value: strconv.Itoa(item.Quantity)},
+		}
+		if item.VariantID != "" {
+			fields["variant_id"] = &types.AttributeValueMemberS{Value: item.VariantID}
+		}
+		orderItems = append(orderItems, &types.AttributeValueMemberM{Value: fields})
+	}
+	lines := make([]types.AttributeValue, 0, len(reservation.Lines))
+	for _, line := range reservation.Lines {
+		fields := map[string]types.AttributeValue{
+			"product_id":  &types.AttributeValueMemberS{Value: line.ProductID},
+			"category_id": &types.AttributeValueMemberS{Value: line.CategoryID},
+			"quantity":    &types.AttributeValueMemberN{Value: strconv.Itoa(line.Quantity)},
+		}
+		if line.VariantID != "" {
+			fields["variant_id"] = &types.AttributeValueMemberS{Value: line.VariantID}
+		}
+		lines = append(lines, &types.AttributeValueMemberM{Value: fields})
+	}
+
+	return map[string]types.AttributeValue{
+		"order_id":   &types.AttributeValueMemberS{Value: reservation.OrderID},
+		"items":      &types.AttributeValueMemberL{Value: orderItems},
+		"lines":      &types.AttributeValueMemberL{Value: lines},
+		"status":     &types.AttributeValueMemberS{Value: string(reservation.Status)},
+		"expires_at": &types.AttributeValueMemberS{Value: sortableTime(reservation.ExpiresAt)},
+		"created_at": &types.AttributeValueMemberS{Value: reservation.CreatedAt.Format(time.RFC3339)},
+		"updated_at": &types.AttributeValueMemberS{Value: reservation.UpdatedAt.Format(time.RFC3339)},
+	}
+}
+
+func reservationFromItem(item map[string]types.AttributeValue) *entity.Reservation {
+	reservation := &entity.Reservation{
+		OrderID: stringAttr(item, "order_id"),
+		Status:  entity.ReservationStatus(stringAttr(item, "status")),
+	}
+
+	if list, ok := item["items"].(*types.AttributeValueMemberL); ok {
+		for _, value := range list.Value {
+			fields, ok := value.(*types.AttributeValueMemberM)
+			if !ok {
+				continue
+			}
+			orderItem := entity.OrderItem{
+				ProductID: stringAttr(fields.Value, "product_id"),
+				VariantID: stringAttr(fields.Value, "variant_id"),
+			}
+			if quantity, err := strconv.Atoi(numberAttr(fields.Value, "quantity")); err == nil {
+				orderItem.Quantity = quantity
+			}
+			reservation.Items = append(reservation.Items, orderItem)
+		}
+	}
+	if list, ok := item["lines"].(*types.AttributeValueMemberL); ok {
+		for _, value := range list.Value {
+			fields, ok := value.(*types.AttributeValueMemberM)
+			if !ok {
+				continue
+			}
+			line := entity.ReservationLine{
+				ProductID:  stringAttr(fields.Value, "product_id"),
+				VariantID:  stringAttr(fields.Value, "variant_id"),
+				CategoryID: stringAttr(fields.Value, "category_id"),
+			}
+			if quantity, err := strconv.Atoi(numberAttr(fields.Value, "quantity")); err == nil {
+				line.Quantity = quantity
+			}
+			reservation.Lines = append(reservation.Lines, line)
+		}
+	}
+
+	if expiresAt, err := time.Parse(_sortableTimeLayout, stringAttr(item, "expires_at")); err == nil {
+		reservation.ExpiresAt = expiresAt
+	}
+	if createdAt, err := time.Parse(time.RFC3339, stringAttr(item, "created_at")); err == nil {
+		reservation.CreatedAt = createdAt
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, stringAttr(item, "updated_at")); err == nil {
+		reservation.UpdatedAt = updatedAt
+	}
+	return reservation
+}
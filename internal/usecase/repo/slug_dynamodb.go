@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+)
+
+// SlugDynamoRepo stores every slug ever given out with kind as partition key and slug
+// as sort key. The conditional put is what keeps the slugs unique, the slug-index GSI
+// of the product and category tables only finds the current slug.
+type SlugDynamoRepo struct {
+	*awsService.DynamoDB
+}
+
+func NewSlugDynamoRepo(d *awsService.DynamoDB) *SlugDynamoRepo {
+	return &SlugDynamoRepo{
+		d,
+	}
+}
+
+// Reserve claims the slug for its target, a target can take back a slug it had before.
+// It reports whether the slug is new, false when it was taken back.
+func (r *SlugDynamoRepo) Reserve(ctx context.Context, slug *entity.Slug) (bool, error) {
+	output, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.SlugTable),
+		Item: map[string]types.AttributeValue{
+			"kind":       &types.AttributeValueMemberS{Value: string(slug.Kind)},
+			"slug":       &types.AttributeValueMemberS{Value: slug.Slug},
+			"target_id":  &types.AttributeValueMemberS{Value: slug.TargetID},
+			"created_at": &types.AttributeValueMemberS{Value: slug.CreatedAt.Format(time.RFC3339)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(slug) OR target_id = :target_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":target_id": &types.AttributeValueMemberS{Value: slug.TargetID},
+		},
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, fmt.Errorf("%w: %s", entity.ErrSlugTaken, slug.Slug)
+		}
+		return false, fmt.Errorf("failed to reserve slug: %w", err)
+	}
+	return len(output.Attributes) == 0, nil
+}
+
+// Release gives back a slug reserved for a write that failed, a slug that does not belong
+// to the target is left alone
+func (r *SlugDynamoRepo) Release(ctx context.Context, kind entity.SlugKind, slug, targetID string) error {
+	_, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.SlugTable),
+		Key: map[string]types.AttributeValue{
+			"kind": &types.AttributeValueMemberS{Value: string(kind)},
+			"slug": &types.AttributeValueMemberS{Value: slug},
+		},
+		ConditionExpression: aws.String("target_id = :target_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":target_id": &types.AttributeValueMemberS{Value: targetID},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil
+		}
+		return fmt.Errorf("failed to release slug: %w", err)
+	}
+	return nil
+}
+
+func (r *SlugDynamoRepo) GetBySlug(ctx context.Context, kind entity.SlugKind, slug string) (*entity.Slug, error) {
+	result, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.SlugTable),
+		Key: map[string]types.AttributeValue{
+			"kind": &types.AttributeValueMemberS{Value: string(kind)},
+			"slug": &types.AttributeValueMemberS{Value: slug},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slug: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, fmt.Errorf("%w, slug: %s", entity.ErrSlugNotFound, slug)
+	}
+
+	reserved := &entity.Slug{
+		Kind:     entity.SlugKind(stringAttr(result.Item, "kind")),
+		Slug:     stringAttr(result.Item, "slug"),
+		TargetID: stringAttr(result.Item, "target_id"),
+	}
+	if createdAt, err := time.Parse(time.RFC3339, stringAttr(result.Item, "created_at")); err == nil {
+		reserved.CreatedAt = createdAt
+	}
+	return reserved, nil
+}
+
+// PurgeByTarget deletes every slug the target ever had, so they can be given out again
+func (r *SlugDynamoRepo) PurgeByTarget(ctx context.Context, kind entity.SlugKind, targetID string) error {
+	fetch := func(
+		ctx context.Context,
+		startKey map[string]types.AttributeValue,
+		limit int32,
+	) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.SlugTable),
+			KeyConditionExpression: aws.String("kind = :kind"),
+			FilterExpression:       aws.String("target_id = :target_id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":kind":      &types.AttributeValueMemberS{Value: string(kind)},
+				":target_id": &types.AttributeValueMemberS{Value: targetID},
+			},
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query slugs: %w", err)
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	}
+
+	keys, err := collectKeys(ctx, fetch, []string{"kind", "slug"})
+	if err != nil {
+		return err
+	}
+
+	err = batchDeleteItems(ctx, r.Client, r.SlugTable, keys)
+	if err != nil {
+		return fmt.Errorf("failed to purge slugs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+	awsService "github.com/idoyudha/eshop-product/pkg/aws"
+)
+
+// StockMovementDynamoRepo stores the inventory ledger with product_id as partition key and
+// event_id as sort key, so an event is recorded once per product. The
+// product_id-created_at-index LSI lists the movements of a product in time order.
+type StockMovementDynamoRepo struct {
+	*awsService.DynamoDB
+}
+
+func NewStockMovementDynamoRepo(d *awsService.DynamoDB) *StockMovementDynamoRepo {
+	return &StockMovementDynamoRepo{
+		d,
+	}
+}
+
+// Apply records the movement and stores its quantity on the product in one transaction.
+// The product has to still hold the quantity and the reserved count it was read with,
+// otherwise ErrStockConflict is returned and nothing is written.
+func (r *StockMovementDynamoRepo) Apply(ctx context.Context, movement *entity.StockMovement, product *entity.Product) error {
+	values := map[string]types.AttributeValue{
+		":quantity": &types.AttributeValueMemberN{Value: strconv.Itoa(movement.Quantity)},
+		":previous": &types.AttributeValueMemberN{Value: strconv.Itoa(product.Quantity)},
+		":reserved": &types.AttributeValueMemberN{Value: strconv.Itoa(product.Reserved)},
+	}
+	condition := "attribute_exists(id) AND quantity = :previous AND reserved = :reserved"
+	if product.Reserved == 0 {
+		condition = "attribute_exists(id) AND quantity = :previous AND (attribute_not_exists(reserved) OR reserved = :reserved)"
+	}
+
+	_, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           aws.String(r.StockMovementTable),
+					Item:                stockMovementToItem(movement),
+					ConditionExpression: aws.String("attribute_not_exists(event_id)"),
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName:                 aws.String(r.ProductTable),
+					Key:                       productKey(product.ID, product.CategoryID),
+					UpdateExpression:          aws.String("SET quantity = :quantity"),
+					ConditionExpression:       aws.String(condition),
+					ExpressionAttributeValues: values,
+				},
+			},
+		},
+	})
+	if err != nil {
+		failed, ok := failedTransactItems(err)
+		if !ok || len(failed) == 0 {
+			return fmt.Errorf("failed to apply stock movement: %w", err)
+		}
+		if failed[0] == 0 {
+			return fmt.Errorf("%w, product: %s, event: %s", entity.ErrDuplicateStockEvent, movement.ProductID, movement.EventID)
+		}
+		return fmt.Errorf("%w, product: %s", entity.ErrStockConflict, movement.ProductID)
+	}
+
+	return nil
+}
+
+// ApplyVariant records the movement and stores its quantity on the variant in one
+// transaction. The variant has to still hold the quantity and the reserved count it was
+// read with and not be deleted, otherwise ErrStockConflict is returned and nothing is written.
+func (r *StockMovementDynamoRepo) ApplyVariant(ctx context.Context, movement *entity.StockMovement, variant *entity.Variant) error {
+	condition := "attribute_exists(id) AND attribute_not_exists(deleted_at) AND quantity = :previous AND reserved = :reserved"
+	if variant.Reserved == 0 {
+		condition = "attribute_exists(id) AND attribute_not_exists(deleted_at) AND quantity = :previous AND (attribute_not_exists(reserved) OR reserved = :reserved)"
+	}
+
+	_, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           aws.String(r.StockMovementTable),
+					Item:                stockMovementToItem(movement),
+					ConditionExpression: aws.String("attribute_not_exists(event_id)"),
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName: aws.String(r.VariantTable),
+					Key: map[string]types.AttributeValue{
+						"product_id": &types.AttributeValueMemberS{Value: variant.ProductID},
+						"id":         &types.AttributeValueMemberS{Value: variant.ID},
+					},
+					UpdateExpression:    aws.String("SET quantity = :quantity"),
+					ConditionExpression: aws.String(condition),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":quantity": &types.AttributeValueMemberN{Value: strconv.Itoa(movement.Quantity)},
+						":previous": &types.AttributeValueMemberN{Value: strconv.Itoa(variant.Quantity)},
+						":reserved": &types.AttributeValueMemberN{Value: strconv.Itoa(variant.Reserved)},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		failed, ok := failedTransactItems(err)
+		if !ok || len(failed) == 0 {
+			return fmt.Errorf("failed to apply variant stock movement: %w", err)
+		}
+		if failed[0] == 0 {
+			return fmt.Errorf("%w, product: %s, event: %s", entity.ErrDuplicateStockEvent, movement.ProductID, movement.EventID)
+		}
+		return fmt.Errorf("%w, variant: %s", entity.ErrStockConflict, variant.ID)
+	}
+
+	return nil
+}
+
+// GetByProductID returns the movements of a product, newest first
+func (r *StockMovementDynamoRepo) GetByProductID(ctx context.Context, productID string, page entity.Pagination) ([]entity.StockMovement, string, error) {
+	fetch := func(
+		ctx context.Context,
+		startKey map[string]types.AttributeValue,
+		limit int32,
+	) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(r.StockMovementTable),
+			IndexName:              aws.String("product_id-created_at-index"),
+			KeyConditionExpression: aws.String("product_id = :product_id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":product_id": &types.AttributeValueMemberS{Value: productID},
+			},
+			ScanIndexForward:  aws.Bool(false),
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query stock movements: %w", err)
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	}
+
+	items, nextCursor, err := collectPage(ctx, page, fetch)
+	if err != nil {
+		return nil, "", err
+	}
+
+	movements := make([]entity.StockMovement, 0, len(items))
+	for _, item := range items {
+		movements = append(movements, stockMovementFromItem(item))
+	}
+	return movements, nextCursor, nil
+}
+
+func stockMovementToItem(movement *entity.StockMovement) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"product_id": &types.AttributeValueMemberS{Value: movement.ProductID},
+		"event_id":   &types.AttributeValueMemberS{Value: movement.EventID},
+		"delta":      &types.AttributeValueMemberN{Value: strconv.Itoa(movement.Delta)},
+		"quantity":   &types.AttributeValueMemberN{Value: strconv.Itoa(movement.Quantity)},
+		"reason":     &types.AttributeValueMemberS{Value: string(movement.Reason)},
+		"source":     &types.AttributeValueMemberS{Value: movement.Source},
+		"created_at": &types.AttributeValueMemberS{Value: sortableTime(movement.CreatedAt)},
+	}
+	if movement.VariantID != "" {
+		item["variant_id"] = &types.AttributeValueMemberS{Value: movement.VariantID}
+	}
+	return item
+}
+
+func stockMovementFromItem(item map[string]types.AttributeValue) entity.StockMovement {
+	movement := entity.StockMovement{
+		ProductID: stringAttr(item, "product_id"),
+		VariantID: stringAttr(item, "variant_id"),
+		EventID:   stringAttr(item, "event_id"),
+		Reason:    entity.StockMovementReason(stringAttr(item, "reason")),
+		Source:    stringAttr(item, "source"),
+	}
+	if delta, err := strconv.Atoi(numberAttr(item, "delta")); err == nil {
+		movement.Delta = delta
+	}
+	if quantity, err := strconv.Atoi(numberAttr(item, "quantity")); err == nil {
+		movement.Quantity = quantity
+	}
+	if createdAt, err := time.Parse(_sortableTimeLayout, stringAttr(item, "created_at")); err == nil {
+		movement.CreatedAt = createdAt
+	}
+	return movement
+}
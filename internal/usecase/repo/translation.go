@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/idoyudha/eshop-product/internal/entity"
+)
+
+// translations are stored as a map of locale to a map of the translated fields
+
+func productTranslationsToAttributeValue(translations map[string]entity.ProductTranslation) *types.AttributeValueMemberM {
+	m := make(map[string]types.AttributeValue, len(translations))
+	for locale, translation := range translations {
+		m[locale] = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"name":        &types.AttributeValueMemberS{Value: translation.Name},
+			"description": &types.AttributeValueMemberS{Value: translation.Description},
+		}}
+	}
+	return &types.AttributeValueMemberM{Value: m}
+}
+
+func productTranslationsFromItem(item map[string]types.AttributeValue) map[string]entity.ProductTranslation {
+	translations := make(map[string]entity.ProductTranslation)
+	m, ok := item["translations"].(*types.AttributeValueMemberM)
+	if !ok {
+		return translations
+	}
+
+	for locale, value := range m.Value {
+		fields, ok := value.(*types.AttributeValueMemberM)
+		if !ok {
+			continue
+		}
+		translations[locale] = entity.ProductTranslation{
+			Name:        stringAttr(fields.Value, "name"),
+			Description: stringAttr(fields.Value, "description"),
+		}
+	}
+	return translations
+}
+
+func categoryTranslationsToAttributeValue(translations map[string]entity.CategoryTranslation) *types.AttributeValueMemberM {
+	m := make(map[string]types.AttributeValue, len(translations))
+	for locale, translation := range translations {
+		m[locale] = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"name": &types.AttributeValueMemberS{Value: translation.Name},
+		}}
+	}
+	return &types.AttributeValueMemberM{Value: m}
+}
+
+func categoryTranslationsFromItem(item map[string]types.AttributeValue) map[string]entity.CategoryTranslation {
+	translations := make(map[string]entity.CategoryTranslation)
+	m, ok := item["translations"].(*types.AttributeValueMemberM)
+	if !ok {
+		return translations
+	}
+
+	for locale, value := range m.Value {
+		fields, ok := value.(*types.AttributeValueMemberM)
+		if !ok {
+			continue
+		}
+		translations[locale] = entity.CategoryTranslation{
+			Name: stringAttr(fields.Value, "name"),
+		}
+	}
+	return translations
+}
@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// _versionIncrement is the update expression part counting an edit, items stored before
+// versions count as version 1
+const _versionIncrement = "version = if_not_exists(version, :version_one) + :version_one"
+
+// versionValues adds the values _versionIncrement and versionCondition refer to
+func versionValues(values map[string]types.AttributeValue, expected int64) {
+	values[":version_one"] = &types.AttributeValueMemberN{Value: "1"}
+	if expected > 0 {
+		values[":version"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expected, 10)}
+	}
+}
+
+// versionCondition checks that the item still has the expected version, an expected
+// version of 0 checks nothing
+func versionCondition(expected int64) string {
+	switch {
+	case expected == 0:
+		return ""
+	case expected == 1:
+		return " AND (attribute_not_exists(version) OR version = :version)"
+	default:
+		return " AND version = :version"
+	}
+}
+
+func versionFromItem(item map[string]types.AttributeValue) int64 {
+	version, err := strconv.ParseInt(numberAttr(item, "version"), 10, 64)
+	if err != nil {
+		return 1
+	}
+	return version
+}
@@ -0,0 +1,511 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/pkg/kafka"
+)
+
+const (
+	stockReservedTopic          = "stock-reserved"
+	stockReservationFailedTopic = "stock-reservation-failed"
+)
+
+type kafkaReservedItem struct {
+	ProductID string `json:"product_id"`
+	VariantID string `json:"variant_id,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+type kafkaStockReservedMessage struct {
+	OrderID   string              `json:"order_id"`
+	Items     []kafkaReservedItem `json:"items"`
+	ExpiresAt time.Time           `json:"expires_at"`
+}
+
+type kafkaStockReservationFailedMessage struct {
+	OrderID    string    `json:"order_id"`
+	Reason     string    `json:"reason"`
+	ProductIDs []string  `json:"product_ids,omitempty"` // the products short of stock
+	VariantIDs []string  `json:"variant_ids,omitempty"` // the variants short of stock
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+type ReservationUseCase struct {
+	reservationRepoDynamo ReservationDynamoRepo
+	productRepoDynamo     ProductDynamoRepo
+	productRepoSearch     ProductSearchRepo
+	bundleRepoDynamo      BundleDynamoRepo
+	priceRepoDynamo       PriceDynamoRepo
+	variantRepoDynamo     VariantDynamoRepo
+	promotions            *PromotionEvaluator
+	producer              *kafka.ProducerServer
+	ttl                   time.Duration
+	stockAlerts           entity.StockAlertPolicy
+}
+
+func NewReservationUseCase(
+	reservationRepoDynamo ReservationDynamoRepo,
+	productRepoDynamo ProductDynamoRepo,
+	productRepoSearch ProductSearchRepo,
+	bundleRepoDynamo BundleDynamoRepo,
+	priceRepoDynamo PriceDynamoRepo,
+	variantRepoDynamo VariantDynamoRepo,
+	promotions *PromotionEvaluator,
+	producer *kafka.ProducerServer,
+	ttl time.Duration,
+	stockAlerts entity.StockAlertPolicy,
+) *ReservationUseCase {
+	return &ReservationUseCase{
+		reservationRepoDynamo: reservationRepoDynamo,
+		productRepoDynamo:     productRepoDynamo,
+		productRepoSearch:     productRepoSearch,
+		bundleRepoDynamo:      bundleRepoDynamo,
+		priceRepoDynamo:       priceRepoDynamo,
+		variantRepoDynamo:     variantRepoDynamo,
+		promotions:            promotions,
+		producer:              producer,
+		ttl:                   ttl,
+		stockAlerts:           stockAlerts,
+	}
+}
+
+// ReserveStock holds the stock of the order items until the order is paid, cancelled or the
+// reservation expires. The outcome is sent to stock-reserved or stock-reservation-failed.
+// An order reserved before is not reserved twice, its open reservation is announced again.
+func (u *ReservationUseCase) ReserveStock(ctx context.Context, orderID string, items []entity.OrderItem) (*entity.Reservation, error) {
+	reservation, err := u.prepareReservation(ctx, orderID, items)
+	if isReservationFailure(err) {
+		return u.reservationFailed(ctx, orderID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// a redelivered order finds its stock taken already, so the reservation is looked up first
+	existing, err := u.reservationRepoDynamo.GetByOrderID(ctx, orderID)
+	if err == nil {
+		return u.reservedBefore(existing)
+	}
+	if !errors.Is(err, entity.ErrReservationNotFound) {
+		return nil, err
+	}
+
+	var products []*entity.Product
+	for attempt := 0; attempt < stockUpdateAttempts; attempt++ {
+		var movements []entity.StockMovement
+		products, movements, err = u.stockMovements(ctx, reservation, entity.StockReasonReserved)
+		if err != nil {
+			break
+		}
+		err = u.reservationRepoDynamo.Reserve(ctx, reservation, movements)
+		if !errors.Is(err, entity.ErrStockConflict) {
+			break
+		}
+	}
+	// another delivery of the order reserved it meanwhile, its outcome stands
+	if errors.Is(err, entity.ErrReservationExists) || errors.Is(err, entity.ErrDuplicateStockEvent) {
+		existing, getErr := u.reservationRepoDynamo.GetByOrderID(ctx, orderID)
+		if getErr == nil {
+			return u.reservedBefore(existing)
+		}
+		if !errors.Is(getErr, entity.ErrReservationNotFound) {
+			return nil, getErr
+		}
+	}
+	if isReservationFailure(err) {
+		return u.reservationFailed(ctx, orderID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.stockChanged(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.produceStockReserved(reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// reservedBefore announces the open reservation of an order reserved before again
+func (u *ReservationUseCase) reservedBefore(existing *entity.Reservation) (*entity.Reservation, error) {
+	if existing.IsOpen() {
+		err := u.produceStockReserved(existing)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// isReservationFailure reports whether the order cannot be reserved as it is, the other
+// errors are worth a retry
+func isReservationFailure(err error) bool {
+	return errors.Is(err, entity.ErrInvalidReservation) ||
+		errors.Is(err, entity.ErrProductNotFound) ||
+		errors.Is(err, entity.ErrVariantNotFound) ||
+		errors.Is(err, entity.ErrInsufficientStock)
+}
+
+// prepareReservation holds every order item on its product or its variant, the items of a
+// bundle on its components
+func (u *ReservationUseCase) prepareReservation(ctx context.Context, orderID string, items []entity.OrderItem) (*entity.Reservation, error) {
+	if orderID == "" {
+		return nil, fmt.Errorf("%w: an order id is required", entity.ErrInvalidReservation)
+	}
+	err := entity.ValidateOrderItems(items)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	reservation := &entity.Reservation{
+		OrderID:   orderID,
+		Items:     items,
+		Status:    entity.ReservationReserved,
+		ExpiresAt: now.Add(u.ttl),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	for _, item := range items {
+		product, err := u.productRepoDynamo.GetProductByID(ctx, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if item.VariantID != "" {
+			if product.IsBundle() {
+				return nil, fmt.Errorf("%w: bundle %s has no variants", entity.ErrInvalidReservation, product.ID)
+			}
+			variant, err := u.variantRepoDynamo.GetByID(ctx, product.ID, item.VariantID)
+			if err != nil {
+				return nil, err
+			}
+			reservation.AddLine(product.ID, variant.ID, product.CategoryID, item.Quantity)
+			continue
+		}
+		if !product.IsBundle() || product.Bundle == nil {
+			reservation.AddLine(product.ID, "", product.CategoryID, item.Quantity)
+			continue
+		}
+
+		for _, component := range product.Bundle.Components {
+			c, err := u.productRepoDynamo.GetProductByID(ctx, component.ProductID)
+			if err != nil {
+				return nil, err
+			}
+			reservation.AddLine(c.ID, "", c.CategoryID, component.Quantity*item.Quantity)
+		}
+	}
+
+	if len(reservation.Lines) > entity.MaxReservationLines {
+		return nil, fmt.Errorf("%w: an order can hold at most %d products and variants", entity.ErrInvalidReservation, entity.MaxReservationLines)
+	}
+	return reservation, nil
+}
+
+// ReleaseReservation puts the stock of a cancelled order back, a reservation released
+// before is returned as it is
+func (u *ReservationUseCase) ReleaseReservation(ctx context.Context, orderID string) (*entity.Reservation, error) {
+	reservation, err := u.reservationRepoDynamo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation.Status == entity.ReservationReleased {
+		return reservation, nil
+	}
+
+	err = u.release(ctx, reservation, entity.ReservationReleased)
+	if err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// CommitReservation turns the stock held for a paid order into sold stock, a reservation
+// committed before is returned as it is
+func (u *ReservationUseCase) CommitReservation(ctx context.Context, orderID string) (*entity.Reservation, error) {
+	reservation, err := u.reservationRepoDynamo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation.Status == entity.ReservationCommitted {
+		return reservation, nil
+	}
+	if !reservation.IsOpen() {
+		return nil, fmt.Errorf("%w, order: %s, status: %s", entity.ErrReservationClosed, orderID, reservation.Status)
+	}
+
+	reservation.Status = entity.ReservationCommitted
+	reservation.UpdatedAt = time.Now()
+	err = u.reservationRepoDynamo.Commit(ctx, reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	// the available quantity does not change, the stock only stops being reserved
+	return reservation, nil
+}
+
+// ReleaseExpiredReservations puts the stock of the reservations that expired back and
+// returns how many were released. A reservation paid or cancelled meanwhile is skipped.
+func (u *ReservationUseCase) ReleaseExpiredReservations(ctx context.Context) (int, error) {
+	expired, err := u.reservationRepoDynamo.GetExpired(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for i := range expired {
+		err = u.release(ctx, &expired[i], entity.ReservationExpired)
+		if errors.Is(err, entity.ErrReservationClosed) {
+			continue
+		}
+		if err != nil {
+			return released, err
+		}
+		released++
+	}
+
+	return released, nil
+}
+
+func (u *ReservationUseCase) release(ctx context.Context, reservation *entity.Reservation, status entity.ReservationStatus) error {
+	if !reservation.IsOpen() {
+		return fmt.Errorf("%w, order: %s, status: %s", entity.ErrReservationClosed, reservation.OrderID, reservation.Status)
+	}
+
+	reservation.Status = status
+	reservation.UpdatedAt = time.Now()
+
+	reason := entity.StockReasonReleased
+	if status == entity.ReservationExpired {
+		reason = entity.StockReasonExpired
+	}
+
+	var products []*entity.Product
+	var err error
+	for attempt := 0; attempt < stockUpdateAttempts; attempt++ {
+		var movements []entity.StockMovement
+		products, movements, err = u.stockMovements(ctx, reservation, reason)
+		if err != nil {
+			return err
+		}
+		err = u.reservationRepoDynamo.Release(ctx, reservation, movements)
+		if !errors.Is(err, entity.ErrStockConflict) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return u.stockChanged(ctx, products)
+}
+
+// stockMovements reads the products and variants of the reservation lines and returns the
+// ledger entries of the change the reason makes, with the quantity every line will hold
+// after it. A reservation takes its quantities out and fails with a StockShortageError when
+// a line has too few, a release puts them back, on trashed products too. A purged product
+// or a deleted variant gets no entry. The products returned are the ones in the catalog,
+// with their quantity after the change; the stock of a variant is not part of its product.
+func (u *ReservationUseCase) stockMovements(
+	ctx context.Context,
+	reservation *entity.Reservation,
+	reason entity.StockMovementReason,
+) ([]*entity.Product, []entity.StockMovement, error) {
+	var products []*entity.Product
+	movements := make([]entity.StockMovement, 0, len(reservation.Lines))
+	shortage := &entity.StockShortageError{}
+	for _, line := range reservation.Lines {
+		if line.VariantID != "" {
+			movement, err := u.variantStockMovement(ctx, reservation, line, reason)
+			if errors.Is(err, entity.ErrInsufficientStock) {
+				shortage.Add(line)
+				continue
+			}
+			if errors.Is(err, entity.ErrVariantNotFound) && reason != entity.StockReasonReserved {
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			movements = append(movements, movement)
+			continue
+		}
+
+		product, err := u.productRepoDynamo.GetProductByID(ctx, line.ProductID)
+		trashed := false
+		if errors.Is(err, entity.ErrProductNotFound) && reason != entity.StockReasonReserved {
+			product, err = u.productRepoDynamo.GetDeletedProductByID(ctx, line.ProductID)
+			if errors.Is(err, entity.ErrProductNotFound) {
+				continue
+			}
+			trashed = true
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		delta := line.Quantity
+		if reason == entity.StockReasonReserved {
+			delta = -line.Quantity
+			if product.Quantity < line.Quantity {
+				shortage.Add(line)
+				continue
+			}
+		}
+
+		movements = append(movements, entity.StockMovement{
+			EventID:   reservation.OrderID + ":" + string(reason),
+			ProductID: product.ID,
+			Delta:     delta,
+			Quantity:  product.Quantity + delta,
+			Reason:    reason,
+			Source:    "order:" + reservation.OrderID,
+			CreatedAt: reservation.UpdatedAt,
+		})
+		if !trashed {
+			product.Quantity += delta
+			products = append(products, product)
+		}
+	}
+	if !shortage.Empty() {
+		return nil, nil, shortage
+	}
+	return products, movements, nil
+}
+
+// variantStockMovement returns the ledger entry of the change the reason makes on the
+// variant of the line, ErrInsufficientStock when a reservation asks for more than it has.
+// The entry is recorded in the ledger of the product, the event id names the variant so
+// the lines of more variants of a product do not collide.
+func (u *ReservationUseCase) variantStockMovement(
+	ctx context.Context,
+	reservation *entity.Reservation,
+	line entity.ReservationLine,
+	reason entity.StockMovementReason,
+) (entity.StockMovement, error) {
+	variant, err := u.variantRepoDynamo.GetByID(ctx, line.ProductID, line.VariantID)
+	if err != nil {
+		return entity.StockMovement{}, err
+	}
+
+	delta := line.Quantity
+	if reason == entity.StockReasonReserved {
+		delta = -line.Quantity
+		if variant.Quantity < line.Quantity {
+			return entity.StockMovement{}, entity.ErrInsufficientStock
+		}
+	}
+
+	return entity.StockMovement{
+		EventID:   reservation.OrderID + ":" + string(reason) + ":" + variant.ID,
+		ProductID: variant.ProductID,
+		VariantID: variant.ID,
+		Delta:     delta,
+		Quantity:  variant.Quantity + delta,
+		Reason:    reason,
+		Source:    "order:" + reservation.OrderID,
+		CreatedAt: reservation.UpdatedAt,
+	}, nil
+}
+
+// stockChanged brings the search index and the bundles in line with the quantities a
+// reservation changed and alerts the stock that runs low
+func (u *ReservationUseCase) stockChanged(ctx context.Context, products []*entity.Product) error {
+	for _, product := range products {
+		err := u.productRepoSearch.UpdateQuantity(ctx, product.ID, product.Quantity)
+		if err != nil {
+			return err
+		}
+
+		err = checkStockAlert(ctx, u.productRepoDynamo, u.producer, u.stockAlerts, product)
+		if err != nil {
+			return err
+		}
+
+		err = refreshBundles(
+			ctx,
+			u.bundleRepoDynamo,
+			u.productRepoDynamo,
+			u.productRepoSearch,
+			u.priceRepoDynamo,
+			u.variantRepoDynamo,
+			u.promotions,
+			u.producer,
+			product.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *ReservationUseCase) produceStockReserved(reservation *entity.Reservation) error {
+	message := kafkaStockReservedMessage{
+		OrderID:   reservation.OrderID,
+		Items:     make([]kafkaReservedItem, 0, len(reservation.Items)),
+		ExpiresAt: reservation.ExpiresAt,
+	}
+	for _, item := range reservation.Items {
+		message.Items = append(message.Items, kafkaReservedItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	err := u.producer.Produce(
+		stockReservedTopic,
+		[]byte(reservation.OrderID),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+	return nil
+}
+
+// reservationFailed sends the reason the order could not be reserved to
+// stock-reservation-failed and returns it. An order another delivery reserved keeps that
+// outcome, the stock it holds may be what this one found missing.
+func (u *ReservationUseCase) reservationFailed(ctx context.Context, orderID string, reason error) (*entity.Reservation, error) {
+	if orderID != "" {
+		existing, err := u.reservationRepoDynamo.GetByOrderID(ctx, orderID)
+		if err == nil {
+			return u.reservedBefore(existing)
+		}
+		if !errors.Is(err, entity.ErrReservationNotFound) {
+			return nil, err
+		}
+	}
+
+	message := kafkaStockReservationFailedMessage{
+		OrderID:  orderID,
+		Reason:   reason.Error(),
+		FailedAt: time.Now(),
+	}
+	var shortage *entity.StockShortageError
+	if errors.As(reason, &shortage) {
+		message.ProductIDs = shortage.ProductIDs
+		message.VariantIDs = shortage.VariantIDs
+	}
+
+	err := u.producer.Produce(
+		stockReservationFailedTopic,
+		[]byte(orderID),
+		message,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+	return nil, fmt.Errorf("failed to reserve stock: %w", reason)
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+)
+
+// _maxSlugAttempts is how many numbered suffixes are tried before the id is used as suffix
+const _maxSlugAttempts = 20
+
+// claimedSlug is a slug reserved for a write of its target. A new one is given back when
+// the write fails, so it can be taken by someone else.
+type claimedSlug struct {
+	kind     entity.SlugKind
+	slug     string
+	targetID string
+	fresh    bool
+}
+
+// releaseOnError gives the slug back when the write failed and returns the error of the
+// write, the slug stays reserved when err is nil
+func (s claimedSlug) releaseOnError(ctx context.Context, slugRepo SlugDynamoRepo, err error) error {
+	if err == nil || !s.fresh {
+		return err
+	}
+	releaseErr := slugRepo.Release(ctx, s.kind, s.slug, s.targetID)
+	if releaseErr != nil {
+		return fmt.Errorf("%w, failed to give back slug %s: %v", err, s.slug, releaseErr)
+	}
+	return err
+}
+
+// reserveSlug claims a slug generated from the name for the target, a taken slug gets
+// the next free number as suffix
+func reserveSlug(ctx context.Context, slugRepo SlugDynamoRepo, kind entity.SlugKind, name, targetID string) (claimedSlug, error) {
+	base := slugBase(kind, name)
+
+	candidates := make([]string, 0, _maxSlugAttempts+1)
+	for n := 1; n <= _maxSlugAttempts; n++ {
+		candidates = append(candidates, entity.SlugCandidate(base, n))
+	}
+	// the end of a uuid v7 is random, so this one is practically always free
+	id := strings.ReplaceAll(targetID, "-", "")
+	candidates = append(candidates, base+"-"+id[max(len(id)-8, 0):])
+
+	now := time.Now()
+	var err error
+	for _, candidate := range candidates {
+		var fresh bool
+		fresh, err = slugRepo.Reserve(ctx, &entity.Slug{
+			Kind:      kind,
+			Slug:      candidate,
+			TargetID:  targetID,
+			CreatedAt: now,
+		})
+		if err == nil {
+			return claimedSlug{kind: kind, slug: candidate, targetID: targetID, fresh: fresh}, nil
+		}
+		if !errors.Is(err, entity.ErrSlugTaken) {
+			return claimedSlug{}, err
+		}
+	}
+	return claimedSlug{}, err
+}
+
+// renameSlug returns the slug of the target after it got the name. The current slug is
+// kept while it still matches the name, otherwise a new one is reserved and the current
+// one stays behind in the history, pointing at the target.
+func renameSlug(ctx context.Context, slugRepo SlugDynamoRepo, kind entity.SlugKind, current, name, targetID string) (claimedSlug, error) {
+	if current != "" && entity.SlugHasBase(current, slugBase(kind, name)) {
+		return claimedSlug{kind: kind, slug: current, targetID: targetID}, nil
+	}
+	return reserveSlug(ctx, slugRepo, kind, name, targetID)
+}
+
+// slugBase falls back to the kind for names that have nothing to transliterate
+func slugBase(kind entity.SlugKind, name string) string {
+	if base := entity.Slugify(name); base != "" {
+		return base
+	}
+	return string(kind)
+}
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/pkg/kafka"
+)
+
+// stockUpdateAttempts bounds the retries of a quantity update that raced another stock change
+const stockUpdateAttempts = 3
+
+const (
+	productStockLowTopic   = "product-stock-low"
+	productOutOfStockTopic = "product-out-of-stock"
+)
+
+type kafkaStockAlertMessage struct {
+	ProductID  string    `json:"product_id"`
+	SKU        string    `json:"sku"`
+	Name       string    `json:"name"`
+	CategoryID string    `json:"category_id"`
+	Quantity   int       `json:"quantity"`
+	Reserved   int       `json:"reserved"`
+	Threshold  int       `json:"threshold"`
+	AlertedAt  time.Time `json:"alerted_at"`
+}
+
+// UpdateProductQuantity stores the stock on hand of the product, what is still reserved for
+// orders is not available. The change is recorded in the ledger under the event id of the
+// movement, an event applied before is skipped with ErrDuplicateStockEvent.
+func (u *ProductUseCase) UpdateProductQuantity(ctx context.Context, productID string, quantity int, movement entity.StockMovement) error {
+	var product *entity.Product
+	var err error
+	for attempt := 0; attempt < stockUpdateAttempts; attempt++ {
+		product, err = u.productRepoDynamo.GetProductByID(ctx, productID)
+		if err != nil {
+			return fmt.Errorf("failed to update product quantity: %w", err)
+		}
+
+		movement.ProductID = productID
+		movement.Quantity = quantity - product.Reserved
+		movement.Delta = movement.Quantity - product.Quantity
+		movement.CreatedAt = time.Now()
+
+		err = u.stockRepoDynamo.Apply(ctx, &movement, product)
+		if !errors.Is(err, entity.ErrStockConflict) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	err = u.productRepoSearch.UpdateQuantity(ctx, productID, movement.Quantity)
+	if err != nil {
+		return err
+	}
+
+	product.Quantity = movement.Quantity
+	err = checkStockAlert(ctx, u.productRepoDynamo, u.producer, u.stockAlerts, product)
+	if err != nil {
+		return err
+	}
+
+	// the stock of the bundles holding the product is derived from it
+	return refreshBundles(
+		ctx,
+		u.bundleRepoDynamo,
+		u.productRepoDynamo,
+		u.productRepoSearch,
+		u.priceRepoDynamo,
+		u.variantRepoDynamo,
+		u.promotions,
+		u.producer,
+		productID,
+	)
+}
+
+// GetStockMovements returns the ledger of the product, newest first
+func (u *ProductUseCase) GetStockMovements(ctx context.Context, productID string, page entity.Pagination) ([]entity.StockMovement, string, error) {
+	return u.stockRepoDynamo.GetByProductID(ctx, productID, page)
+}
+
+// GetLowStockProducts returns a page of the products at or below their reorder threshold
+func (u *ProductUseCase) GetLowStockProducts(ctx context.Context, page entity.Pagination) ([]entity.LowStockProduct, string, error) {
+	products, nextCursor, err := u.productRepoDynamo.GetLowStock(ctx, u.stockAlerts.DefaultThreshold, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	report := make([]entity.LowStockProduct, 0, len(products))
+	for i := range products {
+		report = append(report, entity.LowStockProduct{
+			Product:   products[i],
+			Threshold: u.stockAlerts.Threshold(&products[i]),
+			Level:     u.stockAlerts.Level(&products[i]),
+		})
+	}
+	return report, nextCursor, nil
+}
+
+// checkStockAlert sends product-stock-low or product-out-of-stock when the available
+// quantity of the product fell to its reorder threshold or to zero. The alert state is
+// stored on the product before the message is sent, a change evaluated concurrently is
+// alerted only once. When the message cannot be sent the state is put back, so the next
+// change of stock alerts again.
+func checkStockAlert(
+	ctx context.Context,
+	productRepo ProductDynamoRepo,
+	producer *kafka.ProducerServer,
+	policy entity.StockAlertPolicy,
+	product *entity.Product,
+) error {
+	previous := product.StockAlert
+	next, alert := policy.Next(product, time.Now())
+	if next == previous {
+		return nil
+	}
+
+	product.StockAlert = next
+	err := productRepo.UpdateStockAlert(ctx, product, previous)
+	if errors.Is(err, entity.ErrStockConflict) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !alert {
+		return nil
+	}
+
+	topic := productStockLowTopic
+	if next.Level == entity.StockLevelOut {
+		topic = productOutOfStockTopic
+	}
+	err = producer.Produce(
+		topic,
+		[]byte(product.ID),
+		kafkaStockAlertMessage{
+			ProductID:  product.ID,
+			SKU:        product.SKU,
+			Name:       product.Name,
+			CategoryID: product.CategoryID,
+			Quantity:   product.Quantity,
+			Reserved:   product.Reserved,
+			Threshold:  policy.Threshold(product),
+			AlertedAt:  *next.SentAt,
+		},
+	)
+	if err != nil {
+		product.StockAlert = previous
+		rollbackErr := productRepo.UpdateStockAlert(ctx, product, next)
+		if rollbackErr != nil && !errors.Is(rollbackErr, entity.ErrStockConflict) {
+			return fmt.Errorf("failed to produce kafka message: %w, failed to restore stock alert: %v", err, rollbackErr)
+		}
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+	return nil
+}
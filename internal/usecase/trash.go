@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+)
+
+type TrashUseCase struct {
+	productRepoImage   ProductS3Repo
+	productRepoDynamo  ProductDynamoRepo
+	productRepoSearch  ProductSearchRepo
+	variantRepoDynamo  VariantDynamoRepo
+	priceRepoDynamo    PriceDynamoRepo
+	slugRepoDynamo     SlugDynamoRepo
+	linkRepoDynamo     ProductLinkDynamoRepo
+	bundleRepoDynamo   BundleDynamoRepo
+	categoryRepoDynamo CategoryDynamoRepo
+	categoryRepoRedis  CategoryRedisRepo
+	retention          time.Duration
+}
+
+func NewTrashUseCase(
+	productRepoImage ProductS3Repo,
+	productRepoDynamo ProductDynamoRepo,
+	productRepoSearch ProductSearchRepo,
+	variantRepoDynamo VariantDynamoRepo,
+	priceRepoDynamo PriceDynamoRepo,
+	slugRepoDynamo SlugDynamoRepo,
+	linkRepoDynamo ProductLinkDynamoRepo,
+	bundleRepoDynamo BundleDynamoRepo,
+	categoryRepoDynamo CategoryDynamoRepo,
+	categoryRepoRedis CategoryRedisRepo,
+	retention time.Duration,
+) *TrashUseCase {
+	return &TrashUseCase{
+		productRepoImage:   productRepoImage,
+		productRepoDynamo:  productRepoDynamo,
+		productRepoSearch:  productRepoSearch,
+		variantRepoDynamo:  variantRepoDynamo,
+		priceRepoDynamo:    priceRepoDynamo,
+		slugRepoDynamo:     slugRepoDynamo,
+		linkRepoDynamo:     linkRepoDynamo,
+		bundleRepoDynamo:   bundleRepoDynamo,
+		categoryRepoDynamo: categoryRepoDynamo,
+		categoryRepoRedis:  categoryRepoRedis,
+		retention:          retention,
+	}
+}
+
+func (u *TrashUseCase) GetDeletedProducts(ctx context.Context, page entity.Pagination) ([]entity.Product, string, error) {
+	return u.productRepoDynamo.GetDeleted(ctx, page)
+}
+
+func (u *TrashUseCase) GetDeletedCategories(ctx context.Context) ([]entity.Category, error) {
+	return u.categoryRepoDynamo.GetDeleted(ctx)
+}
+
+// RestoreProduct takes the product out of the trash, its category has to be restored first
+func (u *TrashUseCase) RestoreProduct(ctx context.Context, id string) (*entity.Product, error) {
+	product, err := u.productRepoDynamo.GetDeletedProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = u.categoryRepoDynamo.GetByID(ctx, product.CategoryID)
+	if errors.Is(err, entity.ErrCategoryNotFound) {
+		return nil, fmt.Errorf("%w: category %s of product %s", entity.ErrParentDeleted, product.CategoryID, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	product.DeletedAt = nil
+	product.UpdatedAt = time.Now()
+
+	err = u.productRepoDynamo.Restore(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	err = indexProduct(ctx, u.productRepoSearch, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index product: %w", err)
+	}
+
+	return product, nil
+}
+
+// RestoreCategory takes the category out of the trash, its parent has to be restored first
+func (u *TrashUseCase) RestoreCategory(ctx context.Context, id string) (*entity.Category, error) {
+	category, err := u.categoryRepoDynamo.GetDeletedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if category.ParentID != nil && *category.ParentID != "" {
+		_, err = u.categoryRepoDynamo.GetByID(ctx, *category.ParentID)
+		if errors.Is(err, entity.ErrCategoryNotFound) {
+			return nil, fmt.Errorf("%w: parent %s of category %s", entity.ErrParentDeleted, *category.ParentID, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	category.DeletedAt = nil
+	category.UpdatedAt = time.Now()
+
+	err = u.categoryRepoDynamo.Restore(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	// the category was removed from the cached sets on delete
+	err = u.categoryRepoRedis.Add(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// PurgeExpired hard-deletes the products and categories that were in the trash for
+// longer than the retention period, together with the images and the data kept apart
+// for the products. An item failing to purge does not stop the others, the errors are joined.
+func (u *TrashUseCase) PurgeExpired(ctx context.Context) (*entity.TrashPurgeReport, error) {
+	report := &entity.TrashPurgeReport{}
+	cutoff := time.Now().Add(-u.retention)
+
+	products, err := u.productRepoDynamo.GetDeletedBefore(ctx, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge trash: %w", err)
+	}
+
+	var errs []error
+	for _, product := range products {
+		// the data kept apart goes before the item, so a purge failing half way
+		// is done again on the next run
+		err = u.purgeProductData(ctx, &product)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge the data of product %s: %w", product.ID, err))
+			continue
+		}
+
+		// images a failed delete leaves behind are orphans for the image garbage collection
+		err = u.productRepoDynamo.Purge(ctx, product.ID, product.CategoryID)
+		if errors.Is(err, entity.ErrProductNotFound) {
+			// restored in the meantime
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		report.Products++
+
+		for _, image := range product.Images {
+			err = u.productRepoImage.DeleteImage(ctx, image)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete image %s of product %s: %w", image.ID, product.ID, err))
+				continue
+			}
+			report.Images++
+		}
+	}
+
+	categories, err := u.categoryRepoDynamo.GetDeleted(ctx)
+	if err != nil {
+		return report, errors.Join(append(errs, fmt.Errorf("failed to purge trash: %w", err))...)
+	}
+
+	for _, category := range categories {
+		if category.DeletedAt == nil || !category.DeletedAt.Before(cutoff) {
+			continue
+		}
+
+		// a category that still has products is kept, they would point at nothing. Products
+		// in the trash count too, they could not be restored without their category.
+		hasProducts, err := u.productRepoDynamo.HasProducts(ctx, category.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if hasProducts {
+			continue
+		}
+
+		err = u.categoryRepoDynamo.Purge(ctx, category.ID)
+		if errors.Is(err, entity.ErrCategoryNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		report.Categories++
+	}
+
+	return report, errors.Join(errs...)
+}
+
+// purgeProductData deletes what is stored for the product outside of its item. The
+// translations are part of the item and go with it.
+func (u *TrashUseCase) purgeProductData(ctx context.Context, product *entity.Product) error {
+	err := u.variantRepoDynamo.PurgeByProductID(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+
+	err = u.priceRepoDynamo.PurgeByProductID(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+
+	err = u.slugRepoDynamo.PurgeByTarget(ctx, entity.SlugProduct, product.ID)
+	if err != nil {
+		return err
+	}
+
+	err = u.linkRepoDynamo.PurgeByProductID(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+
+	// the entries listing the components of the bundle, and the bundles the product is a component of
+	if product.Bundle != nil {
+		componentIDs := make([]string, 0, len(product.Bundle.Components))
+		for _, component := range product.Bundle.Components {
+			componentIDs = append(componentIDs, component.ProductID)
+		}
+		err = u.bundleRepoDynamo.DeleteComponents(ctx, product.ID, componentIDs)
+		if err != nil {
+			return err
+		}
+	}
+	bundleIDs, err := u.bundleRepoDynamo.GetBundleIDs(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+	for _, bundleID := range bundleIDs {
+		err = u.bundleRepoDynamo.DeleteComponents(ctx, bundleID, []string{product.ID})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/idoyudha/eshop-product/internal/entity"
+	"github.com/idoyudha/eshop-product/pkg/kafka"
+)
+
+type VariantUseCase struct {
+	variantRepoDynamo VariantDynamoRepo
+	productRepoDynamo ProductDynamoRepo
+	stockRepoDynamo   StockMovementDynamoRepo
+	promotions        *PromotionEvaluator
+	producer          *kafka.ProducerServer
+}
+
+func NewVariantUseCase(
+	variantRepoDynamo VariantDynamoRepo,
+	productRepoDynamo ProductDynamoRepo,
+	stockRepoDynamo StockMovementDynamoRepo,
+	promotions *PromotionEvaluator,
+	producer *kafka.ProducerServer,
+) *VariantUseCase {
+	return &VariantUseCase{
+		variantRepoDynamo: variantRepoDynamo,
+		productRepoDynamo: productRepoDynamo,
+		stockRepoDynamo:   stockRepoDynamo,
+		promotions:        promotions,
+		producer:          producer,
+	}
+}
+
+func (u *VariantUseCase) CreateVariant(ctx context.Context, variant *entity.Variant) (*entity.Variant, error) {
+	product, err := u.productRepoDynamo.GetProductByID(ctx, variant.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create variant: %w", err)
+	}
+
+	err = variant.ValidatePrice(product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create variant: %w", err)
+	}
+
+	err = variant.GenerateVariantID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create variant: %w", err)
+	}
+	variant.GenerateSKU()
+
+	err = u.variantRepoDynamo.Save(ctx, variant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create variant: %w", err)
+	}
+	// a promotion can target the sku of the new variant
+	u.promotions.Invalidate()
+
+	// the variant ids of the product changed
+	err = produceProductUpdated(ctx, u.producer, u.variantRepoDynamo, u.promotions, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return variant, nil
+}
+
+func (u *VariantUseCase) GetVariants(ctx context.Context, productID string) ([]entity.Variant, error) {
+	return u.variantRepoDynamo.GetByProductID(ctx, productID)
+}
+
+func (u *VariantUseCase) GetVariantByID(ctx context.Context, productID, variantID string) (*entity.Variant, error) {
+	return u.variantRepoDynamo.GetByID(ctx, productID, variantID)
+}
+
+func (u *VariantUseCase) UpdateVariant(ctx context.Context, variant *entity.Variant) error {
+	if variant.Price != nil {
+		product, err := u.productRepoDynamo.GetProductByID(ctx, variant.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to update variant: %w", err)
+		}
+
+		err = variant.ValidatePrice(product)
+		if err != nil {
+			return fmt.Errorf("failed to update variant: %w", err)
+		}
+	}
+
+	err := u.variantRepoDynamo.Update(ctx, variant)
+	if err != nil {
+		return fmt.Errorf("failed to update variant: %w", err)
+	}
+	return nil
+}
+
+// UpdateVariantQuantity stores the stock on hand of the variant, what is still reserved for
+// orders is not available. The change is recorded in the ledger of the product under the
+// event id of the movement, an event applied before is skipped with ErrDuplicateStockEvent.
+func (u *VariantUseCase) UpdateVariantQuantity(ctx context.Context, productID, variantID string, quantity int, movement entity.StockMovement) error {
+	var err error
+	for attempt := 0; attempt < stockUpdateAttempts; attempt++ {
+		var variant *entity.Variant
+		variant, err = u.variantRepoDynamo.GetByID(ctx, productID, variantID)
+		if err != nil {
+			return fmt.Errorf("failed to update variant quantity: %w", err)
+		}
+
+		movement.ProductID = productID
+		movement.VariantID = variantID
+		movement.Quantity = quantity - variant.Reserved
+		movement.Delta = movement.Quantity - variant.Quantity
+		movement.CreatedAt = time.Now()
+
+		err = u.stockRepoDynamo.ApplyVariant(ctx, &movement, variant)
+		if !errors.Is(err, entity.ErrStockConflict) {
+			break
+		}
+	}
+	return err
+}
+
+func (u *VariantUseCase) DeleteVariant(ctx context.Context, productID, variantID string) error {
+	product, err := u.productRepoDynamo.GetProductByID(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete variant: %w", err)
+	}
+
+	err = u.variantRepoDynamo.Delete(ctx, productID, variantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete variant: %w", err)
+	}
+	u.promotions.Invalidate()
+
+	err = produceProductUpdated(ctx, u.producer, u.variantRepoDynamo, u.promotions, product)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
+}
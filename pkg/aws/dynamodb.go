@@ -8,18 +8,63 @@ import (
 const (
 	_productTableName  = "eshop-products"
 	_categoryTableName = "eshop-product-categories"
+	_variantTableName  = "eshop-product-variants"
+
+	_priceHistoryTableName  = "eshop-product-price-history"
+	_priceScheduleTableName = "eshop-product-price-schedules"
+	_promotionTableName     = "eshop-product-promotions"
+
+	_importJobTableName   = "eshop-product-import-jobs"
+	_importErrorTableName = "eshop-product-import-errors"
+
+	_slugTableName        = "eshop-product-slugs"
+	_productLinkTableName = "eshop-product-links"
+
+	_bundleComponentTableName = "eshop-product-bundle-components"
+	_reservationTableName     = "eshop-product-reservations"
+	_stockMovementTableName   = "eshop-product-stock-movements"
 )
 
 type DynamoDB struct {
 	Client        *dynamodb.Client
 	ProductTable  string
 	CategoryTable string
+	VariantTable  string
+
+	PriceHistoryTable  string
+	PriceScheduleTable string
+	PromotionTable     string
+
+	ImportJobTable   string
+	ImportErrorTable string
+
+	SlugTable        string
+	ProductLinkTable string
+
+	BundleComponentTable string
+	ReservationTable     string
+	StockMovementTable   string
 }
 
 func NewDynamoDB(cfg *config.AWS) (*DynamoDB, error) {
 	dynamoDB := &DynamoDB{
 		ProductTable:  _productTableName,
 		CategoryTable: _categoryTableName,
+		VariantTable:  _variantTableName,
+
+		PriceHistoryTable:  _priceHistoryTableName,
+		PriceScheduleTable: _priceScheduleTableName,
+		PromotionTable:     _promotionTableName,
+
+		ImportJobTable:   _importJobTableName,
+		ImportErrorTable: _importErrorTableName,
+
+		SlugTable:        _slugTableName,
+		ProductLinkTable: _productLinkTableName,
+
+		BundleComponentTable: _bundleComponentTableName,
+		ReservationTable:     _reservationTableName,
+		StockMovementTable:   _stockMovementTableName,
 	}
 
 	client, err := dynamoDBClient(cfg)
@@ -0,0 +1,156 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"sync"
+
+	// webp decoder for image.Decode
+	_ "golang.org/x/image/webp"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+)
+
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatWebP Format = "webp"
+)
+
+const (
+	OriginalRendition = "original"
+	_jpegQuality      = 85
+)
+
+// Rendition is a size to produce, the image is scaled down to MaxWidth keeping the
+// aspect ratio and never scaled up, a MaxWidth of 0 keeps the original size
+type Rendition struct {
+	Name     string
+	MaxWidth int
+}
+
+// DefaultRenditions are produced for every product image
+var DefaultRenditions = []Rendition{
+	{Name: OriginalRendition},
+	{Name: "thumbnail", MaxWidth: 150},
+	{Name: "medium", MaxWidth: 600},
+	{Name: "large", MaxWidth: 1200},
+}
+
+// Output is one encoded rendition
+type Output struct {
+	Name   string
+	Format Format
+	Width  int
+	Height int
+	Data   []byte
+}
+
+func (o Output) ContentType() string {
+	return "image/" + string(o.Format)
+}
+
+func (o Output) Extension() string {
+	if o.Format == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + string(o.Format)
+}
+
+// Process decodes an image, rotates it upright according to its EXIF orientation and
+// encodes every rendition twice: as jpeg (png when the source may have transparency)
+// and as webp. Decoding and encoding drops all metadata, EXIF included.
+func Process(data []byte, renditions []Rendition) ([]Output, error) {
+	img, sourceFormat, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if sourceFormat == string(FormatJPEG) {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	rasterFormat := FormatPNG
+	if sourceFormat == string(FormatJPEG) {
+		rasterFormat = FormatJPEG
+	}
+
+	outputs := make([]Output, 2*len(renditions))
+	errs := make([]error, 2*len(renditions))
+
+	var wg sync.WaitGroup
+	for i, rendition := range renditions {
+		resized := resize(img, rendition.MaxWidth)
+
+		for j, format := range []Format{rasterFormat, FormatWebP} {
+			wg.Add(1)
+			go func(idx int, name string, format Format) {
+				defer wg.Done()
+
+				encoded, err := encode(resized, format)
+				if err != nil {
+					errs[idx] = fmt.Errorf("failed to encode %s rendition as %s: %w", name, format, err)
+					return
+				}
+				outputs[idx] = Output{
+					Name:   name,
+					Format: format,
+					Width:  resized.Bounds().Dx(),
+					Height: resized.Bounds().Dy(),
+					Data:   encoded,
+				}
+			}(2*i+j, rendition.Name, format)
+		}
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+func resize(img image.Image, maxWidth int) image.Image {
+	b := img.Bounds()
+	if maxWidth <= 0 || b.Dx() <= maxWidth {
+		return img
+	}
+
+	height := b.Dy() * maxWidth / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, maxWidth, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+func encode(img image.Image, format Format) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var err error
+	switch format {
+	case FormatJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: _jpegQuality})
+	case FormatPNG:
+		err = png.Encode(&buf, img)
+	case FormatWebP:
+		err = nativewebp.Encode(&buf, img, nil)
+	default:
+		err = fmt.Errorf("unsupported format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
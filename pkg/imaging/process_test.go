@@ -0,0 +1,251 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	_red  = color.NRGBA{R: 255, A: 255}
+	_blue = color.NRGBA{B: 255, A: 255}
+)
+
+// halvesImage is red on its left half and blue on its right half
+func halvesImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.SetNRGBA(x, y, _red)
+			} else {
+				img.SetNRGBA(x, y, _blue)
+			}
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation puts an EXIF segment holding the orientation right after the start of
+// the jpeg, the TIFF header is written in the given byte order
+func withOrientation(data []byte, orientation int, order binary.ByteOrder) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:4], 42)
+	order.PutUint32(tiff[4:8], 8)
+	order.PutUint16(tiff[8:10], 1)
+	order.PutUint16(tiff[10:12], _exifOrientationTag)
+	order.PutUint16(tiff[12:14], 3) // SHORT
+	order.PutUint32(tiff[14:18], 1)
+	order.PutUint16(tiff[18:20], uint16(orientation))
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, _jpegAPP1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:4], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func decodeOutput(t *testing.T, output Output) image.Image {
+	t.Helper()
+	img, format, err := image.Decode(bytes.NewReader(output.Data))
+	if err != nil {
+		t.Fatalf("decode %s %s: %v", output.Name, output.Format, err)
+	}
+	if Format(format) != output.Format {
+		t.Fatalf("%s is encoded as %s, want %s", output.Name, format, output.Format)
+	}
+	return img
+}
+
+func TestProcessRenditions(t *testing.T) {
+	outputs, err := Process(encodeJPEG(t, halvesImage(1600, 800)), DefaultRenditions)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	want := map[string][2]int{
+		OriginalRendition: {1600, 800},
+		"thumbnail":       {150, 75},
+		"medium":          {600, 300},
+		"large":           {1200, 600},
+	}
+	if len(outputs) != 2*len(want) {
+		t.Fatalf("got %d outputs, want %d", len(outputs), 2*len(want))
+	}
+
+	formats := map[string][]Format{}
+	for _, output := range outputs {
+		size, ok := want[output.Name]
+		if !ok {
+			t.Fatalf("unexpected rendition %s", output.Name)
+		}
+		if output.Width != size[0] || output.Height != size[1] {
+			t.Errorf("%s %s is %dx%d, want %dx%d", output.Name, output.Format, output.Width, output.Height, size[0], size[1])
+		}
+
+		bounds := decodeOutput(t, output).Bounds()
+		if bounds.Dx() != output.Width || bounds.Dy() != output.Height {
+			t.Errorf("%s %s decodes to %dx%d, reported %dx%d", output.Name, output.Format, bounds.Dx(), bounds.Dy(), output.Width, output.Height)
+		}
+		formats[output.Name] = append(formats[output.Name], output.Format)
+	}
+
+	for name, got := range formats {
+		if len(got) != 2 || got[0] != FormatJPEG || got[1] != FormatWebP {
+			t.Errorf("%s is encoded as %v, want [jpeg webp]", name, got)
+		}
+	}
+}
+
+func TestProcessKeepsSmallImagesAndPNG(t *testing.T) {
+	outputs, err := Process(encodePNG(t, halvesImage(100, 50)), DefaultRenditions)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	for _, output := range outputs {
+		// an image smaller than a rendition is never scaled up
+		if output.Width != 100 || output.Height != 50 {
+			t.Errorf("%s %s is %dx%d, want 100x50", output.Name, output.Format, output.Width, output.Height)
+		}
+		// a png may be transparent, it stays a png
+		if output.Format != FormatPNG && output.Format != FormatWebP {
+			t.Errorf("%s is encoded as %s, want png or webp", output.Name, output.Format)
+		}
+		decodeOutput(t, output)
+	}
+}
+
+func TestProcessOrientation(t *testing.T) {
+	// rotated 90 clockwise the left half of the stored pixels ends up on top
+	data := withOrientation(encodeJPEG(t, halvesImage(40, 20)), 6, binary.BigEndian)
+
+	outputs, err := Process(data, []Rendition{{Name: OriginalRendition}})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	for _, output := range outputs {
+		if output.Width != 20 || output.Height != 40 {
+			t.Fatalf("%s is %dx%d, want 20x40", output.Format, output.Width, output.Height)
+		}
+
+		img := decodeOutput(t, output)
+		if !isReddish(img.At(10, 5)) {
+			t.Errorf("%s top is %v, want red", output.Format, img.At(10, 5))
+		}
+		if isReddish(img.At(10, 35)) {
+			t.Errorf("%s bottom is %v, want blue", output.Format, img.At(10, 35))
+		}
+
+		// the EXIF segment is not carried over
+		if bytes.Contains(output.Data, []byte("Exif\x00\x00")) {
+			t.Errorf("%s still holds EXIF data", output.Format)
+		}
+	}
+}
+
+func isReddish(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > 0xC000 && b < 0x4000
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	plain := encodeJPEG(t, halvesImage(8, 8))
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", plain, 1},
+		{"big endian", withOrientation(plain, 6, binary.BigEndian), 6},
+		{"little endian", withOrientation(plain, 3, binary.LittleEndian), 3},
+		{"out of range", withOrientation(plain, 9, binary.BigEndian), 1},
+		{"truncated", withOrientation(plain, 8, binary.BigEndian)[:12], 1},
+		{"not a jpeg", encodePNG(t, halvesImage(8, 8)), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	src.SetNRGBA(0, 0, _red)
+
+	// where the top left pixel goes, and the size after the orientation
+	tests := []struct {
+		orientation int
+		x, y        int
+		w, h        int
+	}{
+		{1, 0, 0, 3, 2},
+		{2, 2, 0, 3, 2},
+		{3, 2, 1, 3, 2},
+		{4, 0, 1, 3, 2},
+		{5, 0, 0, 2, 3},
+		{6, 1, 0, 2, 3},
+		{7, 1, 2, 2, 3},
+		{8, 0, 2, 2, 3},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.orientation)
+		if got.Bounds().Dx() != tt.w || got.Bounds().Dy() != tt.h {
+			t.Errorf("orientation %d: size %v, want %dx%d", tt.orientation, got.Bounds().Size(), tt.w, tt.h)
+			continue
+		}
+		if got.At(tt.x, tt.y) != color.Color(_red) {
+			t.Errorf("orientation %d: the top left pixel is not at %d,%d", tt.orientation, tt.x, tt.y)
+		}
+	}
+}
+
+func TestProcessInvalidImage(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"garbage":   []byte("not an image at all"),
+		"truncated": encodeJPEG(t, halvesImage(64, 64))[:100],
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Process(data, DefaultRenditions); err == nil {
+				t.Error("Process succeeded, want an error")
+			}
+		})
+	}
+}
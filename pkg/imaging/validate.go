@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image too large")
+	ErrInvalidImage      = errors.New("invalid image")
+)
+
+// Limits of an accepted upload, a zero limit is not enforced
+type Limits struct {
+	MaxBytes  int64
+	MaxWidth  int
+	MaxHeight int
+	MaxPixels int
+	Formats   []Format
+}
+
+// Info describes a validated image
+type Info struct {
+	Format Format
+	Width  int
+	Height int
+	Size   int64
+}
+
+// Validator checks uploads by their content instead of trusting the client:
+// the format is sniffed from the magic bytes and the dimensions are read from the
+// header, so a decompression bomb is rejected before its pixels are decoded
+type Validator struct {
+	limits Limits
+}
+
+func NewValidator(limits Limits) *Validator {
+	return &Validator{limits: limits}
+}
+
+func (v *Validator) Validate(r io.Reader) (Info, error) {
+	reader := r
+	if v.limits.MaxBytes > 0 {
+		reader = io.LimitReader(r, v.limits.MaxBytes+1)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	size := int64(len(data))
+	if v.limits.MaxBytes > 0 && size > v.limits.MaxBytes {
+		return Info{}, fmt.Errorf("%w: must not exceed %d bytes", ErrImageTooLarge, v.limits.MaxBytes)
+	}
+
+	format, ok := sniffFormat(data)
+	if !ok || !v.allowed(format) {
+		return Info{}, fmt.Errorf("%w: allowed formats are %v", ErrUnsupportedFormat, v.limits.Formats)
+	}
+
+	cfg, decodedFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || Format(decodedFormat) != format {
+		return Info{}, fmt.Errorf("%w: failed to read %s header", ErrInvalidImage, format)
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return Info{}, fmt.Errorf("%w: empty image", ErrInvalidImage)
+	}
+	if v.limits.MaxWidth > 0 && cfg.Width > v.limits.MaxWidth {
+		return Info{}, fmt.Errorf("%w: width must not exceed %d pixels", ErrImageTooLarge, v.limits.MaxWidth)
+	}
+	if v.limits.MaxHeight > 0 && cfg.Height > v.limits.MaxHeight {
+		return Info{}, fmt.Errorf("%w: height must not exceed %d pixels", ErrImageTooLarge, v.limits.MaxHeight)
+	}
+	if v.limits.MaxPixels > 0 && cfg.Width*cfg.Height > v.limits.MaxPixels {
+		return Info{}, fmt.Errorf("%w: must not exceed %d pixels in total", ErrImageTooLarge, v.limits.MaxPixels)
+	}
+
+	return Info{
+		Format: format,
+		Width:  cfg.Width,
+		Height: cfg.Height,
+		Size:   size,
+	}, nil
+}
+
+// CheckDeclared checks the content type and size a client announces before uploading,
+// the content itself still has to go through Validate once it is uploaded
+func (v *Validator) CheckDeclared(contentType string, size int64) error {
+	format := Format(strings.TrimPrefix(contentType, "image/"))
+	if !strings.HasPrefix(contentType, "image/") || !v.allowed(format) {
+		return fmt.Errorf("%w: allowed formats are %v", ErrUnsupportedFormat, v.limits.Formats)
+	}
+	if size <= 0 {
+		return fmt.Errorf("%w: size must be positive", ErrInvalidImage)
+	}
+	if v.limits.MaxBytes > 0 && size > v.limits.MaxBytes {
+		return fmt.Errorf("%w: must not exceed %d bytes", ErrImageTooLarge, v.limits.MaxBytes)
+	}
+	return nil
+}
+
+func (v *Validator) allowed(format Format) bool {
+	for _, f := range v.limits.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffFormat detects the format from the magic bytes at the start of the file
+func sniffFormat(data []byte) (Format, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG, true
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, true
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return FormatWebP, true
+	}
+	return "", false
+}
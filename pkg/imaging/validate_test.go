@@ -0,0 +1,71 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	pngData := encodePNG(t, halvesImage(200, 100))
+	jpegData := encodeJPEG(t, halvesImage(200, 100))
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		data    []byte
+		wantErr error
+	}{
+		{"accepted", Limits{Formats: []Format{FormatPNG}}, pngData, nil},
+		{"format not allowed", Limits{Formats: []Format{FormatJPEG}}, pngData, ErrUnsupportedFormat},
+		{"unknown format", Limits{Formats: []Format{FormatPNG}}, []byte("GIF89a......"), ErrUnsupportedFormat},
+		{"too many bytes", Limits{MaxBytes: 64, Formats: []Format{FormatPNG}}, pngData, ErrImageTooLarge},
+		{"too wide", Limits{MaxWidth: 199, Formats: []Format{FormatPNG}}, pngData, ErrImageTooLarge},
+		{"too high", Limits{MaxHeight: 99, Formats: []Format{FormatPNG}}, pngData, ErrImageTooLarge},
+		{"too many pixels", Limits{MaxPixels: 19999, Formats: []Format{FormatPNG}}, pngData, ErrImageTooLarge},
+		{"broken header", Limits{Formats: []Format{FormatPNG}}, pngData[:20], ErrInvalidImage},
+		// the magic bytes say png, the content is a jpeg
+		{"mismatched content", Limits{Formats: []Format{FormatPNG}}, append([]byte("\x89PNG\r\n\x1a\n"), jpegData...), ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := NewValidator(tt.limits).Validate(bytes.NewReader(tt.data))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Validate error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if info.Format != FormatPNG || info.Width != 200 || info.Height != 100 || info.Size != int64(len(tt.data)) {
+				t.Errorf("Validate = %+v", info)
+			}
+		})
+	}
+}
+
+func TestCheckDeclared(t *testing.T) {
+	validator := NewValidator(Limits{MaxBytes: 1000, Formats: []Format{FormatJPEG, FormatWebP}})
+
+	tests := []struct {
+		contentType string
+		size        int64
+		wantErr     error
+	}{
+		{"image/jpeg", 1000, nil},
+		{"image/webp", 1, nil},
+		{"image/png", 10, ErrUnsupportedFormat},
+		{"text/plain", 10, ErrUnsupportedFormat},
+		{"jpeg", 10, ErrUnsupportedFormat},
+		{"image/jpeg", 0, ErrInvalidImage},
+		{"image/jpeg", 1001, ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		err := validator.CheckDeclared(tt.contentType, tt.size)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckDeclared(%q, %d) = %v, want %v", tt.contentType, tt.size, err, tt.wantErr)
+		}
+	}
+}
@@ -12,6 +12,9 @@ import (
 const (
 	ProductGroup          = "product-group"
 	ProductQtyUpdateTopic = "product-quantity-updated"
+	OrderCreatedTopic     = "order-created"
+	OrderCancelledTopic   = "order-cancelled"
+	OrderPaidTopic        = "order-paid"
 	maxRetries            = 5
 	retryDelay            = 2 * time.Second
 )
@@ -49,6 +52,9 @@ func NewKafkaConsumer(kafkaCfg config.Kafka) (*ConsumerServer, error) {
 
 	topics := []string{
 		ProductQtyUpdateTopic,
+		OrderCreatedTopic,
+		OrderCancelledTopic,
+		OrderPaidTopic,
 	}
 
 	var subscribeErr error
@@ -0,0 +1,190 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	_defaultK1 = 1.2
+	_defaultB  = 0.75
+)
+
+// Field is a named part of a document, its BM25 score is multiplied by the weight
+type Field struct {
+	Name   string
+	Weight float64
+}
+
+type Result struct {
+	ID    string
+	Score float64
+}
+
+// Index is an in-memory inverted index ranking documents with BM25,
+// every field is scored on its own and the weighted scores are summed.
+// It is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	fields   []Field
+	docs     map[string]document
+	postings map[string]map[string]map[string]int // field -> term -> doc id -> term frequency
+	totalLen map[string]int                       // field -> sum of the field length over all docs
+}
+
+// document keeps what was indexed for a doc so it can be removed again
+type document struct {
+	lengths map[string]int
+	terms   map[string]map[string]int // field -> term -> term frequency
+}
+
+func NewIndex(fields ...Field) *Index {
+	postings := make(map[string]map[string]map[string]int, len(fields))
+	for _, f := range fields {
+		postings[f.Name] = map[string]map[string]int{}
+	}
+
+	return &Index{
+		fields:   fields,
+		docs:     map[string]document{},
+		postings: postings,
+		totalLen: map[string]int{},
+	}
+}
+
+// Add indexes the field values of a document, replacing it when the id is already indexed
+func (i *Index) Add(id string, values map[string]string) {
+	doc := document{
+		lengths: make(map[string]int, len(i.fields)),
+		terms:   make(map[string]map[string]int, len(i.fields)),
+	}
+	for _, f := range i.fields {
+		tokens := Tokenize(values[f.Name])
+		freqs := make(map[string]int, len(tokens))
+		for _, token := range tokens {
+			freqs[token]++
+		}
+		doc.lengths[f.Name] = len(tokens)
+		doc.terms[f.Name] = freqs
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+
+	for field, freqs := range doc.terms {
+		for term, tf := range freqs {
+			if i.postings[field][term] == nil {
+				i.postings[field][term] = map[string]int{}
+			}
+			i.postings[field][term][id] = tf
+		}
+		i.totalLen[field] += doc.lengths[field]
+	}
+	i.docs[id] = doc
+}
+
+func (i *Index) Remove(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+}
+
+func (i *Index) remove(id string) {
+	doc, ok := i.docs[id]
+	if !ok {
+		return
+	}
+
+	for field, freqs := range doc.terms {
+		for term := range freqs {
+			delete(i.postings[field][term], id)
+			if len(i.postings[field][term]) == 0 {
+				delete(i.postings[field], term)
+			}
+		}
+		i.totalLen[field] -= doc.lengths[field]
+	}
+	delete(i.docs, id)
+}
+
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return len(i.docs)
+}
+
+// Search returns at most limit documents matching any query term, best match first
+func (i *Index) Search(query string, limit int) []Result {
+	terms := uniqueTokens(query)
+	if len(terms) == 0 || limit <= 0 {
+		return []Result{}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	n := float64(len(i.docs))
+	if n == 0 {
+		return []Result{}
+	}
+
+	scores := map[string]float64{}
+	for _, f := range i.fields {
+		avgLen := float64(i.totalLen[f.Name]) / n
+		if avgLen == 0 {
+			continue
+		}
+
+		for _, term := range terms {
+			postings := i.postings[f.Name][term]
+			if len(postings) == 0 {
+				continue
+			}
+
+			df := float64(len(postings))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for id, tf := range postings {
+				docLen := float64(i.docs[id].lengths[f.Name])
+				freq := float64(tf)
+				norm := freq * (_defaultK1 + 1) / (freq + _defaultK1*(1-_defaultB+_defaultB*docLen/avgLen))
+				scores[id] += f.Weight * idf * norm
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{ID: id, Score: score})
+	}
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].ID < results[b].ID
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func uniqueTokens(text string) []string {
+	tokens := Tokenize(text)
+	seen := make(map[string]struct{}, len(tokens))
+
+	unique := tokens[:0]
+	for _, token := range tokens {
+		if _, ok := seen[token]; ok {
+			continue
+		}
+		seen[token] = struct{}{}
+		unique = append(unique, token)
+	}
+	return unique
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestIndex() *Index {
+	index := NewIndex(Field{Name: "name", Weight: 2}, Field{Name: "description", Weight: 1})
+	index.Add("trail-shoes", map[string]string{"name": "Trail running shoes", "description": "Lightweight shoes for running on trails"})
+	index.Add("socks", map[string]string{"name": "Running socks", "description": "Socks that keep feet dry while running"})
+	index.Add("boots", map[string]string{"name": "Leather boots", "description": "Hiking boots, also fine for running"})
+	index.Add("jacket", map[string]string{"name": "Rain jacket", "description": "Waterproof jacket"})
+	index.Add("polish", map[string]string{"name": "Shoe polish", "description": "Keeps leather shoes shiny"})
+	return index
+}
+
+func resultIDs(results []Result) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := newTestIndex()
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		// both terms beat one, a term in the name beats it in the description only
+		{"ranking", "running shoes", 10, []string{"trail-shoes", "polish", "socks", "boots"}},
+		{"limit", "running shoes", 2, []string{"trail-shoes", "polish"}},
+		// the query is stemmed like the documents
+		{"stemmed query", "RUN", 10, []string{"socks", "trail-shoes", "boots"}},
+		// a rare term weighs more than a common one
+		{"rare term", "leather running", 10, []string{"boots", "socks", "trail-shoes", "polish"}},
+		{"repeated term", "jacket jacket", 10, []string{"jacket"}},
+		{"no match", "umbrella", 10, []string{}},
+		{"stop words only", "the and for", 10, []string{}},
+		{"zero limit", "running", 0, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resultIDs(index.Search(tt.query, tt.limit)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexScoresDescending(t *testing.T) {
+	results := newTestIndex().Search("running shoes leather", 10)
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score {
+			t.Errorf("result %d scores %f, more than %f before it", i, results[i].Score, results[i-1].Score)
+		}
+	}
+}
+
+func TestIndexTieBreak(t *testing.T) {
+	index := NewIndex(Field{Name: "name", Weight: 1})
+	index.Add("b", map[string]string{"name": "red shoe"})
+	index.Add("a", map[string]string{"name": "red shoe"})
+	index.Add("c", map[string]string{"name": "blue shoe"})
+
+	if got := resultIDs(index.Search("shoe", 10)); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Search = %v, want equal scores ordered by id", got)
+	}
+}
+
+func TestIndexReplaceAndRemove(t *testing.T) {
+	index := newTestIndex()
+
+	index.Add("jacket", map[string]string{"name": "Rain poncho"})
+	if got := index.Search("jacket", 10); len(got) != 0 {
+		t.Errorf("Search(jacket) = %v after the document was replaced", got)
+	}
+	if got := resultIDs(index.Search("poncho", 10)); !reflect.DeepEqual(got, []string{"jacket"}) {
+		t.Errorf("Search(poncho) = %v, want the replaced document", got)
+	}
+
+	index.Remove("polish")
+	index.Remove("unknown")
+	if index.Len() != 4 {
+		t.Errorf("Len = %d, want 4", index.Len())
+	}
+	if got := resultIDs(index.Search("polish", 10)); len(got) != 0 {
+		t.Errorf("Search(polish) = %v after the document was removed", got)
+	}
+}
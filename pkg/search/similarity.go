@@ -0,0 +1,166 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Similarity finds documents alike to a document by the cosine of their TF-IDF vectors.
+// The terms of all fields go in one vector, the term frequency of a field is multiplied
+// by its weight. The vectors are computed again on the first lookup after a change.
+// It is safe for concurrent use.
+type Similarity struct {
+	mu     sync.RWMutex
+	fields []Field
+	docs   map[string]map[string]float64 // doc id -> term -> weighted term frequency
+	df     map[string]int                // term -> number of docs having it
+
+	// computed from docs, nil while stale
+	vectors  map[string]map[string]float64 // doc id -> term -> unit length tf-idf weight
+	postings map[string]map[string]float64 // term -> doc id -> tf-idf weight
+}
+
+func NewSimilarity(fields ...Field) *Similarity {
+	return &Similarity{
+		fields: fields,
+		docs:   map[string]map[string]float64{},
+		df:     map[string]int{},
+	}
+}
+
+// Add takes the field values of a document, replacing it when the id is already added
+func (s *Similarity) Add(id string, values map[string]string) {
+	terms := map[string]float64{}
+	for _, f := range s.fields {
+		counts := map[string]int{}
+		for _, token := range Tokenize(values[f.Name]) {
+			counts[token]++
+		}
+		// sublinear, a word repeated in a description should not outweigh the name
+		for term, count := range counts {
+			terms[term] += f.Weight * (1 + math.Log(float64(count)))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+	for term := range terms {
+		s.df[term]++
+	}
+	s.docs[id] = terms
+	s.vectors, s.postings = nil, nil
+}
+
+func (s *Similarity) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.remove(id) {
+		s.vectors, s.postings = nil, nil
+	}
+}
+
+func (s *Similarity) remove(id string) bool {
+	terms, ok := s.docs[id]
+	if !ok {
+		return false
+	}
+
+	for term := range terms {
+		s.df[term]--
+		if s.df[term] == 0 {
+			delete(s.df, term)
+		}
+	}
+	delete(s.docs, id)
+	return true
+}
+
+// Similar returns at most limit documents sharing terms with the document, most alike
+// first. The document itself is left out, an unknown id has no similar documents.
+func (s *Similarity) Similar(id string, limit int) []Result {
+	if limit <= 0 {
+		return []Result{}
+	}
+
+	s.mu.RLock()
+	for s.vectors == nil {
+		s.mu.RUnlock()
+		s.compute()
+		s.mu.RLock()
+	}
+	defer s.mu.RUnlock()
+
+	vector, ok := s.vectors[id]
+	if !ok {
+		return []Result{}
+	}
+
+	scores := map[string]float64{}
+	for term, weight := range vector {
+		for other, otherWeight := range s.postings[term] {
+			if other != id {
+				scores[other] += weight * otherWeight
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for other, score := range scores {
+		results = append(results, Result{ID: other, Score: score})
+	}
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].ID < results[b].ID
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// compute builds the unit length vectors of all documents with the current document frequencies
+func (s *Similarity) compute() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// another lookup may have computed them while the lock was released
+	if s.vectors != nil {
+		return
+	}
+
+	n := float64(len(s.docs))
+	vectors := make(map[string]map[string]float64, len(s.docs))
+	postings := make(map[string]map[string]float64, len(s.df))
+	for id, terms := range s.docs {
+		vector := make(map[string]float64, len(terms))
+		norm := 0.0
+		for term, tf := range terms {
+			// smoothed, a term in every document still weighs a little
+			weight := tf * (1 + math.Log((1+n)/(1+float64(s.df[term]))))
+			vector[term] = weight
+			norm += weight * weight
+		}
+		if norm == 0 {
+			continue
+		}
+
+		norm = math.Sqrt(norm)
+		for term, weight := range vector {
+			vector[term] = weight / norm
+			if postings[term] == nil {
+				postings[term] = map[string]float64{}
+			}
+			postings[term][id] = vector[term]
+		}
+		vectors[id] = vector
+	}
+
+	s.vectors, s.postings = vectors, postings
+}
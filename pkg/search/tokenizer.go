@@ -0,0 +1,29 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "by": {},
+	"for": {}, "from": {}, "in": {}, "is": {}, "it": {}, "of": {}, "on": {}, "or": {},
+	"the": {}, "this": {}, "to": {}, "with": {},
+}
+
+// Tokenize splits text on anything that is not a letter or a digit,
+// lower cases and stems the words and drops stop words
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		if _, ok := stopWords[word]; ok {
+			continue
+		}
+		tokens = append(tokens, Stem(word))
+	}
+	return tokens
+}
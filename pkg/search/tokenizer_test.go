@@ -0,0 +1,31 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"  ,.;  ", []string{}},
+		{"Running Shoes", []string{"run", "shoe"}},
+		{"running-shoes,size:42!", []string{"run", "shoe", "size", "42"}},
+		// stop words are dropped before stemming
+		{"The shoe is for the trail", []string{"shoe", "trail"}},
+		{"a an and the", []string{}},
+		// letters and digits of any script are kept together
+		{"USB3 Café", []string{"usb3", "café"}},
+		{"iPhone 15 Pro", []string{"iphon", "15", "pro"}},
+		// repeated words are kept, the index counts them
+		{"shoes shoes", []string{"shoe", "shoe"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}